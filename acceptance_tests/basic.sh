@@ -268,14 +268,47 @@ EOL
   cat >test2.yml <<EOL
 a: 1
 EOL
-read -r -d '' expected << EOM
-0
----
-1
-EOM
   ./yq -i test.yml test2.yml
   X=$(./yq e '.a' test.yml)
-  assertEquals "$expected" "$X"
+  assertEquals "0" "$X"
+  X=$(./yq e '.a' test2.yml)
+  assertEquals "1" "$X"
+}
+
+testBasicUpdateInPlaceMultipleFiles() {
+  cat >test.yml <<EOL
+a: 0 # zero
+EOL
+  cat >test2.yml <<EOL
+a: 1
+b: cat
+EOL
+  ./yq -i '.a += 10' test.yml test2.yml
+  X=$(cat test.yml)
+  assertEquals "a: 10 # zero" "$X"
+  X=$(./yq -o=json -I=0 '.' test2.yml)
+  assertEquals '{"a":11,"b":"cat"}' "$X"
+}
+
+testBasicUpdateInPlaceMultipleFilesWithFailures() {
+  cat >test.yml <<EOL
+a: 0
+EOL
+  cat >test2.yml <<EOL
+a: [1
+EOL
+  cat >test3.yml <<EOL
+a: 2
+EOL
+  X=$(./yq -i '.a = "x"' test.yml test2.yml test3.yml test4.yml 2>&1)
+  assertEquals 1 $?
+  assertContains "$X" "test2.yml: bad file 'test2.yml'"
+  assertContains "$X" "test4.yml: stat test4.yml: no such file or directory"
+  assertContains "$X" "Error: failed to update 2 of 4 files"
+  assertEquals "a: x" "$(cat test.yml)"
+  assertEquals "a: [1" "$(cat test2.yml)"
+  assertEquals "a: x" "$(cat test3.yml)"
+  assertEquals "" "$(ls -a | grep '\.tmp$')"
 }
 
 testBasicUpdateInPlaceMultipleFilesNoExpressionEvalAll() {
@@ -0,0 +1,106 @@
+#!/bin/bash
+
+setUp() {
+  rm test*.yml 2>/dev/null || true
+}
+
+testDiffSame() {
+  cat >test-a.yml <<EOL
+a: cat
+b: [1, 2]
+EOL
+  cp test-a.yml test-b.yml
+  X=$(./yq diff -e test-a.yml test-b.yml)
+  assertEquals 0 $?
+  assertEquals "" "$X"
+}
+
+testDiffByKey() {
+  cat >test-a.yml <<EOL
+containers:
+  - name: web
+    image: nginx:1.24
+  - name: db
+    image: postgres
+EOL
+  cat >test-b.yml <<EOL
+containers:
+  - name: init
+    image: busybox
+  - name: web
+    image: nginx:1.25
+EOL
+
+  read -r -d '' expected << EOM
+--- test-a.yml
++++ test-b.yml
+- .containers[1]: {name: db, image: postgres}
++ .containers[0]: {name: init, image: busybox}
+- .containers[0].image: nginx:1.24
++ .containers[1].image: nginx:1.25
+EOM
+  X=$(./yq diff -e --key name test-a.yml test-b.yml 2>/dev/null)
+  assertEquals 1 $?
+  assertEquals "$expected" "$X"
+}
+
+testDiffPatch() {
+  cat >test-a.yml <<EOL
+a: cat
+---
+b: 1
+EOL
+  cat >test-b.yml <<EOL
+a: dog
+---
+b: 1
+EOL
+
+  read -r -d '' expected << EOM
+[{"op":"replace","path":"/a","value":"dog"}]
+[]
+EOM
+  X=$(./yq diff --patch -o json -I0 test-a.yml test-b.yml)
+  assertEquals "$expected" "$X"
+}
+
+testDiffQuotedKeys() {
+  cat >test-a.yml <<EOL
+b.c: 1
+list: [{"x y": 1}]
+EOL
+  cat >test-b.yml <<EOL
+b.c: 2
+list: [{"x y": 2}]
+EOL
+
+  read -r -d '' expected << EOM
+--- test-a.yml
++++ test-b.yml
+- .["b.c"]: 1
++ .["b.c"]: 2
+- .list[0]["x y"]: 1
++ .list[0]["x y"]: 2
+EOM
+  X=$(./yq diff test-a.yml test-b.yml)
+  assertEquals "$expected" "$X"
+}
+
+testDiffDifferentFormats() {
+  cat >test-a.yml <<EOL
+a: cat
+EOL
+  echo 'a = "dog"' > test-b.toml
+
+  read -r -d '' expected << EOM
+--- test-a.yml
++++ test-b.toml
+- .a: cat
++ .a: dog
+EOM
+  X=$(./yq diff test-a.yml test-b.toml)
+  rm test-b.toml
+  assertEquals "$expected" "$X"
+}
+
+source ./scripts/shunit2
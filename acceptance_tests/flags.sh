@@ -17,5 +17,120 @@ testNoStringInterpolation() {
     assertEquals "Mike \(3 + 4)" "$X"
 }
 
+testStreamYaml() {
+    X=$(echo 'a: [1]' | ./yq --stream -o=json -I=0 '.')
+    assertEquals 0 $?
+    assertEquals '[["a",0],1]
+[["a",0]]
+[["a"]]' "$X"
+}
+
+testStreamXml() {
+    X=$(echo '<a><b>1</b></a>' | ./yq -p=xml --stream -o=json -I=0 '.')
+    assertEquals 0 $?
+    assertEquals '[["a","b",0],"1"]
+[["a","b",0]]
+[["a"]]' "$X"
+}
+
+testStreamUnsupportedFormat() {
+    X=$(echo 'a = 1' | ./yq -p=toml --stream '.' 2>&1)
+    assertEquals 1 $?
+    assertEquals "Error: --stream only supports yaml, json and xml input, toml documents are decoded whole - use tostream instead" "$X"
+}
+
+testLibraryPath() {
+    X=$(./yq -L examples/lib -n 'import "k8s" as k8s; {"kind": "Pod"} | k8s::is_kind("Pod")')
+    assertEquals "true" "$X"
+}
+
+testLibraryIncludesRelativeToLibrary() {
+    X=$(./yq -L examples -n 'import "lib/helm" as helm; {"kind": "Pod"} | helm::is_kind("Pod")')
+    assertEquals "true" "$X"
+}
+
+testLibraryPathEnv() {
+    X=$(YQ_LIBRARY_PATH=examples/lib ./yq -n 'include "k8s"; {"kind": "Pod"} | is_kind("Pod")')
+    assertEquals "true" "$X"
+}
+
+testCheckInPlace() {
+    printf 'b: 2\na: 1\n' > test.yml
+    printf 'a: 1\nb: 2\n' > test2.yml
+    X=$(./yq -i --check 'sort_keys(.)' test.yml test2.yml 2>/dev/null)
+    assertEquals 1 $?
+    assertEquals "test.yml" "$X"
+    assertEquals "b: 2" "$(head -n 1 test.yml)"
+
+    X=$(./yq -i --check 'sort_keys(.)' test2.yml)
+    assertEquals 0 $?
+    assertEquals "" "$X"
+}
+
+testDiffInPlace() {
+    printf 'b: 2\na: 1\n' > test.yml
+
+    read -r -d '' expected << EOM
+--- test.yml
++++ test.yml
+@@ -1,2 +1,2 @@
+-b: 2
+ a: 1
++b: 2
+EOM
+    X=$(./yq -i --diff 'sort_keys(.)' test.yml)
+    assertEquals 0 $?
+    assertEquals "$expected" "$X"
+    assertEquals "b: 2" "$(head -n 1 test.yml)"
+}
+
+testCheckWithoutInPlace() {
+    X=$(./yq --check '.' examples/data1.yaml 2>&1)
+    assertEquals 1 $?
+    assertEquals "Error: check and diff flags only applicable when updating files in place (-i)" "$X"
+}
+
+testBackupInPlace() {
+    printf 'a: 1\n' > test.yml
+    ./yq -i --backup '.a = 2' test.yml
+    assertEquals 0 $?
+    assertEquals "a: 2" "$(cat test.yml)"
+    assertEquals "a: 1" "$(cat test.yml.bak)"
+
+    ./yq -i --backup=.orig.yml '.a = 3' test.yml
+    assertEquals "a: 3" "$(cat test.yml)"
+    assertEquals "a: 2" "$(cat test.yml.orig.yml)"
+    rm test.yml.bak
+}
+
+testInPlaceKeepsSymlink() {
+    printf 'a: 1\n' > test.yml
+    ln -s test.yml test-link.yml
+    ./yq -i '.a = 2' test-link.yml
+    assertEquals 0 $?
+    assertTrue "[ -L test-link.yml ]"
+    assertEquals "a: 2" "$(cat test.yml)"
+}
+
+testInPlaceTruncateKeepsHardLink() {
+    printf 'a: 1\n' > test.yml
+    ln test.yml test-hard.yml
+    ./yq -i --write-strategy=truncate '.a = 2' test.yml
+    assertEquals 0 $?
+    assertEquals "a: 2" "$(cat test.yml)"
+    assertEquals "a: 2" "$(cat test-hard.yml)"
+}
+
+testInPlaceBadWriteStrategy() {
+    X=$(./yq -i --write-strategy=copy '.a = 2' test.yml 2>&1)
+    assertEquals 1 $?
+    assertEquals "Error: unknown write strategy 'copy' please use [rename, truncate]" "$X"
+}
+
+testBackupWithoutInPlace() {
+    X=$(./yq --backup '.' examples/data1.yaml 2>&1)
+    assertEquals 1 $?
+    assertEquals "Error: backup and write-strategy flags only applicable when updating files in place (-i)" "$X"
+}
 
-source ./scripts/shunit2
\ No newline at end of file
+source ./scripts/shunit2
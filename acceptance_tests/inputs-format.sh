@@ -6,6 +6,9 @@ setUp() {
   rm test*.csv 2>/dev/null || true
   rm test*.tsv 2>/dev/null || true
   rm test*.xml 2>/dev/null || true
+  rm test*.json 2>/dev/null || true
+  rm test*.jsonc 2>/dev/null || true
+  rm test*.jsonl 2>/dev/null || true
 }
 
 testInputProperties() {
@@ -174,6 +177,33 @@ EOM
   assertEquals "$expected" "$X"
 }
 
+testInputXmlLossless() {
+  cat >test.xml <<EOL
+<?xml version="1.0"?>
+<!-- settings -->
+<map xmlns="some-namespace" xmlns:xsi="some-instance"
+     xsi:schemaLocation="some-url">
+  <cat legs='4'>BiBi <b>the</b> cat</cat>
+  <script><![CDATA[a < b]]></script>
+  <empty />
+</map>
+EOL
+
+  ./yq -i --xml-lossless '(.. | select(tag == "!!map" and has("empty")) | .empty) = "full"' test.xml
+  read -r -d '' expected << EOM
+<?xml version="1.0"?>
+<!-- settings -->
+<map xmlns="some-namespace" xmlns:xsi="some-instance"
+     xsi:schemaLocation="some-url">
+  <cat legs='4'>BiBi <b>the</b> cat</cat>
+  <script><![CDATA[a < b]]></script>
+  <empty>full</empty>
+</map>
+EOM
+
+  assertEquals "$expected" "$(cat test.xml)"
+}
+
 testInputXmlNamespaces() {
   cat >test.xml <<EOL
 <?xml version="1.0"?>
@@ -255,4 +285,88 @@ EOM
   assertEquals "$expected" "$X"
 }
 
+testInputJsonDuplicateKeys() {
+  cat >test.json <<EOL
+{
+  "a": 1,
+  "a": 2
+}
+EOL
+
+  X=$(./yq -p=json -o=json -I=0 test.json)
+  assertEquals '{"a":2}' "$X"
+
+  X=$(./yq -p=json -o=json -I=0 --json-duplicate-keys=first test.json)
+  assertEquals '{"a":1}' "$X"
+
+  X=$(./yq --json-duplicate-keys=error test.json 2>&1)
+  assertEquals 1 $?
+  assertEquals "Error: bad file 'test.json': json: line 3, column 3: duplicate key \"a\", first defined at line 2, column 3" "$X"
+
+  X=$(./yq --json-duplicate-keys=some test.json 2>&1)
+  assertEquals 1 $?
+  assertEquals "Error: unknown json duplicate keys policy 'some' please use [error, first, last]" "$X"
+}
+
+testInputJsonExactNumbers() {
+  cat >test.json <<EOL
+{"id": 123456789012345678901234, "price": 1.10}
+EOL
+
+  X=$(./yq -p=json -o=json -I=0 test.json)
+  assertEquals '{"id":123456789012345678901234,"price":1.10}' "$X"
+}
+
+testInputJsoncInPlace() {
+  cat >test.jsonc <<EOL
+{
+  // the target
+  "target": "es2022", // for now
+  "strict": true,
+}
+EOL
+
+  read -r -d '' expected << EOM
+{
+  // the target
+  "target": "es2024", // for now
+  "strict": true
+}
+EOM
+
+  ./yq -i '.target = "es2024"' test.jsonc
+  assertEquals "$expected" "$(cat test.jsonc)"
+}
+
+testInputJsonLinesSkipInvalid() {
+  cat >test.jsonl <<EOL
+{"level": "info", "msg": "started"}
+{"level": "warn", "msg": "slow
+{"level": "error", "msg": "failed"}
+EOL
+
+  X=$(./yq --skip-invalid '.msg' test.jsonl 2>/dev/null)
+  assertEquals "$(printf '"started"\n"failed"')" "$X"
+
+  X=$(./yq --skip-invalid '.msg' test.jsonl 2>&1 >/dev/null)
+  assertContains "$X" "skipping invalid line: json: line 2, column 31: unexpected end of JSON input"
+}
+
+testInputJsonLinesInvalid() {
+  cat >test.jsonl <<EOL
+{"msg": "started"}
+{"msg": "slow
+EOL
+
+  X=$(./yq '.msg' test.jsonl 2>&1)
+  assertEquals 1 $?
+  assertContains "$X" "Error: bad file 'test.jsonl': json: line 2, column 14: unexpected end of JSON input"
+}
+
+testInputSkipInvalidNeedsJsonLines() {
+  X=$(./yq --skip-invalid '.' test.yml 2>&1)
+  assertEquals 1 $?
+  assertEquals "Error: skip-invalid flag only applicable to ndjson input" "$X"
+}
+
 source ./scripts/shunit2
\ No newline at end of file
@@ -4,6 +4,10 @@ setUp() {
   rm test*.yml || true
 }
 
+tearDown() {
+  rm -f test*.yml test*.yaml
+}
+
 testBasicSplitWithName() {
   cat >test.yml <<EOL
 a: test_doc1
@@ -0,0 +1,27 @@
+#!/bin/bash
+
+setUp() {
+  rm test*.yml 2>/dev/null || true
+}
+
+testValidateValid() {
+  cat >test.yml <<EOL
+name: my-app
+replicas: 2
+EOL
+  X=$(./yq validate --schema examples/schema.json test.yml)
+  assertEquals 0 $?
+  assertEquals "" "$X"
+}
+
+testValidateInvalid() {
+  cat >test.yml <<EOL
+name: my-app
+replicas: 0
+EOL
+  X=$(./yq validate --schema examples/schema.json test.yml 2>/dev/null)
+  assertEquals 1 $?
+  assertEquals "test.yml:2:11: .replicas: must be greater than or equal to 1, but got 0" "$X"
+}
+
+source ./scripts/shunit2
@@ -5,6 +5,11 @@ var unwrapScalarFlag = newUnwrapFlag()
 var unwrapScalar = false
 
 var writeInplace = false
+var checkInPlace = false
+var diffInPlace = false
+var backupSuffix = ""
+var writeStrategy = "rename"
+var jsonDuplicateKeys = "last"
 var outputToJSON = false
 
 var outputFormat = ""
@@ -16,6 +21,7 @@ var indent = 2
 var noDocSeparators = false
 var nullInput = false
 var nulSepOutput = false
+var streamInput = false
 var verbose = false
 var version = false
 var prettyPrint = false
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"container/list"
+	"errors"
+	"os"
+
+	"github.com/mikefarah/yq/v4/pkg/yqlib"
+	"github.com/spf13/cobra"
+)
+
+var diffKey = ""
+var diffAsPatch = false
+
+func createDiffCommand() *cobra.Command {
+	var cmdDiff = &cobra.Command{
+		Use:   "diff [from_file] [to_file]",
+		Short: "Shows the added, removed and changed paths between two files",
+		Example: `
+# Show the differences between two files
+yq diff deployment.yaml deployment-new.yaml
+
+# Match the maps in lists by their name, rather than their index
+yq diff --key name deployment.yaml deployment-new.yaml
+
+# Output a JSON Patch (RFC 6902) instead
+yq diff --patch -o json deployment.yaml deployment-new.yaml
+
+# Compare STDIN with a file
+cat deployment.yaml | yq diff - deployment-new.yaml
+`,
+		Long: `yq is a portable command-line data file processor (https://github.com/mikefarah/yq/) 
+See https://mikefarah.gitbook.io/yq/ for detailed documentation and examples.
+
+## Diff ##
+Compares each document of the first file with the document at the same index of the second,
+and shows the paths that have been added, removed or changed.
+With --exit-status, exits with a non-zero status if the files are different.
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: diff,
+	}
+	cmdDiff.Flags().StringVarP(&diffKey, "key", "k", "", "match the maps in sequences by the value of this key (e.g. name) instead of by index.")
+	cmdDiff.Flags().BoolVarP(&diffAsPatch, "patch", "", false, "output the differences as a JSON Patch (RFC 6902), in the output format.")
+	return cmdDiff
+}
+
+func diff(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	fileInfo, _ := os.Stdout.Stat()
+	if forceColor || (!forceNoColor && (fileInfo.Mode()&os.ModeCharDevice) != 0) {
+		colorsEnabled = true
+	}
+
+	fromDecoder, err := validateDecoder(args[0])
+	if err != nil {
+		return err
+	}
+	toDecoder, err := validateDecoder(args[1])
+	if err != nil {
+		return err
+	}
+
+	documents, err := yqlib.DiffFiles(args[0], args[1], fromDecoder, toDecoder, yqlib.DiffPreferences{Key: diffKey})
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if diffAsPatch {
+		if outputFormat == "" || outputFormat == "auto" || outputFormat == "a" {
+			outputFormat = yqlib.FormatStringFromFilename(args[0])
+		}
+		encoder, err := configureEncoder()
+		if err != nil {
+			return err
+		}
+		printer := yqlib.NewPrinter(encoder, yqlib.NewSinglePrinterWriter(out))
+		if err := printer.PrintResults(diffPatches(documents)); err != nil {
+			return err
+		}
+	} else if err := yqlib.PrintDiff(out, args[0], args[1], documents, colorsEnabled); err != nil {
+		return err
+	}
+
+	if exitStatus {
+		for _, operations := range documents {
+			if len(operations) > 0 {
+				return errors.New("files are different")
+			}
+		}
+	}
+	return nil
+}
+
+// diffPatches returns a patch for each document, so they are printed as separate documents
+func diffPatches(documents [][]*yqlib.DiffOperation) *list.List {
+	patches := list.New()
+	for index, operations := range documents {
+		patch := yqlib.DiffToPatch(operations)
+		patch.SetDocument(uint(index))
+		patches.PushBack(patch)
+	}
+	return patches
+}
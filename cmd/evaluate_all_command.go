@@ -62,7 +62,8 @@ func evaluateAll(cmd *cobra.Command, args []string) (cmdError error) {
 	if writeInplace {
 		// only use colors if its forced
 		colorsEnabled = forceColor
-		writeInPlaceHandler := yqlib.NewWriteInPlaceHandler(args[0])
+		inPlaceFilename := args[0]
+		writeInPlaceHandler := yqlib.NewWriteInPlaceHandler(inPlaceFilename)
 		out, err = writeInPlaceHandler.CreateTempFile()
 		if err != nil {
 			return err
@@ -73,6 +74,9 @@ func evaluateAll(cmd *cobra.Command, args []string) (cmdError error) {
 			if cmdError == nil {
 				cmdError = writeInPlaceHandler.FinishWriteInPlace(completedSuccessfully)
 			}
+			if cmdError == nil && writeInPlaceHandler.Changed() {
+				cmdError = checkInPlaceResult(cmd, []string{inPlaceFilename}, 1)
+			}
 		}()
 	}
 
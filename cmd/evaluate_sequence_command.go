@@ -36,6 +36,9 @@ yq e -n '.a.b.c = "cat"'
 
 # Update a file in place
 yq e '.a.b = "cool"' -i file.yaml 
+
+# Update several files in place, each on its own
+yq e '.metadata.labels.team = "x"' -i k8s/*.yaml
 `,
 		Long: `yq is a portable command-line data file processor (https://github.com/mikefarah/yq/) 
 See https://mikefarah.gitbook.io/yq/ for detailed documentation and examples.
@@ -58,7 +61,7 @@ func processExpression(expression string) string {
 	return expression
 }
 
-func evaluateSequence(cmd *cobra.Command, args []string) (cmdError error) {
+func evaluateSequence(cmd *cobra.Command, args []string) error {
 	// 0 args, read std in
 	// 1 arg, null input, process expression
 	// 1 arg, read file in sequence
@@ -74,20 +77,7 @@ func evaluateSequence(cmd *cobra.Command, args []string) (cmdError error) {
 	}
 
 	if writeInplace {
-		// only use colors if its forced
-		colorsEnabled = forceColor
-		writeInPlaceHandler := yqlib.NewWriteInPlaceHandler(args[0])
-		out, err = writeInPlaceHandler.CreateTempFile()
-		if err != nil {
-			return err
-		}
-		// need to indirectly call the function so  that completedSuccessfully is
-		// passed when we finish execution as opposed to now
-		defer func() {
-			if cmdError == nil {
-				cmdError = writeInPlaceHandler.FinishWriteInPlace(completedSuccessfully)
-			}
-		}()
+		return evaluateSequenceInPlace(cmd, expression, args)
 	}
 
 	format, err := yqlib.FormatFromString(outputFormat)
@@ -143,11 +133,112 @@ func evaluateSequence(cmd *cobra.Command, args []string) (cmdError error) {
 	default:
 		err = streamEvaluator.EvaluateFiles(processExpression(expression), args, printer, decoder)
 	}
-	completedSuccessfully = err == nil
-
 	if err == nil && exitStatus && !printer.PrintedAnything() {
 		return errors.New("no matches found")
 	}
 
 	return err
 }
+
+// evaluateSequenceInPlace runs the expression on each file on its own, writing the result back to that file.
+// Every file is attempted, and the ones that could not be updated are listed at the end.
+func evaluateSequenceInPlace(cmd *cobra.Command, expression string, files []string) error {
+	// only use colors if its forced
+	colorsEnabled = forceColor
+
+	// no need to try every file if the expression is bad
+	if _, err := yqlib.ExpressionParser.ParseExpression(processExpression(expression)); err != nil {
+		return err
+	}
+
+	failures := make([]string, 0)
+	changedFiles := make([]string, 0)
+	var lastErr error
+	for _, filename := range files {
+		changed, err := evaluateFileInPlace(expression, filename)
+		if err != nil {
+			yqlib.GetLogger().Debugf("failed to update %v: %v", filename, err)
+			failures = append(failures, fmt.Sprintf("%v: %v", filename, err))
+			lastErr = err
+		} else if changed {
+			changedFiles = append(changedFiles, filename)
+		}
+	}
+
+	checkErr := checkInPlaceResult(cmd, changedFiles, len(files))
+	if len(failures) == 0 {
+		return checkErr
+	} else if len(files) == 1 {
+		return lastErr
+	}
+	for _, failure := range failures {
+		if _, err := fmt.Fprintln(cmd.ErrOrStderr(), failure); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to update %v of %v files", len(failures), len(files))
+}
+
+func evaluateFileInPlace(expression string, filename string) (changed bool, cmdError error) {
+	writeInPlaceHandler := yqlib.NewWriteInPlaceHandler(filename)
+	out, err := writeInPlaceHandler.CreateTempFile()
+	if err != nil {
+		return false, err
+	}
+	completedSuccessfully := false
+	// the temp file replaces the original only if everything worked,
+	// otherwise it is removed and the original left untouched.
+	defer func() {
+		finishErr := writeInPlaceHandler.FinishWriteInPlace(completedSuccessfully)
+		if cmdError == nil {
+			cmdError = finishErr
+		}
+		changed = writeInPlaceHandler.Changed()
+	}()
+
+	format, err := yqlib.FormatFromString(outputFormat)
+	if err != nil {
+		return false, err
+	}
+	printerWriter, err := configurePrinterWriter(format, out)
+	if err != nil {
+		return false, err
+	}
+	encoder, err := configureEncoder()
+	if err != nil {
+		return false, err
+	}
+	printer := yqlib.NewPrinter(encoder, printerWriter)
+	if nulSepOutput {
+		printer.SetNulSepOutput(true)
+	}
+
+	decoder, err := configureDecoder(false)
+	if err != nil {
+		return false, err
+	}
+
+	inputFilename := filename
+	if frontMatter != "" {
+		frontMatterHandler := yqlib.NewFrontMatterHandler(filename)
+		err = frontMatterHandler.Split()
+		if err != nil {
+			return false, err
+		}
+		inputFilename = frontMatterHandler.GetYamlFrontMatterFilename()
+
+		if frontMatter == "process" {
+			reader := frontMatterHandler.GetContentReader()
+			printer.SetAppendix(reader)
+			defer yqlib.SafelyCloseReader(reader)
+		}
+		defer frontMatterHandler.CleanUp()
+	}
+
+	err = yqlib.NewStreamEvaluator().EvaluateFiles(processExpression(expression), []string{inputFilename}, printer, decoder)
+	if err == nil && exitStatus && !printer.PrintedAnything() {
+		err = errors.New("no matches found")
+	}
+	completedSuccessfully = err == nil
+	return false, err
+}
@@ -17,6 +17,9 @@ func newRuneVar(p *rune) *runeValue {
 }
 
 func (r *runeValue) String() string {
+	if *r == 0 {
+		return ""
+	}
 	return string(*r)
 }
 
@@ -147,11 +150,43 @@ yq -P -oy sample.json
 	}
 	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredXMLPreferences.SkipProcInst, "xml-skip-proc-inst", yqlib.ConfiguredXMLPreferences.SkipProcInst, "skip over process instructions (e.g. <?xml version=\"1\"?>)")
 	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredXMLPreferences.SkipDirectives, "xml-skip-directives", yqlib.ConfiguredXMLPreferences.SkipDirectives, "skip over directives (e.g. <!DOCTYPE thing cat>)")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredXMLPreferences.Lossless, "xml-lossless", yqlib.ConfiguredXMLPreferences.Lossless, "decode xml into an ordered form that keeps all text, cdata, comments and namespaces, and encodes back to the same document")
+	rootCmd.PersistentFlags().StringVar(&yqlib.ConfiguredXMLPreferences.CDataName, "xml-cdata-name", yqlib.ConfiguredXMLPreferences.CDataName, "name for xml cdata sections in lossless mode (e.g. <![CDATA[text]]>)")
+	if err = rootCmd.RegisterFlagCompletionFunc("xml-cdata-name", cobra.NoFileCompletions); err != nil {
+		panic(err)
+	}
+	rootCmd.PersistentFlags().StringVar(&yqlib.ConfiguredXMLPreferences.CommentName, "xml-comment-name", yqlib.ConfiguredXMLPreferences.CommentName, "name for xml comments in lossless mode")
+	if err = rootCmd.RegisterFlagCompletionFunc("xml-comment-name", cobra.NoFileCompletions); err != nil {
+		panic(err)
+	}
+
+	rootCmd.PersistentFlags().StringVar(&jsonDuplicateKeys, "json-duplicate-keys", jsonDuplicateKeys, "how duplicate keys in JSON input are handled: error, first (keep the first value) or last (keep the last value)")
+	if err = rootCmd.RegisterFlagCompletionFunc("json-duplicate-keys", cobra.FixedCompletions([]string{"error", "first", "last"}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		panic(err)
+	}
+
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredJSONPreferences.SkipInvalid, "skip-invalid", yqlib.ConfiguredJSONPreferences.SkipInvalid, "log and skip lines of ndjson input that are not valid JSON, instead of stopping")
 
 	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredCsvPreferences.AutoParse, "csv-auto-parse", yqlib.ConfiguredCsvPreferences.AutoParse, "parse CSV YAML/JSON values")
 	rootCmd.PersistentFlags().Var(newRuneVar(&yqlib.ConfiguredCsvPreferences.Separator), "csv-separator", "CSV Separator character")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredCsvPreferences.NoHeader, "csv-no-header", yqlib.ConfiguredCsvPreferences.NoHeader, "read each CSV row as an array, rather than naming the values with the first row")
+	rootCmd.PersistentFlags().StringSliceVar(&yqlib.ConfiguredCsvPreferences.ColumnNames, "csv-column-names", yqlib.ConfiguredCsvPreferences.ColumnNames, "names of the CSV columns, for input without a header row")
+	rootCmd.PersistentFlags().Var(newRuneVar(&yqlib.ConfiguredCsvPreferences.Comment), "csv-comment", "CSV comment character, lines starting with it are skipped")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredCsvPreferences.LazyQuotes, "csv-lazy-quotes", yqlib.ConfiguredCsvPreferences.LazyQuotes, "allow quotes in unquoted CSV fields and unescaped quotes in quoted fields")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredCsvPreferences.TrimLeadingSpace, "csv-trim-leading-space", yqlib.ConfiguredCsvPreferences.TrimLeadingSpace, "ignore leading white space in CSV fields")
+	rootCmd.PersistentFlags().IntVar(&yqlib.ConfiguredCsvPreferences.SkipRows, "csv-skip-rows", yqlib.ConfiguredCsvPreferences.SkipRows, "number of lines to skip before the CSV table")
+	rootCmd.PersistentFlags().StringSliceVar(&yqlib.ConfiguredCsvPreferences.Headers, "csv-headers", yqlib.ConfiguredCsvPreferences.Headers, "columns to encode objects to CSV with, in order. Nested values are named by their dotted path, e.g. address.city")
+	rootCmd.PersistentFlags().StringVar(&yqlib.ConfiguredCsvPreferences.MissingValue, "csv-missing-value", yqlib.ConfiguredCsvPreferences.MissingValue, "value to encode to CSV for columns an object does not have")
 
 	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredTsvPreferences.AutoParse, "tsv-auto-parse", yqlib.ConfiguredTsvPreferences.AutoParse, "parse TSV YAML/JSON values")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredTsvPreferences.NoHeader, "tsv-no-header", yqlib.ConfiguredTsvPreferences.NoHeader, "read each TSV row as an array, rather than naming the values with the first row")
+	rootCmd.PersistentFlags().StringSliceVar(&yqlib.ConfiguredTsvPreferences.ColumnNames, "tsv-column-names", yqlib.ConfiguredTsvPreferences.ColumnNames, "names of the TSV columns, for input without a header row")
+	rootCmd.PersistentFlags().Var(newRuneVar(&yqlib.ConfiguredTsvPreferences.Comment), "tsv-comment", "TSV comment character, lines starting with it are skipped")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredTsvPreferences.LazyQuotes, "tsv-lazy-quotes", yqlib.ConfiguredTsvPreferences.LazyQuotes, "allow quotes in unquoted TSV fields and unescaped quotes in quoted fields")
+	rootCmd.PersistentFlags().BoolVar(&yqlib.ConfiguredTsvPreferences.TrimLeadingSpace, "tsv-trim-leading-space", yqlib.ConfiguredTsvPreferences.TrimLeadingSpace, "ignore leading white space in TSV fields")
+	rootCmd.PersistentFlags().IntVar(&yqlib.ConfiguredTsvPreferences.SkipRows, "tsv-skip-rows", yqlib.ConfiguredTsvPreferences.SkipRows, "number of lines to skip before the TSV table")
+	rootCmd.PersistentFlags().StringSliceVar(&yqlib.ConfiguredTsvPreferences.Headers, "tsv-headers", yqlib.ConfiguredTsvPreferences.Headers, "columns to encode objects to TSV with, in order. Nested values are named by their dotted path, e.g. address.city")
+	rootCmd.PersistentFlags().StringVar(&yqlib.ConfiguredTsvPreferences.MissingValue, "tsv-missing-value", yqlib.ConfiguredTsvPreferences.MissingValue, "value to encode to TSV for columns an object does not have")
 
 	rootCmd.PersistentFlags().StringVar(&yqlib.ConfiguredLuaPreferences.DocPrefix, "lua-prefix", yqlib.ConfiguredLuaPreferences.DocPrefix, "prefix")
 	if err = rootCmd.RegisterFlagCompletionFunc("lua-prefix", cobra.NoFileCompletions); err != nil {
@@ -171,13 +206,22 @@ yq -P -oy sample.json
 
 	rootCmd.PersistentFlags().BoolVarP(&nullInput, "null-input", "n", false, "Don't read input, simply evaluate the expression given. Useful for creating docs from scratch.")
 	rootCmd.PersistentFlags().BoolVarP(&noDocSeparators, "no-doc", "N", false, "Don't print document separators (---)")
+	rootCmd.PersistentFlags().BoolVarP(&streamInput, "stream", "", false, "parse yaml, json or xml input as a stream of [path, leaf] events, like tostream. The input is streamed as it is read, so huge files can be processed in constant memory.")
 
 	rootCmd.PersistentFlags().IntVarP(&indent, "indent", "I", 2, "sets indent level for output")
 	if err = rootCmd.RegisterFlagCompletionFunc("indent", cobra.NoFileCompletions); err != nil {
 		panic(err)
 	}
 	rootCmd.Flags().BoolVarP(&version, "version", "V", false, "Print version information and quit")
-	rootCmd.PersistentFlags().BoolVarP(&writeInplace, "inplace", "i", false, "update the file in place of first file given.")
+	rootCmd.PersistentFlags().BoolVarP(&writeInplace, "inplace", "i", false, "update each file given in place. With eval-all, the result is written to the first file given.")
+	rootCmd.PersistentFlags().BoolVarP(&checkInPlace, "check", "", false, "with -i, do not write the files, exit with a non-zero status if any file would be changed.")
+	rootCmd.PersistentFlags().BoolVarP(&diffInPlace, "diff", "", false, "with -i, do not write the files, print a unified diff of the changes instead.")
+	rootCmd.PersistentFlags().StringVarP(&backupSuffix, "backup", "", "", "with -i, keep a copy of each original file with the given suffix (default .bak).")
+	rootCmd.PersistentFlags().Lookup("backup").NoOptDefVal = ".bak"
+	rootCmd.PersistentFlags().StringVarP(&writeStrategy, "write-strategy", "", "rename", "with -i, how files are updated: rename (atomically replace the file) or truncate (write into the original file, keeping hard links).")
+	if err = rootCmd.RegisterFlagCompletionFunc("write-strategy", cobra.FixedCompletions([]string{"rename", "truncate"}, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		panic(err)
+	}
 	rootCmd.PersistentFlags().VarP(unwrapScalarFlag, "unwrapScalar", "r", "unwrap scalar, print the value with no quotes, colors or comments. Defaults to true for yaml")
 	rootCmd.PersistentFlags().Lookup("unwrapScalar").NoOptDefVal = "true"
 	rootCmd.PersistentFlags().BoolVarP(&nulSepOutput, "nul-output", "0", false, "Use NUL char to separate values. If unwrap scalar is also set, fail if unwrapped scalar contains NUL char.")
@@ -211,9 +255,16 @@ yq -P -oy sample.json
 		panic(err)
 	}
 
+	rootCmd.PersistentFlags().StringArrayVarP(&yqlib.ConfiguredLibraryPreferences.Paths, "library-path", "L", yqlib.ConfiguredLibraryPreferences.Paths, "directory to search for libraries used by import and include, can be given multiple times. Searched before YQ_LIBRARY_PATH.")
+	if err = rootCmd.MarkPersistentFlagDirname("library-path"); err != nil {
+		panic(err)
+	}
+
 	rootCmd.AddCommand(
 		createEvaluateSequenceCommand(),
 		createEvaluateAllCommand(),
+		createValidateCommand(),
+		createDiffCommand(),
 		completionCmd,
 	)
 	return rootCmd
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/mikefarah/yq/v4/pkg/yqlib"
@@ -50,6 +51,29 @@ func initCommand(cmd *cobra.Command, args []string) (string, []string, error) {
 		return "", nil, fmt.Errorf("front matter flag only applicable when giving an expression and at least one file")
 	}
 
+	if (checkInPlace || diffInPlace) && !writeInplace {
+		return "", nil, fmt.Errorf("check and diff flags only applicable when updating files in place (-i)")
+	}
+	yqlib.ConfiguredWriteInPlacePreferences.Check = checkInPlace
+	yqlib.ConfiguredWriteInPlacePreferences.Diff = diffInPlace
+	yqlib.ConfiguredWriteInPlacePreferences.DiffWriter = cmd.OutOrStdout()
+
+	if (backupSuffix != "" || cmd.Flags().Changed("write-strategy")) && !writeInplace {
+		return "", nil, fmt.Errorf("backup and write-strategy flags only applicable when updating files in place (-i)")
+	}
+	strategy, err := yqlib.WriteStrategyFromString(writeStrategy)
+	if err != nil {
+		return "", nil, err
+	}
+	yqlib.ConfiguredWriteInPlacePreferences.Strategy = strategy
+	yqlib.ConfiguredWriteInPlacePreferences.BackupSuffix = backupSuffix
+
+	duplicateKeys, err := yqlib.JsonDuplicateKeysFromString(jsonDuplicateKeys)
+	if err != nil {
+		return "", nil, err
+	}
+	yqlib.ConfiguredJSONPreferences.DuplicateKeys = duplicateKeys
+
 	if writeInplace && splitFileExp != "" {
 		return "", nil, fmt.Errorf("write in place cannot be used with split file")
 	}
@@ -89,6 +113,12 @@ func initCommand(cmd *cobra.Command, args []string) (string, []string, error) {
 		outputFormat = "yaml"
 	}
 
+	if yqlib.ConfiguredJSONPreferences.SkipInvalid {
+		if inputFormatType, err := yqlib.FormatFromString(inputFormat); err != nil || inputFormatType != yqlib.NDJSONFormat {
+			return "", nil, fmt.Errorf("skip-invalid flag only applicable to ndjson input")
+		}
+	}
+
 	outputFormatType, err := yqlib.FormatFromString(outputFormat)
 
 	if err != nil {
@@ -115,7 +145,15 @@ func configureDecoder(evaluateTogether bool) (yqlib.Decoder, error) {
 	}
 	yqlib.ConfiguredYamlPreferences.EvaluateTogether = evaluateTogether
 
-	yqlibDecoder := format.DecoderFactory()
+	var yqlibDecoder yqlib.Decoder
+	if streamInput {
+		yqlibDecoder, err = yqlib.NewStreamDecoder(format)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		yqlibDecoder = format.DecoderFactory()
+	}
 	if yqlibDecoder == nil {
 		return nil, fmt.Errorf("no support for %s input format", inputFormat)
 	}
@@ -165,6 +203,22 @@ func configureEncoder() (yqlib.Encoder, error) {
 	return encoder, err
 }
 
+// checkInPlaceResult lists the files that would be changed by --check, and returns an error if there are any.
+func checkInPlaceResult(cmd *cobra.Command, changedFiles []string, totalFiles int) error {
+	if !checkInPlace || len(changedFiles) == 0 {
+		return nil
+	}
+	if !diffInPlace {
+		// the diff already shows which files would change
+		for _, filename := range changedFiles {
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), filename); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("%v of %v files would be changed", len(changedFiles), totalFiles)
+}
+
 // this is a hack to enable backwards compatibility with githubactions (which pipe /dev/null into everything)
 // and being able to call yq with the filename as a single parameter
 //
@@ -231,6 +285,8 @@ func processArgs(originalArgs []string) (string, []string, error) {
 		}
 		//replace \r\n (windows) with good ol' unix file endings.
 		expression = strings.ReplaceAll(string(expressionBytes), "\r\n", "\n")
+		// libraries can be kept next to the expression file
+		yqlib.ConfiguredLibraryPreferences.ExpressionDirectory = filepath.Dir(expressionFile)
 	}
 
 	yqlib.GetLogger().Debugf("processed args: %v", args)
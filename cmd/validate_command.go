@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mikefarah/yq/v4/pkg/yqlib"
+	"github.com/spf13/cobra"
+)
+
+var schemaFile = ""
+
+func createValidateCommand() *cobra.Command {
+	var cmdValidate = &cobra.Command{
+		Use:   "validate --schema [schema_file] [yaml_file1]...",
+		Short: "Validates files against a JSON Schema",
+		Example: `
+# Validate a file against a schema (in JSON or YAML)
+yq validate --schema schema.json deployment.yaml
+
+# Validate several files, this exits with a non-zero status if any are invalid
+yq validate --schema schema.json manifests/*.yaml
+
+# Pipe from STDIN
+cat deployment.yaml | yq validate --schema schema.json -
+`,
+		Long: `yq is a portable command-line data file processor (https://github.com/mikefarah/yq/) 
+See https://mikefarah.gitbook.io/yq/ for detailed documentation and examples.
+
+## Validate ##
+Validates every document of every file against a JSON Schema, printing each error with its path, line and column.
+Exits with a non-zero status if any document is invalid.
+`,
+		RunE: validate,
+	}
+	cmdValidate.Flags().StringVarP(&schemaFile, "schema", "", "", "JSON Schema file (in JSON or YAML) to validate against.")
+	if err := cmdValidate.MarkFlagRequired("schema"); err != nil {
+		panic(err)
+	}
+	if err := cmdValidate.MarkFlagFilename("schema"); err != nil {
+		panic(err)
+	}
+	return cmdValidate
+}
+
+func validateDecoder(filename string) (yqlib.Decoder, error) {
+	formatName := inputFormat
+	if formatName == "" || formatName == "auto" || formatName == "a" {
+		formatName = yqlib.FormatStringFromFilename(filename)
+	}
+	format, err := yqlib.FormatFromString(formatName)
+	if err != nil {
+		// unknown file type, default to yaml
+		format = yqlib.YamlFormat
+	}
+	decoder := format.DecoderFactory()
+	if decoder == nil {
+		return nil, fmt.Errorf("no support for %s input format", formatName)
+	}
+	return decoder, nil
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	schema, err := yqlib.LoadSchemaFile(schemaFile)
+	if err != nil {
+		return fmt.Errorf("could not load schema: %w", err)
+	}
+	validator := yqlib.NewSchemaValidator(schema)
+
+	out := cmd.OutOrStdout()
+	totalErrors := 0
+	for _, filename := range args {
+		decoder, err := validateDecoder(filename)
+		if err != nil {
+			return err
+		}
+		errors, err := validator.ValidateFile(filename, decoder)
+		if err != nil {
+			return err
+		}
+		for _, validationError := range errors {
+			if _, err := fmt.Fprintln(out, validationError.Error()); err != nil {
+				return err
+			}
+		}
+		totalErrors = totalErrors + len(errors)
+	}
+
+	if totalErrors > 0 {
+		return fmt.Errorf("%v validation error(s) found", totalErrors)
+	}
+	return nil
+}
@@ -74,6 +74,15 @@ type CandidateNode struct {
 
 	document uint // the document index of this node
 	filename string
+	// rawValue is the value as it was written in the input, for encoders that can
+	// write it back the same way while the value is unchanged
+	rawValue string
+	// tagSpace is the white space in an xml start tag written before an attribute (on the
+	// key of the attribute), or before the end of the tag (on the name of the element)
+	tagSpace string
+	// blankLineBefore is whether there was a blank line before the node in the input, for
+	// encoders that keep the lines of a document grouped as they were
+	blankLineBefore bool
 
 	Line   int
 	Column int
@@ -352,6 +361,10 @@ func (n *CandidateNode) doCopy(cloneContent bool) *CandidateNode {
 		document:  n.document,
 		filename:  n.filename,
 		fileIndex: n.fileIndex,
+		rawValue:  n.rawValue,
+		tagSpace:  n.tagSpace,
+
+		blankLineBefore: n.blankLineBefore,
 
 		Line:   n.Line,
 		Column: n.Column,
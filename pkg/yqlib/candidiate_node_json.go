@@ -5,10 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/goccy/go-json"
 )
 
+var jsonNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
 func (o *CandidateNode) setScalarFromJson(value interface{}) error {
 	o.Kind = ScalarNode
 	switch rawData := value.(type) {
@@ -138,6 +144,25 @@ func (o *CandidateNode) MarshalJSON() ([]byte, error) {
 		return buf.Bytes(), err
 	case ScalarNode:
 		log.Debugf("MarshalJSON ScalarNode")
+		if (o.Tag == "!!int" || o.Tag == "!!float") && jsonNumberRegex.MatchString(o.Value) {
+			// written as is, so numbers read from json keep their precision
+			buf.WriteString(o.Value)
+			return buf.Bytes(), nil
+		}
+		if o.Tag == "!!float" {
+			// json has no NaN or infinity, so like jq they are null and the largest float
+			switch strings.ToLower(o.Value) {
+			case ".nan":
+				buf.WriteString("null")
+				return buf.Bytes(), nil
+			case ".inf", "+.inf":
+				buf.WriteString(strconv.FormatFloat(math.MaxFloat64, 'g', -1, 64))
+				return buf.Bytes(), nil
+			case "-.inf":
+				buf.WriteString(strconv.FormatFloat(-math.MaxFloat64, 'g', -1, 64))
+				return buf.Bytes(), nil
+			}
+		}
 		value, err := o.GetValueRep()
 		if err != nil {
 			return buf.Bytes(), err
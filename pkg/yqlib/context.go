@@ -13,6 +13,7 @@ type Context struct {
 	Variables      map[string]*list.List
 	DontAutoCreate bool
 	datetimeLayout string
+	functions      map[string]*functionDefinition
 }
 
 func (n *Context) SingleReadonlyChildContext(candidate *CandidateNode) Context {
@@ -54,8 +55,25 @@ func (n *Context) SetVariable(name string, value *list.List) {
 	n.Variables[name] = value
 }
 
+func (n *Context) GetFunction(name string, arity int) *functionDefinition {
+	if n.functions == nil {
+		return nil
+	}
+	return n.functions[functionKey(name, arity)]
+}
+
+func (n *Context) SetFunction(definition *functionDefinition) {
+	// functions are shared between child contexts, so copy on write
+	functions := make(map[string]*functionDefinition, len(n.functions)+1)
+	for key, value := range n.functions {
+		functions[key] = value
+	}
+	functions[functionKey(definition.name, len(definition.params))] = definition
+	n.functions = functions
+}
+
 func (n *Context) ChildContext(results *list.List) Context {
-	clone := Context{DontAutoCreate: n.DontAutoCreate, datetimeLayout: n.datetimeLayout}
+	clone := Context{DontAutoCreate: n.DontAutoCreate, datetimeLayout: n.datetimeLayout, functions: n.functions}
 	clone.Variables = make(map[string]*list.List)
 	for variableKey, originalValueList := range n.Variables {
 
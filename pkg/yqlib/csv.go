@@ -3,6 +3,20 @@ package yqlib
 type CsvPreferences struct {
 	Separator rune
 	AutoParse bool
+	// NoHeader reads every row as an array of values, rather than naming them with the first row
+	NoHeader bool
+	// ColumnNames name the values of each row, when the input has no header row
+	ColumnNames []string
+	// Comment is the character that starts comment lines, if there is one
+	Comment          rune
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+	// SkipRows is the number of lines to skip at the start of the input, e.g. a title above the table
+	SkipRows int
+	// Headers are the columns to encode objects with, in order. Nested values are named by their dotted path, e.g. address.city
+	Headers []string
+	// MissingValue is encoded for columns an object does not have
+	MissingValue string
 }
 
 func NewDefaultCsvPreferences() CsvPreferences {
@@ -78,9 +78,9 @@ const expectedYamlFromCSVMissingData = `- name: Gary
   likesApples: false
 `
 
-const csvSimpleMissingData = `name,numberOfCats,height
-Gary,1,168.8
-Samantha's Rabbit,,-188.8
+const csvSimpleMissingData = `name,numberOfCats,height,likesApples
+Gary,1,168.8,
+Samantha's Rabbit,,-188.8,false
 `
 
 const csvTestSimpleYaml = `- [i, like, csv]
@@ -137,11 +137,67 @@ var csvScenarios = []formatScenario{
 	},
 	{
 		description:    "Encode array of objects to csv - missing fields behaviour",
-		subdescription: "First entry is used to determine the headers, and it is missing 'likesApples', so it is not included in the csv. Second entry does not have 'numberOfCats' so that is blank",
+		subdescription: "The headers are every field found in the entries, in the order they are first seen. The first entry does not have 'likesApples' and the second does not have 'numberOfCats', so those are blank.",
 		input:          expectedYamlFromCSVMissingData,
 		expected:       csvSimpleMissingData,
 		scenarioType:   "encode-csv",
 	},
+	{
+		description:    "Encode array of objects to csv - headers of the first entry",
+		subdescription: "Older versions of yq only used the fields of the first entry as the headers. Choose them with `--csv-headers` to get the same output.",
+		input:          expectedYamlFromCSVMissingData,
+		expected:       "name,numberOfCats,height\nGary,1,168.8\nSamantha's Rabbit,,-188.8\n",
+		scenarioType:   "encode-csv-first-headers",
+	},
+	{
+		description:    "Encode nested objects to csv",
+		subdescription: "Nested maps and arrays are flattened into columns named by their dotted path.",
+		input:          "- name: Gary\n  address:\n    city: Sydney\n    postcode: 2000\n  pets: [cat, dog]\n- name: Samantha\n  address:\n    city: Melbourne\n    postcode: 3000\n  pets: [rabbit, fish]\n",
+		expected:       "name,address.city,address.postcode,pets.0,pets.1\nGary,Sydney,2000,cat,dog\nSamantha,Melbourne,3000,rabbit,fish\n",
+		scenarioType:   "encode-csv",
+	},
+	{
+		description:    "Encode array of objects to csv with chosen columns",
+		subdescription: "Only the given columns are encoded, in the given order. Nested values are selected by their dotted path.",
+		input:          "- name: Gary\n  numberOfCats: 1\n  address:\n    city: Sydney\n- name: Samantha\n  numberOfCats: 2\n  address:\n    city: Melbourne\n",
+		expected:       "name,address.city,numberOfCats\nGary,Sydney,1\nSamantha,Melbourne,2\n",
+		scenarioType:   "encode-csv-headers",
+	},
+	{
+		description:    "Encode array of objects to csv with a missing value",
+		subdescription: "Columns an object does not have are filled with the missing value.",
+		input:          "- name: Gary\n  numberOfCats: 1\n- name: Samantha\n",
+		expected:       "name,numberOfCats\nGary,1\nSamantha,N/A\n",
+		scenarioType:   "encode-csv-missing-value",
+	},
+	{
+		description:   "Encode chosen columns that are not scalars",
+		skipDoc:       true,
+		input:         "- name: Gary\n  numberOfCats: 1\n  address:\n    city: Sydney\n- name: Samantha\n  numberOfCats: [1, 2]\n  address:\n    city: Melbourne\n",
+		expectedError: "csv header 'numberOfCats' of child[1] is a !!seq, only scalar values can be encoded",
+		scenarioType:  "encode-csv-headers",
+	},
+	{
+		description:   "Encode chosen columns that are maps",
+		skipDoc:       true,
+		input:         "- name: Gary\n  numberOfCats: 1\n  address:\n    city: {name: Sydney}\n",
+		expectedError: "csv header 'address.city' of child[0] is a !!map, only scalar values can be encoded",
+		scenarioType:  "encode-csv-headers",
+	},
+	{
+		description:   "Encode array of scalars and objects",
+		skipDoc:       true,
+		input:         "- name: Gary\n- cat\n",
+		expectedError: "csv object encoding only works for arrays of objects, child[1] is a !!str",
+		scenarioType:  "encode-csv",
+	},
+	{
+		description:  "Encode with aliases",
+		skipDoc:      true,
+		input:        "- {name: Gary, address: &addr {city: Sydney}}\n- {name: Samantha, address: *addr}\n",
+		expected:     "name,address.city\nGary,Sydney\nSamantha,Sydney\n",
+		scenarioType: "encode-csv",
+	},
 	{
 		description:  "decode csv missing",
 		skipDoc:      true,
@@ -208,6 +264,69 @@ var csvScenarios = []formatScenario{
 		expected:     "cat\n",
 		scenarioType: "roundtrip-csv",
 	},
+	{
+		description:    "Parse CSV without a header row",
+		subdescription: "Each row is read as an array of values.",
+		input:          "Gary,1\nSamantha's Rabbit,2\n",
+		expected:       "- - Gary\n  - 1\n- - Samantha's Rabbit\n  - 2\n",
+		scenarioType:   "decode-csv-no-header",
+	},
+	{
+		description:    "Parse CSV with column names",
+		subdescription: "Names the columns of input that has no header row.",
+		input:          "Gary,1\nSamantha's Rabbit,2\n",
+		expected:       "- name: Gary\n  numberOfCats: 1\n- name: Samantha's Rabbit\n  numberOfCats: 2\n",
+		scenarioType:   "decode-csv-column-names",
+	},
+	{
+		description:   "Rows must match the column names",
+		skipDoc:       true,
+		input:         "Gary,1,extra\n",
+		expectedError: "bad file 'sample.yml': record on line 1: wrong number of fields",
+		scenarioType:  "decode-csv-column-names",
+	},
+	{
+		description:    "Parse CSV with comments",
+		subdescription: "Lines starting with the comment character are skipped.",
+		input:          "# cats by owner\nname,numberOfCats\n# Gary has more now\nGary,1\n",
+		expected:       "- name: Gary\n  numberOfCats: 1\n",
+		scenarioType:   "decode-csv-comment",
+	},
+	{
+		description:    "Parse CSV with lazy quotes",
+		subdescription: "Allows quotes inside unquoted fields, and unescaped quotes inside quoted fields.",
+		input:          "name,nickname\nGary,The \"Cat\" Man\n",
+		expected:       "- name: Gary\n  nickname: The \"Cat\" Man\n",
+		scenarioType:   "decode-csv-lazy-quotes",
+	},
+	{
+		description:   "Bare quotes need lazy quotes",
+		skipDoc:       true,
+		input:         "name,nickname\nGary,The \"Cat\" Man\n",
+		expectedError: "bad file 'sample.yml': parse error on line 2, column 10: bare \" in non-quoted-field, use lazy quotes to allow them",
+		scenarioType:  "decode-csv",
+	},
+	{
+		description:    "Parse CSV trimming leading space",
+		subdescription: "Leading white space in each field is ignored.",
+		input:          "name, numberOfCats\nGary,  1\n",
+		expected:       "- name: Gary\n  numberOfCats: 1\n",
+		scenarioType:   "decode-csv-trim",
+	},
+	{
+		description:    "Parse CSV skipping rows",
+		subdescription: "Skips lines before the table, such as a title. These lines need not be valid CSV.",
+		input:          "Cat \"census\", 2024\nname,numberOfCats\nGary,1\n",
+		expected:       "- name: Gary\n  numberOfCats: 1\n",
+		scenarioType:   "decode-csv-skip-rows",
+	},
+	{
+		description:  "Empty CSV",
+		skipDoc:      true,
+		input:        "",
+		expected:     "",
+		scenarioType: "decode-csv",
+	},
 	{
 		description:    "Parse TSV into an array of objects",
 		subdescription: "First row is assumed to be the header row.",
@@ -224,14 +343,73 @@ var csvScenarios = []formatScenario{
 	},
 }
 
+type csvOptionScenario struct {
+	flags string
+	prefs func(prefs *CsvPreferences)
+}
+
+var csvOptionScenarios = map[string]csvOptionScenario{
+	"decode-csv-no-header": {"--csv-no-header", func(prefs *CsvPreferences) { prefs.NoHeader = true }},
+	"decode-csv-column-names": {"--csv-column-names name,numberOfCats", func(prefs *CsvPreferences) {
+		prefs.ColumnNames = []string{"name", "numberOfCats"}
+	}},
+	"decode-csv-comment":     {"--csv-comment '#'", func(prefs *CsvPreferences) { prefs.Comment = '#' }},
+	"decode-csv-lazy-quotes": {"--csv-lazy-quotes", func(prefs *CsvPreferences) { prefs.LazyQuotes = true }},
+	"decode-csv-trim":        {"--csv-trim-leading-space", func(prefs *CsvPreferences) { prefs.TrimLeadingSpace = true }},
+	"decode-csv-skip-rows":   {"--csv-skip-rows 1", func(prefs *CsvPreferences) { prefs.SkipRows = 1 }},
+	"encode-csv-headers": {"--csv-headers name,address.city,numberOfCats", func(prefs *CsvPreferences) {
+		prefs.Headers = []string{"name", "address.city", "numberOfCats"}
+	}},
+	"encode-csv-missing-value": {"--csv-missing-value N/A", func(prefs *CsvPreferences) { prefs.MissingValue = "N/A" }},
+	"encode-csv-first-headers": {"--csv-headers name,numberOfCats,height", func(prefs *CsvPreferences) {
+		prefs.Headers = []string{"name", "numberOfCats", "height"}
+	}},
+}
+
+func csvOptionPreferences(scenarioType string) CsvPreferences {
+	prefs := NewDefaultCsvPreferences()
+	csvOptionScenarios[scenarioType].prefs(&prefs)
+	return prefs
+}
+
+func testCSVEncodeScenario(t *testing.T, s formatScenario, prefs CsvPreferences) {
+	if s.expectedError == "" {
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(prefs)), s.description)
+		return
+	}
+	_, err := processFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(prefs))
+	if err == nil {
+		t.Errorf("Expected error '%v' but it worked", s.expectedError)
+	} else {
+		test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+	}
+}
+
+func testCSVDecodeScenario(t *testing.T, s formatScenario, prefs CsvPreferences) {
+	if s.expectedError == "" {
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewCSVObjectDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+		return
+	}
+	_, err := processFormatScenario(s, NewCSVObjectDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences))
+	if err == nil {
+		t.Errorf("Expected error '%v' but it worked", s.expectedError)
+	} else {
+		test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+	}
+}
+
 func testCSVScenario(t *testing.T, s formatScenario) {
 	switch s.scenarioType {
 	case "encode-csv":
-		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(ConfiguredCsvPreferences)), s.description)
+		testCSVEncodeScenario(t, s, ConfiguredCsvPreferences)
+	case "encode-csv-headers", "encode-csv-missing-value", "encode-csv-first-headers":
+		testCSVEncodeScenario(t, s, csvOptionPreferences(s.scenarioType))
 	case "encode-tsv":
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(ConfiguredTsvPreferences)), s.description)
 	case "decode-csv":
-		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewCSVObjectDecoder(ConfiguredCsvPreferences), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+		testCSVDecodeScenario(t, s, ConfiguredCsvPreferences)
+	case "decode-csv-no-header", "decode-csv-column-names", "decode-csv-comment", "decode-csv-lazy-quotes", "decode-csv-trim", "decode-csv-skip-rows":
+		testCSVDecodeScenario(t, s, csvOptionPreferences(s.scenarioType))
 	case "decode-csv-no-auto":
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewCSVObjectDecoder(CsvPreferences{Separator: ',', AutoParse: false}), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
 	case "decode-tsv-object":
@@ -293,7 +471,27 @@ func documentCSVDecodeObjectNoAutoScenario(w *bufio.Writer, s formatScenario, fo
 	)
 }
 
-func documentCSVEncodeScenario(w *bufio.Writer, s formatScenario, formatType string) {
+func documentCSVDecodeOptionScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.csv file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```csv\n%v\n```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+	writeOrPanic(w, fmt.Sprintf("```bash\nyq -p=csv %v sample.csv\n```\n", csvOptionScenarios[s.scenarioType].flags))
+	writeOrPanic(w, "will output\n")
+
+	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n",
+		mustProcessFormatScenario(s, NewCSVObjectDecoder(csvOptionPreferences(s.scenarioType)), NewYamlEncoder(ConfiguredYamlPreferences))),
+	)
+}
+
+func documentCSVEncodeScenario(w *bufio.Writer, s formatScenario, formatType string, prefs CsvPreferences, flags string) {
 	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
 
 	if s.subdescription != "" {
@@ -309,20 +507,14 @@ func documentCSVEncodeScenario(w *bufio.Writer, s formatScenario, formatType str
 	expression := s.expression
 
 	if expression != "" {
-		writeOrPanic(w, fmt.Sprintf("```bash\nyq -o=%v '%v' sample.yml\n```\n", formatType, expression))
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq -o=%v%v '%v' sample.yml\n```\n", formatType, flags, expression))
 	} else {
-		writeOrPanic(w, fmt.Sprintf("```bash\nyq -o=%v sample.yml\n```\n", formatType))
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq -o=%v%v sample.yml\n```\n", formatType, flags))
 	}
 	writeOrPanic(w, "will output\n")
 
-	separator := ','
-	if formatType == "tsv" {
-		separator = '\t'
-	}
-	csvPrefs := NewDefaultCsvPreferences()
-	csvPrefs.Separator = separator
 	writeOrPanic(w, fmt.Sprintf("```%v\n%v```\n\n", formatType,
-		mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(csvPrefs))),
+		mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewCsvEncoder(prefs))),
 	)
 }
 
@@ -368,9 +560,11 @@ func documentCSVScenario(_ *testing.T, w *bufio.Writer, i interface{}) {
 	}
 	switch s.scenarioType {
 	case "encode-csv":
-		documentCSVEncodeScenario(w, s, "csv")
+		documentCSVEncodeScenario(w, s, "csv", NewDefaultCsvPreferences(), "")
 	case "encode-tsv":
-		documentCSVEncodeScenario(w, s, "tsv")
+		documentCSVEncodeScenario(w, s, "tsv", NewDefaultTsvPreferences(), "")
+	case "encode-csv-headers", "encode-csv-missing-value", "encode-csv-first-headers":
+		documentCSVEncodeScenario(w, s, "csv", csvOptionPreferences(s.scenarioType), " "+csvOptionScenarios[s.scenarioType].flags)
 	case "decode-csv":
 		documentCSVDecodeObjectScenario(w, s, "csv")
 	case "decode-csv-no-auto":
@@ -379,6 +573,8 @@ func documentCSVScenario(_ *testing.T, w *bufio.Writer, i interface{}) {
 		documentCSVDecodeObjectScenario(w, s, "tsv")
 	case "roundtrip-csv":
 		documentCSVRoundTripScenario(w, s, "csv")
+	case "decode-csv-no-header", "decode-csv-column-names", "decode-csv-comment", "decode-csv-lazy-quotes", "decode-csv-trim", "decode-csv-skip-rows":
+		documentCSVDecodeOptionScenario(w, s)
 
 	default:
 		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
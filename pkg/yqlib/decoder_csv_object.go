@@ -1,8 +1,10 @@
 package yqlib
 
 import (
+	"bufio"
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/dimchansky/utfbom"
@@ -10,6 +12,7 @@ import (
 
 type csvObjectDecoder struct {
 	prefs    CsvPreferences
+	input    *bufio.Reader
 	reader   csv.Reader
 	finished bool
 }
@@ -21,8 +24,15 @@ func NewCSVObjectDecoder(prefs CsvPreferences) Decoder {
 func (dec *csvObjectDecoder) Init(reader io.Reader) error {
 	cleanReader, enc := utfbom.Skip(reader)
 	log.Debugf("Detected encoding: %s\n", enc)
-	dec.reader = *csv.NewReader(cleanReader)
+	dec.input = bufio.NewReader(cleanReader)
+	dec.reader = *csv.NewReader(dec.input)
 	dec.reader.Comma = dec.prefs.Separator
+	dec.reader.Comment = dec.prefs.Comment
+	dec.reader.LazyQuotes = dec.prefs.LazyQuotes
+	dec.reader.TrimLeadingSpace = dec.prefs.TrimLeadingSpace
+	if len(dec.prefs.ColumnNames) > 0 {
+		dec.reader.FieldsPerRecord = len(dec.prefs.ColumnNames)
+	}
 	dec.finished = false
 	return nil
 }
@@ -46,27 +56,67 @@ func (dec *csvObjectDecoder) createObject(headerRow []string, contentRow []strin
 	return objectNode
 }
 
+func (dec *csvObjectDecoder) createArray(contentRow []string) *CandidateNode {
+	arrayNode := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+
+	for _, content := range contentRow {
+		arrayNode.AddChild(dec.convertToNode(content))
+	}
+	return arrayNode
+}
+
+// skipRows skips the lines before the table, these need not be valid csv.
+func (dec *csvObjectDecoder) skipRows() error {
+	for i := 0; i < dec.prefs.SkipRows; i++ {
+		if _, err := dec.input.ReadString('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (dec *csvObjectDecoder) Decode() (*CandidateNode, error) {
 	if dec.finished {
 		return nil, io.EOF
 	}
-	headerRow, err := dec.reader.Read()
-	log.Debugf(": headerRow%v", headerRow)
-	if err != nil {
+	dec.finished = true
+	if err := dec.skipRows(); err != nil {
 		return nil, err
 	}
 
+	headerRow := dec.prefs.ColumnNames
+	if len(headerRow) == 0 && !dec.prefs.NoHeader {
+		var err error
+		headerRow, err = dec.reader.Read()
+		log.Debugf(": headerRow%v", headerRow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	rootArray := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
 
 	contentRow, err := dec.reader.Read()
+	if errors.Is(err, io.EOF) && len(headerRow) == len(dec.prefs.ColumnNames) {
+		// there is nothing in the input, not even a header row
+		return nil, err
+	}
 
 	for err == nil && len(contentRow) > 0 {
 		log.Debugf("Adding contentRow: %v", contentRow)
-		rootArray.AddChild(dec.createObject(headerRow, contentRow))
+		if len(headerRow) == 0 {
+			rootArray.AddChild(dec.createArray(contentRow))
+		} else {
+			rootArray.AddChild(dec.createObject(headerRow, contentRow))
+		}
 		contentRow, err = dec.reader.Read()
 		log.Debugf("Read next contentRow: %v, %v", contentRow, err)
 	}
 	if !errors.Is(err, io.EOF) {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrBareQuote) {
+			return nil, fmt.Errorf("%w, use lazy quotes to allow them", err)
+		}
 		return nil, err
 	}
 
@@ -3,31 +3,478 @@
 package yqlib
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"io"
-
-	"github.com/goccy/go-json"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
 )
 
+// jsonDecoder reads JSON documents token by token, recording the line and column of each
+// node and keeping numbers exactly as they were written.
 type jsonDecoder struct {
-	decoder json.Decoder
+	prefs JsonPreferences
+	// json5 accepts comments, trailing commas, unquoted keys and the other JSON5 extensions
+	json5  bool
+	reader *bufio.Reader
+
+	line   int
+	column int
+	// position before the last rune read, so it can be unread
+	previousLine   int
+	previousColumn int
+
+	// comments read since they were last attached to a node, json5 only
+	comments []jsonComment
+	// whether a new line was started since the last token, to tell line comments from head comments
+	newLineSinceToken bool
 }
 
 func NewJSONDecoder() Decoder {
-	return &jsonDecoder{}
+	return NewJSONDecoderWithPreferences(ConfiguredJSONPreferences)
+}
+
+// NewJSONDecoderWithPreferences returns a JSON decoder that uses the given preferences,
+// e.g. for how duplicate keys are handled, rather than the configured ones.
+func NewJSONDecoderWithPreferences(prefs JsonPreferences) Decoder {
+	return &jsonDecoder{prefs: prefs}
 }
 
 func (dec *jsonDecoder) Init(reader io.Reader) error {
-	dec.decoder = *json.NewDecoder(reader)
+	dec.reader = bufio.NewReader(reader)
+	dec.line = 1
+	dec.column = 0
+	dec.comments = make([]jsonComment, 0)
+	dec.newLineSinceToken = true
 	return nil
 }
 
 func (dec *jsonDecoder) Decode() (*CandidateNode, error) {
+	r, err := dec.skipWhitespace()
+	if err != nil {
+		return nil, err
+	}
+	headComment := formatJSONComments(dec.takeComments(), "\n")
+	node, err := dec.decodeValue(r)
+	if err != nil {
+		return nil, err
+	}
+	if dec.json5 {
+		node.HeadComment = joinComments([]string{headComment, node.HeadComment}, "\n")
+		if err := dec.decodeTrailingComments(node); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (dec *jsonDecoder) errorf(line int, column int, format string, a ...interface{}) error {
+	return fmt.Errorf("json: line %v, column %v: %v", line, column, fmt.Sprintf(format, a...))
+}
+
+func (dec *jsonDecoder) readRune() (rune, error) {
+	r, _, err := dec.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	dec.previousLine, dec.previousColumn = dec.line, dec.column
+	if r == '\n' {
+		dec.line++
+		dec.column = 0
+	} else {
+		dec.column++
+	}
+	return r, nil
+}
+
+func (dec *jsonDecoder) unreadRune() error {
+	dec.line, dec.column = dec.previousLine, dec.previousColumn
+	return dec.reader.UnreadRune()
+}
+
+// readRuneInValue reads the next rune, where the end of the input is unexpected.
+func (dec *jsonDecoder) readRuneInValue() (rune, error) {
+	r, err := dec.readRune()
+	if errors.Is(err, io.EOF) {
+		return 0, dec.errorf(dec.line, dec.column+1, "unexpected end of JSON input")
+	}
+	return r, err
+}
+
+// skipWhitespace returns the first rune of the next token, collecting any comments on the way.
+func (dec *jsonDecoder) skipWhitespace() (rune, error) {
+	for {
+		r, err := dec.readRune()
+		if errors.Is(err, io.EOF) {
+			dec.endCommentLine()
+			return 0, err
+		} else if err != nil {
+			return 0, err
+		}
+		switch {
+		case r == '\n':
+			dec.newLineSinceToken = true
+			dec.endCommentLine()
+			continue
+		case r == ' ' || r == '\t' || r == '\r':
+			continue
+		case dec.json5 && r == '/':
+			if err := dec.readComment(); err != nil {
+				return 0, err
+			}
+			continue
+		case dec.json5 && (unicode.IsSpace(r) || r == '\uFEFF'):
+			continue
+		}
+		if r == ',' || r == ']' || r == '}' {
+			// comments before these on the same line still follow the last value
+			dec.endCommentLine()
+		}
+		dec.newLineSinceToken = false
+		return r, nil
+	}
+}
+
+func (dec *jsonDecoder) skipWhitespaceInValue() (rune, error) {
+	r, err := dec.skipWhitespace()
+	if errors.Is(err, io.EOF) {
+		return 0, dec.errorf(dec.line, dec.column+1, "unexpected end of JSON input")
+	}
+	return r, err
+}
+
+func (dec *jsonDecoder) isNumberStart(r rune) bool {
+	if r == '-' || (r >= '0' && r <= '9') {
+		return true
+	}
+	return dec.json5 && (r == '+' || r == '.' || r == 'I' || r == 'N')
+}
+
+func (dec *jsonDecoder) decodeValue(first rune) (*CandidateNode, error) {
+	line, column := dec.line, dec.column
+	var node *CandidateNode
+	var err error
+	switch {
+	case first == '{':
+		node, err = dec.decodeObject()
+	case first == '[':
+		node, err = dec.decodeArray()
+	case first == '"' || (dec.json5 && first == '\''):
+		var value string
+		value, err = dec.decodeString(first)
+		node = &CandidateNode{Kind: ScalarNode, Tag: "!!str", Value: value}
+	case dec.isNumberStart(first):
+		node, err = dec.decodeNumber(first)
+	case first >= 'a' && first <= 'z':
+		node, err = dec.decodeLiteral(first)
+	default:
+		return nil, dec.errorf(line, column, "unexpected character %q", first)
+	}
+	if err != nil {
+		return nil, err
+	}
+	node.Line = line
+	node.Column = column
+	return node, nil
+}
+
+func (dec *jsonDecoder) decodeKey(first rune) (*CandidateNode, error) {
+	key := &CandidateNode{Kind: ScalarNode, Tag: "!!str", IsMapKey: true, Line: dec.line, Column: dec.column}
+	var err error
+	switch {
+	case first == '"' || (dec.json5 && first == '\''):
+		key.Value, err = dec.decodeString(first)
+	case dec.json5 && isJSON5IdentifierRune(first, true):
+		key.Value, err = dec.decodeIdentifier(first)
+	default:
+		return nil, dec.errorf(dec.line, dec.column, "expected a string key but found %q", first)
+	}
+	return key, err
+}
+
+func (dec *jsonDecoder) decodeObject() (*CandidateNode, error) {
+	node := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+	r, err := dec.skipWhitespaceInValue()
+	if err != nil {
+		return nil, err
+	}
+	var value *CandidateNode
+	for r != '}' {
+		key, err := dec.decodeKey(r)
+		if err != nil {
+			return nil, err
+		}
+		key.Parent = node
+		key.HeadComment = formatJSONComments(dec.takeComments(), "\n")
+
+		if r, err = dec.skipWhitespaceInValue(); err != nil {
+			return nil, err
+		} else if r != ':' {
+			return nil, dec.errorf(dec.line, dec.column, "expected ':' after key %q but found %q", key.Value, r)
+		}
+		if r, err = dec.skipWhitespaceInValue(); err != nil {
+			return nil, err
+		}
+		key.LineComment = formatJSONComments(dec.takeComments(), " ")
+		if value, err = dec.decodeValue(r); err != nil {
+			return nil, err
+		}
+		value.Parent = node
+		value.Key = key
+
+		if err := dec.addKeyValue(node, key, value); err != nil {
+			return nil, err
+		}
+
+		// line comments after a map or array value go on the key, where yaml expects them
+		lineCommentNode := value
+		if value.Kind != ScalarNode {
+			lineCommentNode = key
+		}
+		if r, err = dec.skipWhitespaceInValue(); err != nil {
+			return nil, err
+		}
+		lineCommentNode.LineComment = joinComments([]string{lineCommentNode.LineComment, dec.takeLineComments()}, " ")
+		if r == ',' {
+			if r, err = dec.skipWhitespaceInValue(); err != nil {
+				return nil, err
+			}
+			lineCommentNode.LineComment = joinComments([]string{lineCommentNode.LineComment, dec.takeLineComments()}, " ")
+			if r == '}' && !dec.json5 {
+				return nil, dec.errorf(dec.line, dec.column, "unexpected trailing comma before '}'")
+			}
+		} else if r != '}' {
+			return nil, dec.errorf(dec.line, dec.column, "expected ',' or '}' after object value but found %q", r)
+		}
+	}
+	dec.attachFootComments(node, value)
+	return node, nil
+}
+
+// addKeyValue adds the entry to the map, applying the duplicate key policy if the key is already there.
+func (dec *jsonDecoder) addKeyValue(node *CandidateNode, key *CandidateNode, value *CandidateNode) error {
+	for index := 0; index+1 < len(node.Content); index = index + 2 {
+		existing := node.Content[index]
+		if existing.Value != key.Value {
+			continue
+		}
+		switch dec.prefs.DuplicateKeys {
+		case JsonDuplicateKeysError:
+			return dec.errorf(key.Line, key.Column, "duplicate key %q, first defined at line %v, column %v", key.Value, existing.Line, existing.Column)
+		case JsonDuplicateKeysFirst:
+			log.Debugf("jsonDecoder: ignoring duplicate key %v at line %v", key.Value, key.Line)
+		default:
+			log.Debugf("jsonDecoder: duplicate key %v at line %v replaces the earlier value", key.Value, key.Line)
+			value.Key = existing
+			node.Content[index+1] = value
+		}
+		return nil
+	}
+	node.Content = append(node.Content, key, value)
+	return nil
+}
+
+func (dec *jsonDecoder) decodeArray() (*CandidateNode, error) {
+	node := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+	r, err := dec.skipWhitespaceInValue()
+	if err != nil {
+		return nil, err
+	}
+	var value *CandidateNode
+	for r != ']' {
+		headComment := formatJSONComments(dec.takeComments(), "\n")
+		if value, err = dec.decodeValue(r); err != nil {
+			return nil, err
+		}
+		value.HeadComment = joinComments([]string{headComment, value.HeadComment}, "\n")
+		index := len(node.Content)
+		value.Parent = node
+		value.Key = &CandidateNode{Kind: ScalarNode, Tag: "!!int", Value: fmt.Sprintf("%v", index), IsMapKey: true, Parent: node}
+		node.Content = append(node.Content, value)
+
+		if r, err = dec.skipWhitespaceInValue(); err != nil {
+			return nil, err
+		}
+		value.LineComment = joinComments([]string{value.LineComment, dec.takeLineComments()}, " ")
+		if r == ',' {
+			if r, err = dec.skipWhitespaceInValue(); err != nil {
+				return nil, err
+			}
+			value.LineComment = joinComments([]string{value.LineComment, dec.takeLineComments()}, " ")
+			if r == ']' && !dec.json5 {
+				return nil, dec.errorf(dec.line, dec.column, "unexpected trailing comma before ']'")
+			}
+		} else if r != ']' {
+			return nil, dec.errorf(dec.line, dec.column, "expected ',' or ']' after array element but found %q", r)
+		}
+	}
+	dec.attachFootComments(node, value)
+	return node, nil
+}
+
+// decodeString reads the rest of a string, after its opening quote.
+func (dec *jsonDecoder) decodeString(quote rune) (string, error) {
+	var sb strings.Builder
+	// the first half of a surrogate pair, waiting for its second half
+	var surrogate rune
+	for {
+		r, err := dec.readRuneInValue()
+		if err != nil {
+			return "", err
+		}
+		if r == '\\' {
+			if r, err = dec.readRuneInValue(); err != nil {
+				return "", err
+			}
+			if dec.json5 && isJSON5LineContinuation(r) {
+				if err := dec.skipLineContinuation(r); err != nil {
+					return "", err
+				}
+				continue
+			}
+			if r == 'u' {
+				if r, err = dec.readHex(4); err != nil {
+					return "", err
+				}
+				if surrogate != 0 {
+					if combined := utf16.DecodeRune(surrogate, r); combined != unicode.ReplacementChar {
+						sb.WriteRune(combined)
+						surrogate = 0
+						continue
+					}
+				}
+			} else if r, err = dec.unescape(r); err != nil {
+				return "", err
+			}
+		} else if r == quote {
+			if surrogate != 0 {
+				sb.WriteRune(unicode.ReplacementChar)
+			}
+			return sb.String(), nil
+		} else if r == '\n' || r == '\r' || (!dec.json5 && r < 0x20) {
+			return "", dec.errorf(dec.line, dec.column, "invalid control character %q in string", r)
+		}
+
+		if surrogate != 0 {
+			sb.WriteRune(unicode.ReplacementChar)
+			surrogate = 0
+		}
+		if utf16.IsSurrogate(r) {
+			surrogate = r
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func (dec *jsonDecoder) unescape(escaped rune) (rune, error) {
+	switch escaped {
+	case '"', '\\', '/':
+		return escaped, nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	}
+	if dec.json5 {
+		return dec.unescapeJSON5(escaped)
+	}
+	return 0, dec.errorf(dec.line, dec.column, "invalid escape character %q in string", escaped)
+}
+
+func (dec *jsonDecoder) readHex(length int) (rune, error) {
+	line, column := dec.line, dec.column
+	digits := make([]rune, length)
+	for i := range digits {
+		r, err := dec.readRuneInValue()
+		if err != nil {
+			return 0, err
+		}
+		digits[i] = r
+	}
+	value, err := strconv.ParseUint(string(digits), 16, 16)
+	if err != nil {
+		return 0, dec.errorf(line, column, "invalid unicode escape %v", string(digits))
+	}
+	return rune(value), nil
+}
+
+// readWord reads the rest of a number or literal
+func (dec *jsonDecoder) readWord(first rune) (string, error) {
+	var sb strings.Builder
+	sb.WriteRune(first)
+	for {
+		r, err := dec.readRune()
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+		if r == '+' || r == '-' || r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			continue
+		}
+		return sb.String(), dec.unreadRune()
+	}
+}
 
-	var dataBucket CandidateNode
-	err := dec.decoder.Decode(&dataBucket)
+func (dec *jsonDecoder) decodeNumber(first rune) (*CandidateNode, error) {
+	line, column := dec.line, dec.column
+	value, err := dec.readWord(first)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dataBucket, nil
+	var node *CandidateNode
+	if dec.json5 {
+		node = decodeJSON5Number(value)
+	} else if jsonNumberRegex.MatchString(value) {
+		node = decodeJSONNumber(value)
+	}
+	if node == nil {
+		return nil, dec.errorf(line, column, "invalid number %v", value)
+	}
+	if node.Tag == "!!float" && !strings.HasSuffix(node.Value, ".inf") && node.Value != ".nan" {
+		// numbers that do not fit in a float64 could not be used or written out again
+		if _, err := strconv.ParseFloat(node.Value, 64); errors.Is(err, strconv.ErrRange) {
+			return nil, dec.errorf(line, column, "number %v is out of range", value)
+		}
+	}
+	return node, nil
+}
+
+func decodeJSONNumber(value string) *CandidateNode {
+	// numbers are kept as written, so big and precise numbers are not rounded.
+	// Integers too big for an int64 are floats, as they would be in yaml.
+	tag := "!!float"
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		tag = "!!int"
+	}
+	return &CandidateNode{Kind: ScalarNode, Tag: tag, Value: value}
+}
+
+func (dec *jsonDecoder) decodeLiteral(first rune) (*CandidateNode, error) {
+	line, column := dec.line, dec.column
+	literal, err := dec.readWord(first)
+	if err != nil {
+		return nil, err
+	}
+
+	switch literal {
+	case "true", "false":
+		return &CandidateNode{Kind: ScalarNode, Tag: "!!bool", Value: literal}, nil
+	case "null":
+		return &CandidateNode{Kind: ScalarNode, Tag: "!!null", Value: literal}, nil
+	default:
+		return nil, dec.errorf(line, column, "invalid literal %v", literal)
+	}
 }
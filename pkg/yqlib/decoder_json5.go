@@ -0,0 +1,240 @@
+//go:build !yq_nojson
+
+package yqlib
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var json5NumberRegex = regexp.MustCompile(`^[+-]?((0|[1-9][0-9]*)(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+var json5HexNumberRegex = regexp.MustCompile(`^[+-]?0[xX][0-9a-fA-F]+$`)
+
+type jsonComment struct {
+	// text is the comment in yaml style, e.g. "# a comment"
+	text string
+	// ownLine is whether the comment starts its own line, rather than following a value
+	ownLine bool
+	// endsLine is whether no value follows the comment on its line, a block comment
+	// with a value after it on the same line belongs to that value
+	endsLine bool
+}
+
+// NewJSON5Decoder reads JSON5, and so JSONC too. Comments before a value become its head comment,
+// comments after a value on the same line its line comment and comments at the end of a map or
+// array the foot comment of its last entry.
+func NewJSON5Decoder(prefs JsonPreferences) Decoder {
+	return &jsonDecoder{prefs: prefs, json5: true}
+}
+
+// readComment reads a comment, after its first '/'
+func (dec *jsonDecoder) readComment() error {
+	line, column := dec.line, dec.column
+	ownLine := dec.newLineSinceToken
+	r, err := dec.readRune()
+	if errors.Is(err, io.EOF) {
+		return dec.errorf(line, column, "unexpected character '/'")
+	} else if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	switch r {
+	case '/':
+		for {
+			r, err := dec.readRune()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			if r == '\n' {
+				dec.newLineSinceToken = true
+				break
+			}
+			sb.WriteRune(r)
+		}
+		dec.comments = append(dec.comments, jsonComment{"#" + strings.TrimRight(sb.String(), "\r"), ownLine, true})
+		dec.endCommentLine()
+	case '*':
+		previous := rune(0)
+		for {
+			r, err := dec.readRune()
+			if errors.Is(err, io.EOF) {
+				return dec.errorf(line, column, "unterminated comment")
+			} else if err != nil {
+				return err
+			}
+			if previous == '*' && r == '/' {
+				break
+			}
+			if previous != 0 {
+				sb.WriteRune(previous)
+			}
+			previous = r
+		}
+		dec.comments = append(dec.comments, jsonComment{formatJSON5BlockComment(sb.String()), ownLine, false})
+	default:
+		return dec.errorf(line, column, "unexpected character '/'")
+	}
+	return nil
+}
+
+// formatJSON5BlockComment turns the text of a /* */ comment into yaml comment lines,
+// dropping the leading '*' of javadoc style comments.
+func formatJSON5BlockComment(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if i > 0 {
+			line = strings.TrimLeft(line, " \t")
+			line = strings.TrimPrefix(line, "*")
+			if line != "" && !strings.HasPrefix(line, " ") {
+				line = " " + line
+			}
+		}
+		lines[i] = line
+	}
+	if len(lines) > 1 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return "#" + strings.Join(lines, "\n#")
+}
+
+// endCommentLine marks the comments read since the last new line or token as ending their line
+func (dec *jsonDecoder) endCommentLine() {
+	for i := len(dec.comments) - 1; i >= 0 && !dec.comments[i].endsLine; i-- {
+		dec.comments[i].endsLine = true
+	}
+}
+
+func formatJSONComments(comments []jsonComment, separator string) string {
+	texts := make([]string, len(comments))
+	for i, comment := range comments {
+		texts[i] = comment.text
+	}
+	return strings.Join(texts, separator)
+}
+
+func (dec *jsonDecoder) takeComments() []jsonComment {
+	comments := dec.comments
+	dec.comments = make([]jsonComment, 0)
+	return comments
+}
+
+// takeLineComments takes the comments that follow the last token on its line, and are not
+// followed by a value on the same line
+func (dec *jsonDecoder) takeLineComments() string {
+	count := 0
+	for count < len(dec.comments) && !dec.comments[count].ownLine && dec.comments[count].endsLine {
+		count++
+	}
+	lineComments := formatJSONComments(dec.comments[:count], " ")
+	dec.comments = dec.comments[count:]
+	return lineComments
+}
+
+// attachFootComments adds the comments at the end of a map or array to its last entry, if there is one
+func (dec *jsonDecoder) attachFootComments(node *CandidateNode, last *CandidateNode) {
+	footComment := formatJSONComments(dec.takeComments(), "\n")
+	if last != nil {
+		node = last
+	}
+	node.FootComment = joinComments([]string{node.FootComment, footComment}, "\n")
+}
+
+// decodeTrailingComments reads the comments after a document. Those on its last line are line comments,
+// and at the end of the input the rest are foot comments, otherwise they belong to the next document.
+func (dec *jsonDecoder) decodeTrailingComments(node *CandidateNode) error {
+	_, err := dec.skipWhitespace()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	node.LineComment = joinComments([]string{node.LineComment, dec.takeLineComments()}, " ")
+	if errors.Is(err, io.EOF) {
+		node.FootComment = joinComments([]string{node.FootComment, formatJSONComments(dec.takeComments(), "\n")}, "\n")
+		return nil
+	}
+	return dec.unreadRune()
+}
+
+func isJSON5IdentifierRune(r rune, first bool) bool {
+	if r == '_' || r == '$' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Pc, r))
+}
+
+func (dec *jsonDecoder) decodeIdentifier(first rune) (string, error) {
+	var sb strings.Builder
+	sb.WriteRune(first)
+	for {
+		r, err := dec.readRuneInValue()
+		if err != nil {
+			return "", err
+		}
+		if !isJSON5IdentifierRune(r, false) {
+			return sb.String(), dec.unreadRune()
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func isJSON5LineContinuation(r rune) bool {
+	return r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029'
+}
+
+func (dec *jsonDecoder) skipLineContinuation(r rune) error {
+	if r != '\r' {
+		return nil
+	}
+	next, err := dec.readRuneInValue()
+	if err != nil {
+		return err
+	}
+	if next != '\n' {
+		return dec.unreadRune()
+	}
+	return nil
+}
+
+func (dec *jsonDecoder) unescapeJSON5(escaped rune) (rune, error) {
+	switch {
+	case escaped == 'v':
+		return '\v', nil
+	case escaped == '0':
+		return 0, nil
+	case escaped == 'x':
+		return dec.readHex(2)
+	case escaped >= '1' && escaped <= '9':
+		return 0, dec.errorf(dec.line, dec.column, "invalid escape character %q in string", escaped)
+	}
+	// any other character, like \', is itself
+	return escaped, nil
+}
+
+// decodeJSON5Number returns the node for a JSON5 number, or nil if it is not one.
+// Infinity and NaN are the yaml .inf and .nan, other numbers are kept as written.
+func decodeJSON5Number(value string) *CandidateNode {
+	unsigned := strings.TrimLeft(value, "+-")
+	sign := value[:len(value)-len(unsigned)]
+	switch {
+	case len(sign) > 1:
+		return nil
+	case unsigned == "Infinity":
+		return &CandidateNode{Kind: ScalarNode, Tag: "!!float", Value: sign + ".inf"}
+	case unsigned == "NaN":
+		return &CandidateNode{Kind: ScalarNode, Tag: "!!float", Value: ".nan"}
+	case json5HexNumberRegex.MatchString(value):
+		return &CandidateNode{Kind: ScalarNode, Tag: "!!int", Value: value}
+	case json5NumberRegex.MatchString(value):
+		return decodeJSONNumber(value)
+	}
+	return nil
+}
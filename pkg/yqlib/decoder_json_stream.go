@@ -0,0 +1,72 @@
+//go:build !yq_nojson
+
+package yqlib
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// jsonStreamDecoder reads JSON token by token, returning [path, leaf] stream events
+// without ever holding more than the current path in memory.
+type jsonStreamDecoder struct {
+	decoder *json.Decoder
+	path    streamPath
+}
+
+func NewJSONStreamDecoder() Decoder {
+	return &jsonStreamDecoder{}
+}
+
+func (dec *jsonStreamDecoder) Init(reader io.Reader) error {
+	dec.decoder = json.NewDecoder(reader)
+	// numbers are kept as written, like the json decoder, rather than rounded to a float64
+	dec.decoder.UseNumber()
+	dec.path = streamPath{}
+	return nil
+}
+
+func (dec *jsonStreamDecoder) Decode() (*CandidateNode, error) {
+	for {
+		tok, err := dec.decoder.Token()
+		if err == io.EOF && len(dec.path.frames) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{':
+				dec.path.beginValue()
+				dec.path.push(&CandidateNode{Kind: MappingNode, Tag: "!!map", Style: FlowStyle})
+			case '[':
+				dec.path.beginValue()
+				dec.path.push(&CandidateNode{Kind: SequenceNode, Tag: "!!seq", Style: FlowStyle})
+			case '}', ']':
+				return createStreamEvent(dec.path.pop()), nil
+			}
+		default:
+			if dec.path.expectingKey() {
+				dec.path.setKey(createStringScalarNode(fmt.Sprintf("%v", tok)))
+				continue
+			}
+			dec.path.beginValue()
+			var leaf *CandidateNode
+			if number, ok := tok.(json.Number); ok {
+				leaf = decodeJSONNumber(string(number))
+			} else {
+				leaf = &CandidateNode{}
+				if err := leaf.setScalarFromJson(tok); err != nil {
+					return nil, err
+				}
+			}
+			event := createStreamEvent(dec.path.current(), leaf)
+			dec.path.endValue()
+			return event, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !yq_nojson
+
+package yqlib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ndjsonDecoder struct {
+	prefs  JsonPreferences
+	reader *bufio.Reader
+	line   int
+}
+
+// NewNDJSONDecoder reads newline delimited JSON (JSON Lines), one document per line.
+// Blank lines are ignored, and with SkipInvalid lines that are not valid JSON are
+// logged and skipped instead of stopping the decoding.
+func NewNDJSONDecoder(prefs JsonPreferences) Decoder {
+	return &ndjsonDecoder{prefs: prefs}
+}
+
+func (dec *ndjsonDecoder) Init(reader io.Reader) error {
+	dec.reader = bufio.NewReader(reader)
+	dec.line = 0
+	return nil
+}
+
+func (dec *ndjsonDecoder) Decode() (*CandidateNode, error) {
+	for {
+		text, err := dec.reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if text == "" && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		dec.line++
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		node, decodeErr := dec.decodeLine(text)
+		if decodeErr == nil {
+			return node, nil
+		}
+		if !dec.prefs.SkipInvalid {
+			return nil, decodeErr
+		}
+		log.Warningf("skipping invalid line: %v", decodeErr)
+	}
+}
+
+// decodeLine decodes the single JSON value on the current line
+func (dec *ndjsonDecoder) decodeLine(text string) (*CandidateNode, error) {
+	lineDecoder := &jsonDecoder{prefs: dec.prefs}
+	// without the new line, a value that is cut short is reported at the end of its line
+	if err := lineDecoder.Init(strings.NewReader(strings.TrimRight(text, "\n"))); err != nil {
+		return nil, err
+	}
+	// positions, in nodes and errors, are those in the whole input
+	lineDecoder.line = dec.line
+
+	node, err := lineDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lineDecoder.Decode(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("json: line %v: expected one value on the line but found more", dec.line)
+	}
+	return node, nil
+}
@@ -40,20 +40,114 @@ func parsePropKey(key string) []interface{} {
 	return path
 }
 
-func (dec *propertiesDecoder) processComment(c string) string {
-	if c == "" {
-		return ""
+// propertiesComments are the comments in a properties file, as yaml comments. Blank lines between
+// the comments before a key are kept, comments at the top that are kept apart from the first key by
+// a blank line belong to the document, as do those at the end. blankBefore has the keys after the
+// first that have a blank line before them (and their comments).
+type propertiesComments struct {
+	head        string
+	keys        map[string]string
+	foot        string
+	blankBefore map[string]bool
+}
+
+func joinCommentLines(lines []string) string {
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func parsePropertiesComments(content string) propertiesComments {
+	comments := propertiesComments{keys: make(map[string]string), blankBefore: make(map[string]bool)}
+	pending := make([]string, 0)
+	seenKey := false
+	blank := false
+	continued := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(strings.TrimRight(line, "\r"), " \t\f")
+		wasContinued := continued
+		continued = false
+		switch {
+		case wasContinued:
+			// the rest of the value on the previous line
+			continued = isContinuedPropertyLine(trimmed)
+		case trimmed == "":
+			if len(pending) > 0 && pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+			blank = blank || len(pending) == 0
+		case trimmed[0] == '#' || trimmed[0] == '!':
+			pending = append(pending, "#"+trimmed[1:])
+		default:
+			key := parsePropertyLineKey(trimmed)
+			if seenKey && blank {
+				comments.blankBefore[key] = true
+			}
+			blank = false
+			if !seenKey {
+				seenKey = true
+				for i := len(pending) - 1; i >= 0; i-- {
+					if pending[i] == "" {
+						comments.head = joinCommentLines(pending[:i])
+						pending = pending[i+1:]
+						break
+					}
+				}
+			}
+			if comment := joinCommentLines(pending); comment != "" {
+				comments.keys[key] = comment
+			}
+			pending = make([]string, 0)
+			continued = isContinuedPropertyLine(trimmed)
+		}
+	}
+	comments.foot = joinCommentLines(pending)
+	return comments
+}
+
+// isContinuedPropertyLine is whether the line ends with an odd number of backslashes
+func isContinuedPropertyLine(line string) bool {
+	backslashes := len(line) - len(strings.TrimRight(line, "\\"))
+	return backslashes%2 == 1
+}
+
+// parsePropertyLineKey reads the key at the start of a property line, up to the separator.
+func parsePropertyLineKey(line string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range line {
+		if escaped {
+			switch r {
+			case 't':
+				r = '\t'
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			case 'f':
+				r = '\f'
+			}
+			sb.WriteRune(r)
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+			continue
+		case '=', ':', ' ', '\t', '\f':
+			return sb.String()
+		}
+		sb.WriteRune(r)
 	}
-	return "# " + c
+	return sb.String()
 }
 
-func (dec *propertiesDecoder) applyPropertyComments(context Context, path []interface{}, comments []string) error {
+func (dec *propertiesDecoder) applyPropertyComments(context Context, path []interface{}, comment string) error {
 	assignmentOp := &Operation{OperationType: assignOpType, Preferences: assignPreferences{}}
 
 	rhsCandidateNode := &CandidateNode{
 		Tag:         "!!str",
 		Value:       fmt.Sprintf("%v", path[len(path)-1]),
-		HeadComment: dec.processComment(strings.Join(comments, "\n")),
+		HeadComment: comment,
 		Kind:        ScalarNode,
 	}
 
@@ -71,13 +165,12 @@ func (dec *propertiesDecoder) applyPropertyComments(context Context, path []inte
 	return err
 }
 
-func (dec *propertiesDecoder) applyProperty(context Context, properties *properties.Properties, key string) error {
+func (dec *propertiesDecoder) applyProperty(context Context, properties *properties.Properties, comments propertiesComments, key string) error {
 	value, _ := properties.Get(key)
 	path := parsePropKey(key)
 
-	propertyComments := properties.GetComments(key)
-	if len(propertyComments) > 0 {
-		err := dec.applyPropertyComments(context, path, propertyComments)
+	if propertyComment := comments.keys[key]; propertyComment != "" {
+		err := dec.applyPropertyComments(context, path, propertyComment)
 		if err != nil {
 			return nil
 		}
@@ -86,7 +179,20 @@ func (dec *propertiesDecoder) applyProperty(context Context, properties *propert
 	rhsNode := createStringScalarNode(value)
 	rhsNode.Tag = rhsNode.guessTagFromCustomType()
 
-	return dec.d.DeeplyAssign(context, path, rhsNode)
+	if err := dec.d.DeeplyAssign(context, path, rhsNode); err != nil {
+		return err
+	}
+	if !comments.blankBefore[key] {
+		return nil
+	}
+	assigned, err := dec.d.GetMatchingNodes(context, createTraversalTree(path, traversePreferences{}, false))
+	if err != nil {
+		return err
+	}
+	for el := assigned.MatchingNodes.Front(); el != nil; el = el.Next() {
+		el.Value.(*CandidateNode).blankLineBefore = true
+	}
+	return nil
 }
 
 func (dec *propertiesDecoder) Decode() (*CandidateNode, error) {
@@ -108,16 +214,19 @@ func (dec *propertiesDecoder) Decode() (*CandidateNode, error) {
 	}
 	properties.DisableExpansion = true
 
+	comments := parsePropertiesComments(buf.String())
 	rootMap := &CandidateNode{
-		Kind: MappingNode,
-		Tag:  "!!map",
+		Kind:        MappingNode,
+		Tag:         "!!map",
+		HeadComment: comments.head,
+		FootComment: comments.foot,
 	}
 
 	context := Context{}
 	context = context.SingleChildContext(rootMap)
 
 	for _, key := range properties.Keys() {
-		if err := dec.applyProperty(context, properties, key); err != nil {
+		if err := dec.applyProperty(context, properties, comments, key); err != nil {
 			return nil, err
 		}
 
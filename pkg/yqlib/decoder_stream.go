@@ -0,0 +1,107 @@
+package yqlib
+
+import (
+	"fmt"
+)
+
+// NewStreamDecoder returns a decoder that decodes the input as [path, leaf] stream
+// events, like `tostream`, as it is read so that it uses constant memory. JSON, YAML
+// and XML can be read like this; use `tostream` for the other formats.
+func NewStreamDecoder(format *Format) (Decoder, error) {
+	var decoder Decoder
+	switch format {
+	case JSONFormat, NDJSONFormat:
+		decoder = NewJSONStreamDecoder()
+	case YamlFormat:
+		decoder = NewYamlStreamDecoder()
+	case XMLFormat:
+		decoder = NewXMLStreamDecoder(ConfiguredXMLPreferences)
+	default:
+		return nil, fmt.Errorf("--stream only supports yaml, json and xml input, %v documents are decoded whole - use tostream instead", format.FormalName)
+	}
+	if decoder == nil {
+		return nil, fmt.Errorf("no support for %v input format", format.FormalName)
+	}
+	return decoder, nil
+}
+
+type streamFrame struct {
+	isMap bool
+	// the key or index of the current entry
+	pathElement *CandidateNode
+	index       int
+	expectKey   bool
+	// the leaf to give if the map or sequence turns out to be empty
+	empty *CandidateNode
+}
+
+// streamPath is the path to the value being decoded by a stream decoder, one frame
+// for each map and sequence it is in.
+type streamPath struct {
+	frames []*streamFrame
+}
+
+func (sp *streamPath) current() []*CandidateNode {
+	path := make([]*CandidateNode, len(sp.frames))
+	for i, frame := range sp.frames {
+		path[i] = frame.pathElement
+	}
+	return path
+}
+
+func (sp *streamPath) top() *streamFrame {
+	if len(sp.frames) == 0 {
+		return nil
+	}
+	return sp.frames[len(sp.frames)-1]
+}
+
+// expectingKey is whether the next scalar is the key of a map entry
+func (sp *streamPath) expectingKey() bool {
+	frame := sp.top()
+	return frame != nil && frame.expectKey
+}
+
+func (sp *streamPath) setKey(key *CandidateNode) {
+	frame := sp.top()
+	frame.pathElement = key
+	frame.expectKey = false
+}
+
+// beginValue moves the current sequence, if any, on to its next entry
+func (sp *streamPath) beginValue() {
+	frame := sp.top()
+	if frame != nil && !frame.isMap {
+		frame.index++
+		frame.pathElement = createScalarNode(frame.index, fmt.Sprintf("%v", frame.index))
+	}
+}
+
+func (sp *streamPath) endValue() {
+	frame := sp.top()
+	if frame != nil && frame.isMap {
+		frame.expectKey = true
+	}
+}
+
+func (sp *streamPath) push(empty *CandidateNode) {
+	isMap := empty.Kind == MappingNode
+	sp.frames = append(sp.frames, &streamFrame{isMap: isMap, index: -1, expectKey: isMap, empty: empty})
+}
+
+// pop ends the current map or sequence, returning the path and leaf of its closing
+// event. Empty maps and sequences have no entries to close, so they are a leaf instead.
+func (sp *streamPath) pop() ([]*CandidateNode, *CandidateNode) {
+	frame := sp.top()
+	var path []*CandidateNode
+	var leaf *CandidateNode
+	if frame.pathElement == nil {
+		sp.frames = sp.frames[:len(sp.frames)-1]
+		path, leaf = sp.current(), frame.empty
+	} else {
+		path = sp.current()
+		sp.frames = sp.frames[:len(sp.frames)-1]
+	}
+	sp.endValue()
+	return path, leaf
+}
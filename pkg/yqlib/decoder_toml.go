@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	toml "github.com/pelletier/go-toml/v2/unstable"
@@ -18,6 +19,10 @@ type tomlDecoder struct {
 	finished bool
 	d        DataTreeNavigator
 	rootMap  *CandidateNode
+	// comments on their own lines, waiting for the next key or table.
+	// Empty entries are blank lines between them.
+	pendingComments []string
+	seenExpression  bool
 }
 
 func NewTomlDecoder() Decoder {
@@ -28,7 +33,9 @@ func NewTomlDecoder() Decoder {
 }
 
 func (dec *tomlDecoder) Init(reader io.Reader) error {
-	dec.parser = toml.Parser{}
+	dec.parser = toml.Parser{KeepComments: true}
+	dec.pendingComments = make([]string, 0)
+	dec.seenExpression = false
 	buf := new(bytes.Buffer)
 	_, err := buf.ReadFrom(reader)
 	if err != nil {
@@ -53,6 +60,34 @@ func (dec *tomlDecoder) getFullPath(tomlNode *toml.Node) []interface{} {
 	}
 }
 
+// table headers that pass through an array of tables refer to its last entry,
+// e.g. [fruits.physical] after [[fruits]].
+func (dec *tomlDecoder) resolveArrayTablePath(path []interface{}) []interface{} {
+	resolvedPath := make([]interface{}, 0, len(path))
+	current := dec.rootMap
+	for index, key := range path {
+		resolvedPath = append(resolvedPath, key)
+		if current == nil || index == len(path)-1 {
+			continue
+		}
+		var child *CandidateNode
+		for i := 0; i < len(current.Content)-1; i = i + 2 {
+			if current.Content[i].Value == key {
+				child = current.Content[i+1]
+			}
+		}
+		if child != nil && child.Kind == SequenceNode && len(child.Content) > 0 {
+			resolvedPath = append(resolvedPath, len(child.Content)-1)
+			child = child.Content[len(child.Content)-1]
+		}
+		if child != nil && child.Kind != MappingNode {
+			child = nil
+		}
+		current = child
+	}
+	return resolvedPath
+}
+
 func (dec *tomlDecoder) processKeyValueIntoMap(rootMap *CandidateNode, tomlNode *toml.Node) error {
 	value := tomlNode.Value()
 	path := dec.getFullPath(value.Next())
@@ -66,21 +101,57 @@ func (dec *tomlDecoder) processKeyValueIntoMap(rootMap *CandidateNode, tomlNode
 	context := Context{}
 	context = context.SingleChildContext(rootMap)
 
-	return dec.d.DeeplyAssign(context, path, valueNode)
+	if err := dec.d.DeeplyAssign(context, path, valueNode); err != nil {
+		return err
+	}
+	_, assigned := findTomlEntry(rootMap, path)
+	keepTomlFlowStyles(valueNode, assigned)
+	return nil
+}
+
+// keepTomlFlowStyles marks the inline tables of a decoded value as flow maps where it
+// was assigned, as maps are merged in and that does not keep their style.
+func keepTomlFlowStyles(decoded *CandidateNode, assigned *CandidateNode) {
+	if assigned == nil || decoded.Kind != assigned.Kind {
+		return
+	}
+	if decoded.Style == FlowStyle {
+		assigned.Style = FlowStyle
+	}
+	if decoded.Kind != MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(decoded.Content); i = i + 2 {
+		_, value := findTomlEntry(assigned, []interface{}{decoded.Content[i].Value})
+		keepTomlFlowStyles(decoded.Content[i+1], value)
+	}
+}
+
+// processKeyValueExpression adds a top level key value line to the map, along with the comments
+// before it and at the end of its line.
+func (dec *tomlDecoder) processKeyValueExpression(rootMap *CandidateNode, tomlNode *toml.Node) error {
+	headComment := dec.takeHeadComment()
+	lineComment := dec.lineComment(tomlNode)
+	if err := dec.processKeyValueIntoMap(rootMap, tomlNode); err != nil {
+		return err
+	}
+	key, value := findTomlEntry(rootMap, dec.getFullPath(tomlNode.Value().Next()))
+	dec.applyComments(key, value, headComment, lineComment)
+	return nil
 }
 
 func (dec *tomlDecoder) decodeKeyValuesIntoMap(rootMap *CandidateNode, tomlNode *toml.Node) (bool, error) {
 	log.Debug("decodeKeyValuesIntoMap -- processing first (current) entry")
-	if err := dec.processKeyValueIntoMap(rootMap, tomlNode); err != nil {
+	if err := dec.processKeyValueExpression(rootMap, tomlNode); err != nil {
 		return false, err
 	}
 
-	for dec.parser.NextExpression() {
+	for dec.nextExpression() {
 		nextItem := dec.parser.Expression()
 		log.Debug("decodeKeyValuesIntoMap -- next exp, its a %v", nextItem.Kind)
 
 		if nextItem.Kind == toml.KeyValue {
-			if err := dec.processKeyValueIntoMap(rootMap, nextItem); err != nil {
+			if err := dec.processKeyValueExpression(rootMap, nextItem); err != nil {
 				return false, err
 			}
 		} else {
@@ -116,36 +187,79 @@ func (dec *tomlDecoder) createInlineTableMap(tomlNode *toml.Node) (*CandidateNod
 		content = append(content, keyValues.Content...)
 	}
 
+	// inline tables are flow maps, so they are written back inline rather than as tables
 	return &CandidateNode{
 		Kind:    MappingNode,
 		Tag:     "!!map",
+		Style:   FlowStyle,
 		Content: content,
 	}, nil
 }
 
 func (dec *tomlDecoder) createArray(tomlNode *toml.Node) (*CandidateNode, error) {
 	content := make([]*CandidateNode, 0)
+	// comments on their own lines go on the next entry, or the last one at the end of the array
+	headComments := make([]string, 0)
 	iterator := tomlNode.Children()
 	for iterator.Next() {
 		child := iterator.Node()
+		if child.Kind == toml.Comment {
+			for _, comment := range dec.commentGroup(child) {
+				if len(content) > 0 && !dec.isOwnLineComment(comment) {
+					last := content[len(content)-1]
+					last.LineComment = joinComments([]string{last.LineComment, dec.commentText(comment)}, " ")
+				} else {
+					headComments = append(headComments, dec.commentText(comment))
+				}
+			}
+			continue
+		}
 		yamlNode, err := dec.decodeNode(child)
 		if err != nil {
 			return nil, err
 		}
+		yamlNode.HeadComment = strings.Join(headComments, "\n")
+		headComments = headComments[:0]
 		content = append(content, yamlNode)
 	}
 
-	return &CandidateNode{
+	node := &CandidateNode{
 		Kind:    SequenceNode,
 		Tag:     "!!seq",
 		Content: content,
-	}, nil
+	}
+	// likewise an array of inline tables, rather than an array of tables
+	if len(content) > 0 && isInlineTableArray(content) {
+		node.Style = FlowStyle
+	}
+	if len(content) > 0 {
+		content[len(content)-1].FootComment = strings.Join(headComments, "\n")
+	} else {
+		node.FootComment = strings.Join(headComments, "\n")
+	}
+	return node, nil
+}
 
+func isInlineTableArray(content []*CandidateNode) bool {
+	for _, child := range content {
+		if child.Kind != MappingNode || child.Style != FlowStyle {
+			return false
+		}
+	}
+	return true
 }
 
 func (dec *tomlDecoder) createStringScalar(tomlNode *toml.Node) (*CandidateNode, error) {
 	content := string(tomlNode.Data)
-	return createScalarNode(content, content), nil
+	node := createScalarNode(content, content)
+	// keep track of literal and multiline strings so they can be written back the same way
+	raw := dec.parser.Raw(tomlNode.Raw)
+	if bytes.HasPrefix(raw, []byte(`'''`)) || bytes.HasPrefix(raw, []byte(`"""`)) {
+		node.Style = LiteralStyle
+	} else if bytes.HasPrefix(raw, []byte("'")) {
+		node.Style = SingleQuotedStyle
+	}
+	return node, nil
 }
 
 func (dec *tomlDecoder) createBoolScalar(tomlNode *toml.Node) (*CandidateNode, error) {
@@ -159,10 +273,22 @@ func (dec *tomlDecoder) createIntegerScalar(tomlNode *toml.Node) (*CandidateNode
 	return createScalarNode(num, content), err
 }
 
+// toml supports offset date times, local date times, local dates and local times.
+var tomlDateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"}
+
 func (dec *tomlDecoder) createDateTimeScalar(tomlNode *toml.Node) (*CandidateNode, error) {
 	content := string(tomlNode.Data)
-	val, err := parseDateTime(time.RFC3339, content)
-	return createScalarNode(val, content), err
+	// the date and time may be separated with a space instead of a 'T'
+	normalised := strings.ToUpper(strings.Replace(content, " ", "T", 1))
+	var err error
+	for _, layout := range tomlDateTimeLayouts {
+		if _, err = time.Parse(layout, normalised); err == nil {
+			break
+		}
+	}
+	node := createStringScalarNode(content)
+	node.Tag = "!!timestamp"
+	return node, err
 }
 
 func (dec *tomlDecoder) createFloatScalar(tomlNode *toml.Node) (*CandidateNode, error) {
@@ -179,7 +305,7 @@ func (dec *tomlDecoder) decodeNode(tomlNode *toml.Node) (*CandidateNode, error)
 		return dec.createBoolScalar(tomlNode)
 	case toml.Integer:
 		return dec.createIntegerScalar(tomlNode)
-	case toml.DateTime:
+	case toml.DateTime, toml.LocalDateTime, toml.LocalDate, toml.LocalTime:
 		return dec.createDateTimeScalar(tomlNode)
 	case toml.Float:
 		return dec.createFloatScalar(tomlNode)
@@ -213,7 +339,7 @@ func (dec *tomlDecoder) Decode() (*CandidateNode, error) {
 	log.Debug("ok here we go")
 	var runAgainstCurrentExp = false
 	var err error
-	for runAgainstCurrentExp || dec.parser.NextExpression() {
+	for runAgainstCurrentExp || dec.nextExpression() {
 
 		if runAgainstCurrentExp {
 			log.Debug("running against current exp")
@@ -236,6 +362,7 @@ func (dec *tomlDecoder) Decode() (*CandidateNode, error) {
 
 	// must have finished
 	dec.finished = true
+	dec.rootMap.FootComment = dec.takeHeadComment()
 
 	if len(dec.rootMap.Content) == 0 {
 		return nil, io.EOF
@@ -249,6 +376,10 @@ func (dec *tomlDecoder) processTopLevelNode(currentNode *toml.Node) (bool, error
 	var runAgainstCurrentExp bool
 	var err error
 	log.Debug("processTopLevelNode: Going to process %v state is current %v", currentNode.Kind, NodeToString(dec.rootMap))
+	if !dec.seenExpression {
+		dec.seenExpression = true
+		dec.takeDocumentHeadComment()
+	}
 	if currentNode.Kind == toml.Table {
 		runAgainstCurrentExp, err = dec.processTable(currentNode)
 	} else if currentNode.Kind == toml.ArrayTable {
@@ -263,8 +394,10 @@ func (dec *tomlDecoder) processTopLevelNode(currentNode *toml.Node) (bool, error
 
 func (dec *tomlDecoder) processTable(currentNode *toml.Node) (bool, error) {
 	log.Debug("Enter processTable")
-	fullPath := dec.getFullPath(currentNode.Child())
+	fullPath := dec.resolveArrayTablePath(dec.getFullPath(currentNode.Child()))
 	log.Debug("fullpath: %v", fullPath)
+	headComment := dec.takeHeadComment()
+	lineComment := dec.lineComment(currentNode)
 
 	tableNodeValue := &CandidateNode{
 		Kind:    MappingNode,
@@ -275,19 +408,19 @@ func (dec *tomlDecoder) processTable(currentNode *toml.Node) (bool, error) {
 	var tableValue *toml.Node
 	runAgainstCurrentExp := false
 	var err error
-	hasValue := dec.parser.NextExpression()
+	hasValue := dec.nextExpression()
 	// check to see if there is any table data
 	if hasValue {
 		tableValue = dec.parser.Expression()
-		// next expression is not table data, so we are done
+		// next expression is not table data, so this is an empty table
 		if tableValue.Kind != toml.KeyValue {
-			log.Debug("got an empty table, returning")
-			return true, nil
-		}
-
-		runAgainstCurrentExp, err = dec.decodeKeyValuesIntoMap(tableNodeValue, tableValue)
-		if err != nil && !errors.Is(err, io.EOF) {
-			return false, err
+			log.Debug("got an empty table")
+			runAgainstCurrentExp = true
+		} else {
+			runAgainstCurrentExp, err = dec.decodeKeyValuesIntoMap(tableNodeValue, tableValue)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return false, err
+			}
 		}
 	}
 
@@ -297,6 +430,9 @@ func (dec *tomlDecoder) processTable(currentNode *toml.Node) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	key, value := findTomlEntry(dec.rootMap, fullPath)
+	keepTomlFlowStyles(tableNodeValue, value)
+	dec.applyComments(key, value, headComment, lineComment)
 	return runAgainstCurrentExp, nil
 }
 
@@ -324,27 +460,36 @@ func (dec *tomlDecoder) arrayAppend(context Context, path []interface{}, rhsNode
 
 func (dec *tomlDecoder) processArrayTable(currentNode *toml.Node) (bool, error) {
 	log.Debug("Entering processArrayTable")
-	fullPath := dec.getFullPath(currentNode.Child())
+	fullPath := dec.resolveArrayTablePath(dec.getFullPath(currentNode.Child()))
 	log.Debug("Fullpath: %v", fullPath)
+	headComment := dec.takeHeadComment()
+	lineComment := dec.lineComment(currentNode)
 
 	// need to use the array append exp to add another entry to
 	// this array: fullpath += [ thing ]
 
-	hasValue := dec.parser.NextExpression()
-	if !hasValue {
-		return false, fmt.Errorf("error retrieving table %v value: %w", fullPath, dec.parser.Error())
-	}
-
 	tableNodeValue := &CandidateNode{
 		Kind: MappingNode,
 		Tag:  "!!map",
 	}
 
-	tableValue := dec.parser.Expression()
-	runAgainstCurrentExp, err := dec.decodeKeyValuesIntoMap(tableNodeValue, tableValue)
-	log.Debugf("table node err: %w", err)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return false, err
+	runAgainstCurrentExp := false
+	var err error
+	hasValue := dec.nextExpression()
+	if !hasValue && dec.parser.Error() != nil {
+		return false, fmt.Errorf("error retrieving table %v value: %w", fullPath, dec.parser.Error())
+	} else if hasValue {
+		tableValue := dec.parser.Expression()
+		if tableValue.Kind != toml.KeyValue {
+			// next expression is not table data, so this is an empty entry
+			runAgainstCurrentExp = true
+		} else {
+			runAgainstCurrentExp, err = dec.decodeKeyValuesIntoMap(tableNodeValue, tableValue)
+			log.Debugf("table node err: %w", err)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return false, err
+			}
+		}
 	}
 	c := Context{}
 
@@ -352,6 +497,146 @@ func (dec *tomlDecoder) processArrayTable(currentNode *toml.Node) (bool, error)
 
 	// += function
 	err = dec.arrayAppend(c, fullPath, tableNodeValue)
+	if err != nil {
+		return false, err
+	}
+	// the comments go on the new entry, there may be more of them in the array
+	if _, array := findTomlEntry(dec.rootMap, fullPath); array != nil && len(array.Content) > 0 {
+		keepTomlFlowStyles(tableNodeValue, array.Content[len(array.Content)-1])
+		dec.applyComments(nil, array.Content[len(array.Content)-1], headComment, lineComment)
+	}
+	return runAgainstCurrentExp, nil
+}
 
-	return runAgainstCurrentExp, err
+// nextExpression moves on to the next key value or table, keeping the comments on their own lines until then.
+func (dec *tomlDecoder) nextExpression() bool {
+	for dec.parser.NextExpression() {
+		expression := dec.parser.Expression()
+		if expression.Kind != toml.Comment {
+			return true
+		}
+		dec.pendingComments = append(dec.pendingComments, dec.commentText(expression))
+		if dec.isFollowedByBlankLine(expression) {
+			dec.pendingComments = append(dec.pendingComments, "")
+		}
+	}
+	return false
+}
+
+// commentText is the comment as yaml stores it, e.g. "# a comment"
+func (dec *tomlDecoder) commentText(comment *toml.Node) string {
+	return strings.TrimRight(string(comment.Data), "\r")
+}
+
+// commentGroup lists a run of comments, as the parser groups those in arrays
+func (dec *tomlDecoder) commentGroup(comment *toml.Node) []*toml.Node {
+	comments := []*toml.Node{comment}
+	iterator := comment.Children()
+	for iterator.Next() {
+		comments = append(comments, iterator.Node())
+	}
+	return comments
+}
+
+// isOwnLineComment is whether only whitespace comes before the comment on its line
+func (dec *tomlDecoder) isOwnLineComment(comment *toml.Node) bool {
+	data := dec.parser.Data()
+	for i := int(comment.Raw.Offset) - 1; i >= 0; i-- {
+		switch data[i] {
+		case '\n':
+			return true
+		case ' ', '\t':
+			continue
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (dec *tomlDecoder) isFollowedByBlankLine(comment *toml.Node) bool {
+	rest := dec.parser.Data()[comment.Raw.Offset+comment.Raw.Length:]
+	rest = bytes.TrimPrefix(rest, []byte("\n"))
+	rest = bytes.TrimLeft(rest, " \t")
+	return bytes.HasPrefix(rest, []byte("\n")) || bytes.HasPrefix(rest, []byte("\r\n"))
+}
+
+// lineComment is the comment at the end of a key value or table header line, if there is one
+func (dec *tomlDecoder) lineComment(expression *toml.Node) string {
+	comment := expression.Next()
+	if comment == nil || comment.Kind != toml.Comment {
+		return ""
+	}
+	return dec.commentText(comment)
+}
+
+// takeHeadComment returns the pending comments, keeping blank lines between them.
+func (dec *tomlDecoder) takeHeadComment() string {
+	comment := strings.Trim(strings.Join(dec.pendingComments, "\n"), "\n")
+	dec.pendingComments = dec.pendingComments[:0]
+	return comment
+}
+
+// takeDocumentHeadComment moves comments at the top of the file, that are separated
+// from the first key or table by a blank line, to the document.
+func (dec *tomlDecoder) takeDocumentHeadComment() {
+	for i := len(dec.pendingComments) - 1; i >= 0; i-- {
+		if dec.pendingComments[i] == "" {
+			rest := append(make([]string, 0), dec.pendingComments[i+1:]...)
+			dec.pendingComments = dec.pendingComments[:i]
+			dec.rootMap.HeadComment = dec.takeHeadComment()
+			dec.pendingComments = rest
+			return
+		}
+	}
+}
+
+// applyComments puts comments where yaml expects them: before the key, and for scalars
+// at the end of the value. Entries of arrays of tables have no key, yaml would move a line
+// comment on them to the next entry so it goes with the comments before them.
+func (dec *tomlDecoder) applyComments(key *CandidateNode, value *CandidateNode, headComment string, lineComment string) {
+	if value == nil {
+		return
+	}
+	if key == nil {
+		value.HeadComment = joinComments([]string{value.HeadComment, headComment, lineComment}, "\n")
+		return
+	}
+	key.HeadComment = joinComments([]string{key.HeadComment, headComment}, "\n")
+	target := key
+	if value.Kind == ScalarNode {
+		target = value
+	}
+	target.LineComment = joinComments([]string{target.LineComment, lineComment}, " ")
+}
+
+// findTomlEntry finds the key and value at the path, the key is nil for array entries.
+func findTomlEntry(node *CandidateNode, path []interface{}) (*CandidateNode, *CandidateNode) {
+	var key *CandidateNode
+	for _, pathElement := range path {
+		var next *CandidateNode
+		switch element := pathElement.(type) {
+		case string:
+			if node.Kind != MappingNode {
+				return nil, nil
+			}
+			for i := 0; i < len(node.Content)-1; i = i + 2 {
+				if node.Content[i].Value == element {
+					key = node.Content[i]
+					next = node.Content[i+1]
+				}
+			}
+		case int:
+			if node.Kind != SequenceNode || element >= len(node.Content) {
+				return nil, nil
+			}
+			key = nil
+			next = node.Content[element]
+		}
+		if next == nil {
+			return nil, nil
+		}
+		node = next
+	}
+	return key, node
 }
@@ -22,6 +22,9 @@ type xmlDecoder struct {
 }
 
 func NewXMLDecoder(prefs XmlPreferences) Decoder {
+	if prefs.Lossless {
+		return &xmlLosslessDecoder{prefs: prefs}
+	}
 	return &xmlDecoder{
 		finished: false,
 		prefs:    prefs,
@@ -0,0 +1,249 @@
+//go:build !yq_noxml
+
+package yqlib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// xmlLosslessDecoder keeps everything in the document, in order. Each element is a
+// single entry map of its name to its body. The body is the text of the element,
+// null for an empty tag like <a/>, or a map of its attributes and its content - a
+// list of the text, cdata, comments, processing instructions and child elements
+// within it. The document itself is a map of the content outside the root element.
+type xmlLosslessDecoder struct {
+	reader   io.Reader
+	finished bool
+	prefs    XmlPreferences
+}
+
+type xmlLosslessElement struct {
+	name        string
+	attributes  []xml.Attr
+	content     []*CandidateNode
+	selfClosing bool
+	layout      xmlStartTagLayout
+}
+
+// xmlStartTagLayout is how a start tag was written, beyond what the token tells us.
+type xmlStartTagLayout struct {
+	attributeSpaces []string
+	attributeQuotes []byte
+	// attributeValues are the values as written, before entities and character references are decoded
+	attributeValues []string
+	trailingSpace   string
+}
+
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// parseXMLStartTagLayout finds the white space before each attribute, the quote
+// used for its value, the value as written and the white space before the end of the tag.
+func parseXMLStartTagLayout(raw []byte, attributeCount int) xmlStartTagLayout {
+	layout := xmlStartTagLayout{}
+	i := 1
+	readSpace := func() string {
+		start := i
+		for i < len(raw) && isXMLSpace(raw[i]) {
+			i++
+		}
+		return string(raw[start:i])
+	}
+	// skip over the name
+	for i < len(raw) && !isXMLSpace(raw[i]) && raw[i] != '>' && raw[i] != '/' {
+		i++
+	}
+	for a := 0; a < attributeCount; a++ {
+		space := readSpace()
+		for i < len(raw) && raw[i] != '=' {
+			i++
+		}
+		i++
+		readSpace()
+		if i >= len(raw) || (raw[i] != '"' && raw[i] != '\'') {
+			// not what we expected, e.g. the input was in another charset
+			return xmlStartTagLayout{}
+		}
+		quote := raw[i]
+		i++
+		valueStart := i
+		for i < len(raw) && raw[i] != quote {
+			i++
+		}
+		value := string(raw[valueStart:min(i, len(raw))])
+		i++
+		layout.attributeSpaces = append(layout.attributeSpaces, space)
+		layout.attributeQuotes = append(layout.attributeQuotes, quote)
+		layout.attributeValues = append(layout.attributeValues, value)
+	}
+	layout.trailingSpace = readSpace()
+	return layout
+}
+
+func (dec *xmlLosslessDecoder) Init(reader io.Reader) error {
+	dec.reader = reader
+	dec.finished = false
+	return nil
+}
+
+func xmlRawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func (dec *xmlLosslessDecoder) createEntry(key string, value *CandidateNode) *CandidateNode {
+	return dec.createEntryWithKey(createScalarNode(key, key), value)
+}
+
+func (dec *xmlLosslessDecoder) createEntryWithKey(key *CandidateNode, value *CandidateNode) *CandidateNode {
+	entry := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+	entry.AddKeyValueChild(key, value)
+	return entry
+}
+
+// createElementEntry creates the map of the element name to its body. Any white
+// space before the end of its start tag is kept on the name.
+func (dec *xmlLosslessDecoder) createElementEntry(elem *xmlLosslessElement) *CandidateNode {
+	key := createScalarNode(elem.name, elem.name)
+	key.tagSpace = elem.layout.trailingSpace
+	return dec.createEntryWithKey(key, dec.createBody(elem))
+}
+
+func (dec *xmlLosslessDecoder) createBody(elem *xmlLosslessElement) *CandidateNode {
+	if len(elem.attributes) == 0 {
+		switch {
+		case len(elem.content) == 0 && elem.selfClosing:
+			return createScalarNode(nil, "")
+		case len(elem.content) == 0:
+			return createScalarNode("", "")
+		case len(elem.content) == 1 && elem.content[0].Kind == ScalarNode:
+			return elem.content[0]
+		}
+	}
+
+	body := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+	for i, attr := range elem.attributes {
+		label := dec.prefs.AttributePrefix + xmlRawName(attr.Name)
+		key := createScalarNode(label, label)
+		value := createScalarNode(attr.Value, attr.Value)
+		if i < len(elem.layout.attributeSpaces) {
+			// the white space before an attribute is kept on its key
+			if elem.layout.attributeSpaces[i] != " " {
+				key.tagSpace = elem.layout.attributeSpaces[i]
+			}
+			if elem.layout.attributeQuotes[i] == '\'' {
+				value.Style = SingleQuotedStyle
+			}
+			value.rawValue = elem.layout.attributeValues[i]
+		}
+		body.AddKeyValueChild(key, value)
+	}
+	if !elem.selfClosing {
+		contentNode := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+		contentNode.AddChildren(elem.content)
+		body.AddKeyValueChild(createScalarNode(dec.prefs.ContentName, dec.prefs.ContentName), contentNode)
+	}
+	return body
+}
+
+func (dec *xmlLosslessDecoder) Decode() (*CandidateNode, error) {
+	if dec.finished {
+		return nil, io.EOF
+	}
+	dec.finished = true
+
+	// the raw input tells us what the tokens do not: whether text was cdata and
+	// whether an element was self closing
+	data, err := io.ReadAll(dec.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	xmlDec := xml.NewDecoder(bytes.NewReader(data))
+	xmlDec.Strict = dec.prefs.StrictMode
+	xmlDec.CharsetReader = charset.NewReaderLabel
+
+	root := &xmlLosslessElement{}
+	stack := []*xmlLosslessElement{root}
+	readAnything := false
+
+	for {
+		tokenStart := xmlDec.InputOffset()
+		// raw tokens keep namespace prefixes exactly as they were written
+		t, err := xmlDec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		rawToken := data[min(tokenStart, int64(len(data))):min(xmlDec.InputOffset(), int64(len(data)))]
+		current := stack[len(stack)-1]
+
+		switch se := t.(type) {
+		case xml.StartElement:
+			elem := &xmlLosslessElement{
+				name:        xmlRawName(se.Name),
+				attributes:  se.Copy().Attr,
+				selfClosing: bytes.HasSuffix(rawToken, []byte("/>")),
+				layout:      parseXMLStartTagLayout(rawToken, len(se.Attr)),
+			}
+			stack = append(stack, elem)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("invalid XML: unexpected end element </%v>", xmlRawName(se.Name))
+			}
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			parent.content = append(parent.content, dec.createElementEntry(current))
+		case xml.CharData:
+			text := string(se)
+			if bytes.HasPrefix(rawToken, []byte("<![CDATA[")) {
+				current.content = append(current.content, dec.createEntry(dec.prefs.CDataName, createScalarNode(text, text)))
+			} else if len(stack) == 1 && strings.TrimSpace(text) != "" {
+				return nil, fmt.Errorf("invalid XML: Encountered chardata [%v] outside of XML node", strings.TrimSpace(text))
+			} else {
+				// the text as written is kept, so entities and character references can be written back the same way
+				textNode := createScalarNode(text, text)
+				textNode.rawValue = string(rawToken)
+				current.content = append(current.content, textNode)
+			}
+		case xml.Comment:
+			comment := string(se)
+			current.content = append(current.content, dec.createEntry(dec.prefs.CommentName, createScalarNode(comment, comment)))
+		case xml.ProcInst:
+			if !dec.prefs.SkipProcInst {
+				inst := string(se.Inst)
+				current.content = append(current.content, dec.createEntry(dec.prefs.ProcInstPrefix+se.Target, createScalarNode(inst, inst)))
+			}
+		case xml.Directive:
+			if !dec.prefs.SkipDirectives {
+				directive := string(se)
+				current.content = append(current.content, dec.createEntry(dec.prefs.DirectiveName, createScalarNode(directive, directive)))
+			}
+		}
+		readAnything = true
+	}
+
+	if !readAnything {
+		return nil, io.EOF
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("invalid XML: element <%v> is not closed", stack[len(stack)-1].name)
+	}
+
+	document := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+	contentNode := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+	contentNode.AddChildren(root.content)
+	document.AddKeyValueChild(createScalarNode(dec.prefs.ContentName, dec.prefs.ContentName), contentNode)
+	return document, nil
+}
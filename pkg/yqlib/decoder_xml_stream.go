@@ -0,0 +1,170 @@
+//go:build !yq_noxml
+
+package yqlib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html/charset"
+)
+
+type xmlStreamElement struct {
+	path []*CandidateNode
+	// how many child elements of each name there have been, for their index
+	childCounts map[string]int
+	// the text of an element with no attributes or children yet, it is the value of
+	// the element if none follow
+	text []string
+	// the path of the last attribute, text or child, for the closing event
+	lastEntry []*CandidateNode
+}
+
+// xmlStreamDecoder reads XML token by token, returning [path, leaf] stream events
+// without holding the document in memory. Elements are decoded like the default XML
+// decoder, except that a child element can't be known to be repeated until it is,
+// so each child element is always indexed by its position among those with its name.
+type xmlStreamDecoder struct {
+	prefs    XmlPreferences
+	decoder  *xml.Decoder
+	elements []*xmlStreamElement
+	pending  []*CandidateNode
+}
+
+func NewXMLStreamDecoder(prefs XmlPreferences) Decoder {
+	return &xmlStreamDecoder{prefs: prefs}
+}
+
+func (dec *xmlStreamDecoder) Init(reader io.Reader) error {
+	dec.decoder = xml.NewDecoder(reader)
+	dec.decoder.Strict = dec.prefs.StrictMode
+	dec.decoder.CharsetReader = charset.NewReaderLabel
+	dec.elements = nil
+	dec.pending = nil
+	return nil
+}
+
+func (dec *xmlStreamDecoder) appendPath(path []*CandidateNode, elements ...*CandidateNode) []*CandidateNode {
+	return append(path[:len(path):len(path)], elements...)
+}
+
+func (dec *xmlStreamDecoder) emit(path []*CandidateNode, leaf *CandidateNode) {
+	dec.pending = append(dec.pending, createStreamEvent(path, leaf))
+}
+
+// emitEntry emits a leaf in the map of the element
+func (dec *xmlStreamDecoder) emitEntry(elem *xmlStreamElement, key string, value string) {
+	elem.lastEntry = dec.appendPath(elem.path, createStringScalarNode(key))
+	dec.emit(elem.lastEntry, createScalarNode(value, value))
+}
+
+// flushText emits text read before the attributes or children of an element as its content
+func (dec *xmlStreamDecoder) flushText(elem *xmlStreamElement) {
+	for _, text := range elem.text {
+		dec.emitEntry(elem, dec.prefs.ContentName, text)
+	}
+	elem.text = nil
+}
+
+func (dec *xmlStreamDecoder) top() *xmlStreamElement {
+	if len(dec.elements) == 0 {
+		return nil
+	}
+	return dec.elements[len(dec.elements)-1]
+}
+
+func (dec *xmlStreamDecoder) startElement(se xml.StartElement) {
+	elem := &xmlStreamElement{childCounts: make(map[string]int)}
+	if parent := dec.top(); parent != nil {
+		dec.flushText(parent)
+		index := parent.childCounts[se.Name.Local]
+		parent.childCounts[se.Name.Local]++
+		elem.path = dec.appendPath(parent.path, createStringScalarNode(se.Name.Local), createScalarNode(index, fmt.Sprintf("%v", index)))
+	} else {
+		elem.path = []*CandidateNode{createStringScalarNode(se.Name.Local)}
+	}
+	dec.elements = append(dec.elements, elem)
+
+	for _, a := range se.Attr {
+		if dec.prefs.KeepNamespace && a.Name.Space != "" {
+			a.Name.Local = a.Name.Space + ":" + a.Name.Local
+		}
+		dec.emitEntry(elem, dec.prefs.AttributePrefix+a.Name.Local, a.Value)
+	}
+}
+
+func (dec *xmlStreamDecoder) endElement() {
+	elem := dec.top()
+	dec.elements = dec.elements[:len(dec.elements)-1]
+
+	switch {
+	case elem.lastEntry != nil:
+		dec.flushText(elem)
+		dec.emit(elem.lastEntry, nil)
+	case len(elem.text) == 0:
+		dec.emit(elem.path, createScalarNode(nil, ""))
+	case len(elem.text) == 1:
+		dec.emit(elem.path, createScalarNode(elem.text[0], elem.text[0]))
+	default:
+		var last []*CandidateNode
+		for index, text := range elem.text {
+			last = dec.appendPath(elem.path, createScalarNode(index, fmt.Sprintf("%v", index)))
+			dec.emit(last, createScalarNode(text, text))
+		}
+		dec.emit(last, nil)
+	}
+
+	if parent := dec.top(); parent != nil {
+		parent.lastEntry = elem.path
+	} else {
+		// the end of the document, which is a map of the root element
+		dec.emit(elem.path, nil)
+	}
+}
+
+func (dec *xmlStreamDecoder) Decode() (*CandidateNode, error) {
+	for len(dec.pending) == 0 {
+		var token xml.Token
+		var err error
+		if dec.prefs.UseRawToken {
+			token, err = dec.decoder.RawToken()
+		} else {
+			token, err = dec.decoder.Token()
+		}
+		if errors.Is(err, io.EOF) {
+			if len(dec.elements) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch se := token.(type) {
+		case xml.StartElement:
+			dec.startElement(se)
+		case xml.EndElement:
+			if len(dec.elements) == 0 {
+				return nil, fmt.Errorf("invalid XML: unexpected end element </%v>", se.Name.Local)
+			}
+			dec.endElement()
+		case xml.CharData:
+			text := trimNonGraphic(string(se))
+			elem := dec.top()
+			switch {
+			case text == "":
+			case elem == nil:
+				return nil, fmt.Errorf("invalid XML: Encountered chardata [%v] outside of XML node", text)
+			case elem.lastEntry != nil:
+				dec.emitEntry(elem, dec.prefs.ContentName, text)
+			default:
+				elem.text = append(elem.text, text)
+			}
+		}
+	}
+	event := dec.pending[0]
+	dec.pending = dec.pending[1:]
+	return event, nil
+}
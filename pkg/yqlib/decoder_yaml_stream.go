@@ -0,0 +1,152 @@
+package yqlib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mikefarah/yq/v4/pkg/yqlib/internal/yamlparser"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlStreamRecording collects the events of an anchored map or sequence, relative to
+// its path, so they can be given again for its aliases.
+type yamlStreamRecording struct {
+	anchor string
+	depth  int
+	events []*CandidateNode
+}
+
+// yamlStreamDecoder reads YAML parser events, returning [path, leaf] stream events
+// without holding the document in memory. Only the current path, and the values of
+// anchors so aliases can be expanded, are kept.
+type yamlStreamDecoder struct {
+	parser     *yamlparser.Parser
+	path       streamPath
+	anchors    map[string][]*CandidateNode
+	recordings []*yamlStreamRecording
+	// the events to return, an alias can give many
+	pending []*CandidateNode
+}
+
+func NewYamlStreamDecoder() Decoder {
+	return &yamlStreamDecoder{}
+}
+
+func (dec *yamlStreamDecoder) Init(reader io.Reader) error {
+	dec.parser = yamlparser.NewParser(reader)
+	dec.path = streamPath{}
+	dec.anchors = make(map[string][]*CandidateNode)
+	dec.recordings = nil
+	dec.pending = nil
+	return nil
+}
+
+func yamlStreamLeaf(node *yaml.Node) *CandidateNode {
+	leaf := &CandidateNode{Kind: ScalarNode}
+	if node.Kind != yaml.ScalarNode {
+		leaf.Kind = SequenceNode
+		if node.Kind == yaml.MappingNode {
+			leaf.Kind = MappingNode
+		}
+	}
+	leaf.copyFromYamlNode(node, make(map[string]*CandidateNode))
+	return leaf
+}
+
+// emit queues the event, and records it for the anchors it is within
+func (dec *yamlStreamDecoder) emit(path []*CandidateNode, leaf *CandidateNode) {
+	dec.pending = append(dec.pending, createStreamEvent(path, leaf))
+	for _, recording := range dec.recordings {
+		recording.events = append(recording.events, createStreamEvent(path[recording.depth:], leaf))
+	}
+}
+
+func (dec *yamlStreamDecoder) alias(node *yaml.Node) error {
+	events, exists := dec.anchors[node.Value]
+	if !exists {
+		return fmt.Errorf("yaml: line %v: unknown anchor '%v' referenced", node.Line, node.Value)
+	}
+	if dec.path.expectingKey() {
+		if len(events) != 1 || len(events[0].Content) != 2 || events[0].Content[1].Kind != ScalarNode {
+			return fmt.Errorf("yaml: line %v: --stream only supports scalar keys", node.Line)
+		}
+		dec.path.setKey(events[0].Content[1])
+		return nil
+	}
+	dec.path.beginValue()
+	path := dec.path.current()
+	for _, event := range events {
+		relativePath, leaf, err := parseStreamEvent(event)
+		if err != nil {
+			return err
+		}
+		dec.emit(append(path[:len(path):len(path)], relativePath...), leaf)
+	}
+	dec.path.endValue()
+	return nil
+}
+
+func (dec *yamlStreamDecoder) startCollection(node *yaml.Node) error {
+	if dec.path.expectingKey() {
+		return fmt.Errorf("yaml: line %v: --stream only supports scalar keys", node.Line)
+	}
+	dec.path.beginValue()
+	if node.Anchor != "" {
+		dec.recordings = append(dec.recordings, &yamlStreamRecording{anchor: node.Anchor, depth: len(dec.path.frames)})
+	}
+	empty := yamlStreamLeaf(node)
+	empty.Style = FlowStyle
+	dec.path.push(empty)
+	return nil
+}
+
+func (dec *yamlStreamDecoder) endCollection() {
+	dec.emit(dec.path.pop())
+
+	// the recording of an anchored collection ends with it
+	last := len(dec.recordings) - 1
+	if last >= 0 && dec.recordings[last].depth == len(dec.path.frames) {
+		dec.anchors[dec.recordings[last].anchor] = dec.recordings[last].events
+		dec.recordings = dec.recordings[:last]
+	}
+}
+
+func (dec *yamlStreamDecoder) Decode() (*CandidateNode, error) {
+	for len(dec.pending) == 0 {
+		event, err := dec.parser.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch event.Kind {
+		case yamlparser.ScalarEvent:
+			leaf := yamlStreamLeaf(event.Node)
+			if dec.path.expectingKey() {
+				dec.path.setKey(leaf)
+				continue
+			}
+			dec.path.beginValue()
+			if event.Node.Anchor != "" {
+				dec.anchors[event.Node.Anchor] = []*CandidateNode{createStreamEvent(nil, leaf)}
+			}
+			dec.emit(dec.path.current(), leaf)
+			dec.path.endValue()
+		case yamlparser.AliasEvent:
+			if err := dec.alias(event.Node); err != nil {
+				return nil, err
+			}
+		case yamlparser.MappingStartEvent, yamlparser.SequenceStartEvent:
+			if err := dec.startCollection(event.Node); err != nil {
+				return nil, err
+			}
+		case yamlparser.MappingEndEvent, yamlparser.SequenceEndEvent:
+			dec.endCollection()
+		}
+	}
+	event := dec.pending[0]
+	dec.pending = dec.pending[1:]
+	return event, nil
+}
@@ -0,0 +1,358 @@
+package yqlib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DiffPreferences configures how documents are compared.
+type DiffPreferences struct {
+	// Key matches the maps in a sequence by the value of this key (e.g. name, as Kubernetes lists are)
+	// instead of by their index.
+	Key string
+}
+
+// DiffOperation is a single difference between two documents, in the same terms as
+// a JSON Patch (RFC 6902) operation: applying the operations in order turns one document into the other.
+type DiffOperation struct {
+	// add, remove or replace
+	Op   string
+	Path []interface{}
+	// FromPath is where the old value is in the from document. It differs from Path
+	// when earlier operations have removed items before it from a sequence.
+	FromPath []interface{}
+	Value    *CandidateNode
+	OldValue *CandidateNode
+}
+
+// JSONPointer returns the path as a JSON Pointer (RFC 6901), e.g. /spec/containers/0/image
+func (o *DiffOperation) JSONPointer() string {
+	var sb strings.Builder
+	for _, element := range o.Path {
+		elementStr := fmt.Sprintf("%v", element)
+		elementStr = strings.ReplaceAll(elementStr, "~", "~0")
+		elementStr = strings.ReplaceAll(elementStr, "/", "~1")
+		sb.WriteString("/" + elementStr)
+	}
+	return sb.String()
+}
+
+// NicePath returns the path in yq syntax, e.g. .spec.containers[0].image
+func (o *DiffOperation) NicePath() string {
+	return expressionPath(o.Path)
+}
+
+func appendDiffPath(path []interface{}, element interface{}) []interface{} {
+	return append(path[:len(path):len(path)], element)
+}
+
+// Diff returns the operations that turn the from node into the to node.
+func Diff(from *CandidateNode, to *CandidateNode, prefs DiffPreferences) []*DiffOperation {
+	return diffNodes(from, to, make([]interface{}, 0), make([]interface{}, 0), prefs, make([]*DiffOperation, 0))
+}
+
+// DiffFiles compares each document of the from file with the document at the same index of the to file,
+// returning the operations for each document. Each file is read with its own decoder, so they can be
+// in different formats.
+func DiffFiles(fromFilename string, toFilename string, fromDecoder Decoder, toDecoder Decoder, prefs DiffPreferences) ([][]*DiffOperation, error) {
+	fromDocuments, err := readDiffFile(fromFilename, fromDecoder)
+	if err != nil {
+		return nil, err
+	}
+	toDocuments, err := readDiffFile(toFilename, toDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([][]*DiffOperation, 0)
+	for index := 0; index < len(fromDocuments) || index < len(toDocuments); index++ {
+		switch {
+		case index >= len(toDocuments):
+			documents = append(documents, []*DiffOperation{{Op: "remove", Path: make([]interface{}, 0), FromPath: make([]interface{}, 0), OldValue: fromDocuments[index]}})
+		case index >= len(fromDocuments):
+			documents = append(documents, []*DiffOperation{{Op: "add", Path: make([]interface{}, 0), Value: toDocuments[index]}})
+		default:
+			documents = append(documents, Diff(fromDocuments[index], toDocuments[index], prefs))
+		}
+	}
+	return documents, nil
+}
+
+func readDiffFile(filename string, decoder Decoder) ([]*CandidateNode, error) {
+	reader, err := readStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer SafelyCloseReader(reader)
+
+	documents, err := readDocuments(reader, filename, 0, decoder)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*CandidateNode, 0, documents.Len())
+	for el := documents.Front(); el != nil; el = el.Next() {
+		nodes = append(nodes, el.Value.(*CandidateNode))
+	}
+	return nodes, nil
+}
+
+// diffNodes compares the nodes, where path is the path of the operations and fromPath
+// is where the from node is in the original document.
+func diffNodes(from *CandidateNode, to *CandidateNode, path []interface{}, fromPath []interface{}, prefs DiffPreferences, operations []*DiffOperation) []*DiffOperation {
+	if from.Kind == AliasNode {
+		from = from.Alias
+	}
+	if to.Kind == AliasNode {
+		to = to.Alias
+	}
+
+	switch {
+	case from.Kind == MappingNode && to.Kind == MappingNode:
+		return diffMaps(from, to, path, fromPath, prefs, operations)
+	case from.Kind == SequenceNode && to.Kind == SequenceNode:
+		if prefs.Key != "" {
+			if fromKeys, toKeys, ok := diffSequenceKeys(from, to, prefs.Key); ok {
+				return diffKeyedSequences(from, to, fromKeys, toKeys, path, fromPath, prefs, operations)
+			}
+		}
+		return diffSequences(from, to, path, fromPath, prefs, operations)
+	case !recursiveNodeEqual(from, to):
+		return append(operations, &DiffOperation{Op: "replace", Path: path, FromPath: fromPath, Value: to, OldValue: from})
+	}
+	return operations
+}
+
+func diffMapValue(node *CandidateNode, key string) *CandidateNode {
+	for index := 0; index+1 < len(node.Content); index = index + 2 {
+		if node.Content[index].Value == key {
+			return node.Content[index+1]
+		}
+	}
+	return nil
+}
+
+func diffMaps(from *CandidateNode, to *CandidateNode, path []interface{}, fromPath []interface{}, prefs DiffPreferences, operations []*DiffOperation) []*DiffOperation {
+	for index := 0; index+1 < len(from.Content); index = index + 2 {
+		key := from.Content[index].Value
+		toValue := diffMapValue(to, key)
+		if toValue == nil {
+			operations = append(operations, &DiffOperation{Op: "remove", Path: appendDiffPath(path, key), FromPath: appendDiffPath(fromPath, key), OldValue: from.Content[index+1]})
+		} else {
+			operations = diffNodes(from.Content[index+1], toValue, appendDiffPath(path, key), appendDiffPath(fromPath, key), prefs, operations)
+		}
+	}
+	for index := 0; index+1 < len(to.Content); index = index + 2 {
+		key := to.Content[index].Value
+		if diffMapValue(from, key) == nil {
+			operations = append(operations, &DiffOperation{Op: "add", Path: appendDiffPath(path, key), Value: to.Content[index+1]})
+		}
+	}
+	return operations
+}
+
+func diffSequences(from *CandidateNode, to *CandidateNode, path []interface{}, fromPath []interface{}, prefs DiffPreferences, operations []*DiffOperation) []*DiffOperation {
+	index := 0
+	for ; index < len(from.Content) && index < len(to.Content); index++ {
+		operations = diffNodes(from.Content[index], to.Content[index], appendDiffPath(path, index), appendDiffPath(fromPath, index), prefs, operations)
+	}
+	// remove from the end, so the indices of the remaining items do not change
+	for removeIndex := len(from.Content) - 1; removeIndex >= index; removeIndex-- {
+		operations = append(operations, &DiffOperation{Op: "remove", Path: appendDiffPath(path, removeIndex), FromPath: appendDiffPath(fromPath, removeIndex), OldValue: from.Content[removeIndex]})
+	}
+	for ; index < len(to.Content); index++ {
+		operations = append(operations, &DiffOperation{Op: "add", Path: appendDiffPath(path, index), Value: to.Content[index]})
+	}
+	return operations
+}
+
+func diffSequenceItemKey(item *CandidateNode, key string) (string, bool) {
+	if item.Kind == AliasNode {
+		item = item.Alias
+	}
+	if item.Kind != MappingNode {
+		return "", false
+	}
+	keyValue := diffMapValue(item, key)
+	if keyValue == nil || keyValue.Kind != ScalarNode {
+		return "", false
+	}
+	return keyValue.Value, true
+}
+
+// diffSequenceKeys returns the key of every item, if every item in both sequences has a unique one.
+func diffSequenceKeys(from *CandidateNode, to *CandidateNode, key string) ([]string, []string, bool) {
+	keysOf := func(node *CandidateNode) ([]string, bool) {
+		keys := make([]string, 0, len(node.Content))
+		seen := make(map[string]bool)
+		for _, item := range node.Content {
+			itemKey, ok := diffSequenceItemKey(item, key)
+			if !ok || seen[itemKey] {
+				return nil, false
+			}
+			seen[itemKey] = true
+			keys = append(keys, itemKey)
+		}
+		return keys, true
+	}
+	fromKeys, fromOk := keysOf(from)
+	toKeys, toOk := keysOf(to)
+	return fromKeys, toKeys, fromOk && toOk
+}
+
+func diffKeyedSequences(from *CandidateNode, to *CandidateNode, fromKeys []string, toKeys []string, path []interface{}, fromPath []interface{}, prefs DiffPreferences, operations []*DiffOperation) []*DiffOperation {
+	fromIndices := make(map[string]int)
+	for index, key := range fromKeys {
+		fromIndices[key] = index
+	}
+	toIndices := make(map[string]int)
+	for index, key := range toKeys {
+		toIndices[key] = index
+	}
+
+	// the items in both must be in the same order, otherwise there are
+	// no simple add and remove operations that match them up.
+	lastFromIndex := -1
+	for _, key := range toKeys {
+		if fromIndex, exists := fromIndices[key]; exists {
+			if fromIndex < lastFromIndex {
+				return append(operations, &DiffOperation{Op: "replace", Path: path, FromPath: fromPath, Value: to, OldValue: from})
+			}
+			lastFromIndex = fromIndex
+		}
+	}
+
+	for index := len(fromKeys) - 1; index >= 0; index-- {
+		if _, exists := toIndices[fromKeys[index]]; !exists {
+			operations = append(operations, &DiffOperation{Op: "remove", Path: appendDiffPath(path, index), FromPath: appendDiffPath(fromPath, index), OldValue: from.Content[index]})
+		}
+	}
+	// with the removed items gone, the remaining items line up with
+	// the to sequence as the added items are inserted in order.
+	for index, key := range toKeys {
+		if fromIndex, exists := fromIndices[key]; exists {
+			operations = diffNodes(from.Content[fromIndex], to.Content[index], appendDiffPath(path, index), appendDiffPath(fromPath, fromIndex), prefs, operations)
+		} else {
+			operations = append(operations, &DiffOperation{Op: "add", Path: appendDiffPath(path, index), Value: to.Content[index]})
+		}
+	}
+	return operations
+}
+
+// DiffToPatch returns the operations as a JSON Patch (RFC 6902) document.
+func DiffToPatch(operations []*DiffOperation) *CandidateNode {
+	patch := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+	for _, operation := range operations {
+		operationNode := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+		operationNode.AddKeyValueChild(createStringScalarNode("op"), createStringScalarNode(operation.Op))
+		operationNode.AddKeyValueChild(createStringScalarNode("path"), createStringScalarNode(operation.JSONPointer()))
+		if operation.Value != nil {
+			operationNode.AddKeyValueChild(createStringScalarNode("value"), operation.Value)
+		}
+		patch.AddChild(operationNode)
+	}
+	return patch
+}
+
+func setDiffValueStyle(node *CandidateNode) {
+	switch node.Kind {
+	case MappingNode, SequenceNode:
+		node.Style = FlowStyle
+	case ScalarNode:
+		if strings.ContainsRune(node.Value, '\n') {
+			node.Style = DoubleQuotedStyle
+		}
+	}
+	node.HeadComment = ""
+	node.LineComment = ""
+	node.FootComment = ""
+	for _, child := range node.Content {
+		setDiffValueStyle(child)
+	}
+}
+
+func diffValueString(node *CandidateNode) (string, error) {
+	value := node.Copy()
+	value.Key = nil
+	setDiffValueStyle(value)
+
+	prefs := ConfiguredYamlPreferences.Copy()
+	prefs.ColorsEnabled = false
+	prefs.UnwrapScalar = true
+	prefs.PrintDocSeparators = false
+
+	var output bytes.Buffer
+	writer := bufio.NewWriter(&output)
+	printer := NewPrinter(NewYamlEncoder(prefs), NewSinglePrinterWriter(writer))
+	if err := printer.PrintResults(value.AsList()); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(output.String(), "\n"), nil
+}
+
+func printDiffOperation(writer io.Writer, operation *DiffOperation, removed *color.Color, added *color.Color) error {
+	if operation.OldValue != nil {
+		value, err := diffValueString(operation.OldValue)
+		if err != nil {
+			return err
+		}
+		// old values are shown where they are in the from document
+		if _, err := removed.Fprintf(writer, "- %v: %v\n", expressionPath(operation.FromPath), value); err != nil {
+			return err
+		}
+	}
+	if operation.Value != nil {
+		value, err := diffValueString(operation.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := added.Fprintf(writer, "+ %v: %v\n", operation.NicePath(), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintDiff writes the operations of each document as a readable, unified diff like view,
+// with a '-' line for each old value and a '+' line for each new value.
+func PrintDiff(writer io.Writer, fromFilename string, toFilename string, documents [][]*DiffOperation, colorsEnabled bool) error {
+	removed := color.New(color.FgRed)
+	added := color.New(color.FgGreen)
+	header := color.New(color.Bold)
+	documentHeader := color.New(color.FgCyan)
+	for _, c := range []*color.Color{removed, added, header, documentHeader} {
+		if colorsEnabled {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+	}
+
+	printedHeader := false
+	for index, operations := range documents {
+		if len(operations) == 0 {
+			continue
+		}
+		if !printedHeader {
+			if _, err := header.Fprintf(writer, "--- %v\n+++ %v\n", fromFilename, toFilename); err != nil {
+				return err
+			}
+			printedHeader = true
+		}
+		if len(documents) > 1 {
+			if _, err := documentHeader.Fprintf(writer, "@@ document %v @@\n", index); err != nil {
+				return err
+			}
+		}
+		for _, operation := range operations {
+			if err := printDiffOperation(writer, operation, removed, added); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -7,11 +7,13 @@ import (
 )
 
 type csvEncoder struct {
-	separator rune
+	separator    rune
+	headers      []string
+	missingValue string
 }
 
 func NewCsvEncoder(prefs CsvPreferences) Encoder {
-	return &csvEncoder{separator: prefs.Separator}
+	return &csvEncoder{separator: prefs.Separator, headers: prefs.Headers, missingValue: prefs.MissingValue}
 }
 
 func (e *csvEncoder) CanHandleAliases() bool {
@@ -53,49 +55,98 @@ func (e *csvEncoder) encodeArrays(csvWriter *csv.Writer, content []*CandidateNod
 	return nil
 }
 
-func (e *csvEncoder) extractHeader(child *CandidateNode) ([]*CandidateNode, error) {
-	if child.Kind != MappingNode {
-		return nil, fmt.Errorf("csv object encoding only works for arrays of flat objects (string key => string/numbers/boolean value), child[0] is a %v", child.Tag)
+// csvRow is an object flattened into dotted paths, in the order they were found.
+type csvRow struct {
+	paths  []string
+	values map[string]string
+	// collections are the tags of the maps and arrays that were flattened, by their path
+	collections map[string]string
+}
+
+func (e *csvEncoder) flattenObject(child *CandidateNode) *csvRow {
+	row := &csvRow{values: map[string]string{}, collections: map[string]string{}}
+	e.flatten(row, child, "")
+	return row
+}
+
+func (e *csvEncoder) flatten(row *csvRow, node *CandidateNode, path string) {
+	switch node.Kind {
+	case ScalarNode:
+		if _, exists := row.values[path]; !exists {
+			row.paths = append(row.paths, path)
+		}
+		row.values[path] = node.Value
+	case SequenceNode:
+		row.collections[path] = node.Tag
+		for index, child := range node.Content {
+			e.flatten(row, child, appendFlattenedPath(path, index, false))
+		}
+	case MappingNode:
+		row.collections[path] = node.Tag
+		for index := 0; index < len(node.Content); index = index + 2 {
+			e.flatten(row, node.Content[index+1], appendFlattenedPath(path, node.Content[index].Value, false))
+		}
+	case AliasNode:
+		e.flatten(row, node.Alias, path)
 	}
-	mapKeys := getMapKeys(child)
-	return mapKeys.Content, nil
 }
 
-func (e *csvEncoder) createChildRow(child *CandidateNode, headers []*CandidateNode) []*CandidateNode {
-	childRow := make([]*CandidateNode, 0)
-	for _, header := range headers {
-		keyIndex := findKeyInMap(child, header)
-		value := createScalarNode(nil, "")
-		if keyIndex != -1 {
-			value = child.Content[keyIndex+1]
+// extractHeaders returns the configured headers, or else every path found in the rows, in the order they were first found.
+func (e *csvEncoder) extractHeaders(rows []*csvRow) []string {
+	if len(e.headers) > 0 {
+		return e.headers
+	}
+	headers := []string{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, path := range row.paths {
+			if !seen[path] {
+				seen[path] = true
+				headers = append(headers, path)
+			}
 		}
-		childRow = append(childRow, value)
 	}
-	return childRow
+	return headers
+}
 
+func (e *csvEncoder) createChildRow(row *csvRow, index int, headers []string) ([]string, error) {
+	childRow := make([]string, len(headers))
+	for i, header := range headers {
+		value, exists := row.values[header]
+		if tag, isCollection := row.collections[header]; isCollection {
+			return nil, fmt.Errorf("csv header '%v' of child[%v] is a %v, only scalar values can be encoded", header, index, tag)
+		} else if !exists {
+			value = e.missingValue
+		}
+		childRow[i] = value
+	}
+	return childRow, nil
 }
 
 func (e *csvEncoder) encodeObjects(csvWriter *csv.Writer, content []*CandidateNode) error {
-	headers, err := e.extractHeader(content[0])
-	if err != nil {
-		return nil
+	rows := make([]*csvRow, len(content))
+	for i, child := range content {
+		if child.Kind != MappingNode {
+			return fmt.Errorf("csv object encoding only works for arrays of objects, child[%v] is a %v", i, child.Tag)
+		}
+		rows[i] = e.flattenObject(child)
 	}
 
-	err = e.encodeRow(csvWriter, headers)
+	headers := e.extractHeaders(rows)
+	err := csvWriter.Write(headers)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	for i, child := range content {
-		if child.Kind != MappingNode {
-			return fmt.Errorf("csv object encoding only works for arrays of flat objects (string key => string/numbers/boolean value), child[%v] is a %v", i, child.Tag)
+	for i, row := range rows {
+		childRow, err := e.createChildRow(row, i, headers)
+		if err != nil {
+			return err
 		}
-		row := e.createChildRow(child, headers)
-		err = e.encodeRow(csvWriter, row)
+		err = csvWriter.Write(childRow)
 		if err != nil {
 			return err
 		}
-
 	}
 	return nil
 }
@@ -0,0 +1,307 @@
+//go:build !yq_nojson
+
+package yqlib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+var json5TrailingSpaceRegex = regexp.MustCompile(` +\n`)
+
+type json5Encoder struct {
+	prefs JsonPreferences
+	// jsonc only writes numbers that JSON allows, so e.g. hex numbers are written in decimal
+	jsonc bool
+}
+
+// NewJSON5Encoder writes JSON with comments. Keys are always quoted and there are no trailing
+// commas, numbers are kept as written where JSON5 allows it.
+func NewJSON5Encoder(prefs JsonPreferences) Encoder {
+	return &json5Encoder{prefs: prefs}
+}
+
+// NewJSONCEncoder writes JSON with comments like NewJSON5Encoder, but numbers are written as
+// JSON numbers, so unless the data has infinite or NaN numbers the output is valid JSONC.
+func NewJSONCEncoder(prefs JsonPreferences) Encoder {
+	return &json5Encoder{prefs: prefs, jsonc: true}
+}
+
+func (je *json5Encoder) CanHandleAliases() bool {
+	return false
+}
+
+func (je *json5Encoder) PrintDocumentSeparator(_ io.Writer) error {
+	return nil
+}
+
+func (je *json5Encoder) PrintLeadingContent(writer io.Writer, content string) error {
+	reader := bufio.NewReader(strings.NewReader(content))
+	for {
+		readline, errReading := reader.ReadString('\n')
+		if errReading != nil && !errors.Is(errReading, io.EOF) {
+			return errReading
+		}
+		trimmed := strings.TrimSpace(readline)
+		if strings.HasPrefix(trimmed, "#") {
+			if err := writeString(writer, "//"+strings.TrimPrefix(trimmed, "#")+"\n"); err != nil {
+				return err
+			}
+		} else if trimmed == "" && readline != "" {
+			if err := writeString(writer, "\n"); err != nil {
+				return err
+			}
+		}
+		if errors.Is(errReading, io.EOF) {
+			return nil
+		}
+	}
+}
+
+func (je *json5Encoder) Encode(writer io.Writer, node *CandidateNode) error {
+	if node.Kind == ScalarNode && je.prefs.UnwrapScalar {
+		return writeString(writer, node.Value+"\n")
+	}
+
+	var sb strings.Builder
+	je.writeHeadComment(&sb, node.HeadComment, 0)
+	if err := je.encodeNode(&sb, node, 0); err != nil {
+		return err
+	}
+	je.writeLineComment(&sb, node.LineComment)
+	je.writeFootComment(&sb, node, 0)
+	sb.WriteString("\n")
+	// blank comment lines are indented like the rest
+	return writeString(writer, json5TrailingSpaceRegex.ReplaceAllString(sb.String(), "\n"))
+}
+
+// json5CommentLines turns a yaml comment, e.g. "# a\n# b", into the text of each line after the '#'.
+// Blank lines in the comment are nil.
+func json5CommentLines(comment string) []*string {
+	if comment == "" {
+		return nil
+	}
+	rawLines := strings.Split(strings.TrimRight(comment, "\n"), "\n")
+	lines := make([]*string, len(rawLines))
+	for i, rawLine := range rawLines {
+		trimmed := strings.TrimLeft(rawLine, " \t")
+		if strings.HasPrefix(trimmed, "#") {
+			text := strings.TrimPrefix(trimmed, "#")
+			lines[i] = &text
+		}
+	}
+	return lines
+}
+
+func (je *json5Encoder) newLine(sb *strings.Builder, depth int) {
+	if je.prefs.Indent == 0 {
+		return
+	}
+	sb.WriteString("\n")
+	sb.WriteString(strings.Repeat(" ", depth*je.prefs.Indent))
+}
+
+// writeComment writes the comment lines at the given depth, without a new line after the last one
+func (je *json5Encoder) writeComment(sb *strings.Builder, comment string, depth int) bool {
+	lines := json5CommentLines(comment)
+	for i, line := range lines {
+		if je.prefs.Indent == 0 {
+			if line != nil {
+				sb.WriteString("/*" + *line + " */")
+			}
+			continue
+		}
+		if i > 0 {
+			je.newLine(sb, depth)
+		}
+		if line != nil {
+			sb.WriteString("//" + *line)
+		}
+	}
+	return len(lines) > 0
+}
+
+// writeHeadComment writes comments on their own lines, before a value at the given depth
+func (je *json5Encoder) writeHeadComment(sb *strings.Builder, comment string, depth int) {
+	if je.writeComment(sb, comment, depth) {
+		je.newLine(sb, depth)
+	}
+}
+
+// writeInlineHeadComment writes comments as block comments, before a value on the same line
+func (je *json5Encoder) writeInlineHeadComment(sb *strings.Builder, comment string, _ int) {
+	for _, line := range json5CommentLines(comment) {
+		if line != nil {
+			sb.WriteString("/*" + *line + " */")
+			if je.prefs.Indent > 0 {
+				sb.WriteString(" ")
+			}
+		}
+	}
+}
+
+func (je *json5Encoder) writeLineComment(sb *strings.Builder, comment string) {
+	lines := json5CommentLines(comment)
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line != nil {
+			texts = append(texts, strings.TrimSpace(*line))
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+	if je.prefs.Indent == 0 {
+		sb.WriteString("/* " + strings.Join(texts, " ") + " */")
+		return
+	}
+	sb.WriteString(" // " + strings.Join(texts, " "))
+}
+
+// writeFootComment writes the comments after a value, on the lines after it
+func (je *json5Encoder) writeFootComment(sb *strings.Builder, node *CandidateNode, depth int) {
+	if node.FootComment == "" || isEmptyJSON5Collection(node) {
+		return
+	}
+	je.newLine(sb, depth)
+	je.writeComment(sb, node.FootComment, depth)
+}
+
+func isEmptyJSON5Collection(node *CandidateNode) bool {
+	return (node.Kind == MappingNode || node.Kind == SequenceNode) && len(node.Content) == 0
+}
+
+// encodeNode writes the node at the current position, comments around it are written by the caller.
+func (je *json5Encoder) encodeNode(sb *strings.Builder, node *CandidateNode, depth int) error {
+	switch node.Kind {
+	case AliasNode:
+		return je.encodeNode(sb, node.Alias, depth)
+	case MappingNode:
+		return je.encodeCollection(sb, node, depth, "{", "}")
+	case SequenceNode:
+		return je.encodeCollection(sb, node, depth, "[", "]")
+	}
+	value, err := je.encodeScalar(node)
+	if err != nil {
+		return err
+	}
+	sb.WriteString(value)
+	return nil
+}
+
+func (je *json5Encoder) encodeCollection(sb *strings.Builder, node *CandidateNode, depth int, open string, close string) error {
+	sb.WriteString(open)
+
+	if isEmptyJSON5Collection(node) {
+		if node.FootComment != "" {
+			je.newLine(sb, depth+1)
+			je.writeComment(sb, node.FootComment, depth+1)
+			je.newLine(sb, depth)
+		}
+		sb.WriteString(close)
+		return nil
+	}
+
+	step := 1
+	if node.Kind == MappingNode {
+		step = 2
+	}
+	for index := 0; index+step-1 < len(node.Content); index = index + step {
+		value := node.Content[index+step-1]
+		je.newLine(sb, depth+1)
+
+		// entries that were on the same line as the one before keep their comments on that line too
+		entry := node.Content[index]
+		inline := index > 0 && entry.Line != 0 && entry.Line == node.Content[index-step].Line
+		writeHeadComment := je.writeHeadComment
+		if inline {
+			writeHeadComment = je.writeInlineHeadComment
+		}
+
+		lineComment := value.LineComment
+		if node.Kind == MappingNode {
+			key := node.Content[index]
+			writeHeadComment(sb, joinComments([]string{key.HeadComment, value.HeadComment}, "\n"), depth+1)
+			keyString, err := json5String(key.Value)
+			if err != nil {
+				return err
+			}
+			sb.WriteString(keyString + ":")
+			if je.prefs.Indent > 0 {
+				sb.WriteString(" ")
+			}
+			lineComment = joinComments([]string{key.LineComment, value.LineComment}, " ")
+		} else {
+			writeHeadComment(sb, value.HeadComment, depth+1)
+		}
+
+		if err := je.encodeNode(sb, value, depth+1); err != nil {
+			return err
+		}
+		if index+step < len(node.Content) {
+			sb.WriteString(",")
+		}
+		je.writeLineComment(sb, lineComment)
+		if node.Kind == MappingNode {
+			je.writeFootComment(sb, node.Content[index], depth+1)
+		}
+		je.writeFootComment(sb, value, depth+1)
+	}
+	je.newLine(sb, depth)
+	sb.WriteString(close)
+	return nil
+}
+
+func json5String(value string) (string, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func (je *json5Encoder) encodeScalar(node *CandidateNode) (string, error) {
+	if node.Tag == "!!int" || node.Tag == "!!float" {
+		switch strings.ToLower(node.Value) {
+		case ".inf", "+.inf":
+			return "Infinity", nil
+		case "-.inf":
+			return "-Infinity", nil
+		case ".nan":
+			return "NaN", nil
+		}
+		// numbers are kept as written where the format allows it
+		if je.jsonc && jsonNumberRegex.MatchString(node.Value) {
+			return node.Value, nil
+		} else if !je.jsonc && (json5NumberRegex.MatchString(node.Value) || json5HexNumberRegex.MatchString(node.Value)) {
+			return node.Value, nil
+		}
+	}
+	if node.Tag == "!!int" {
+		// hex and octal numbers are written in decimal
+		unsigned := strings.TrimLeft(node.Value, "+-")
+		if _, value, err := parseInt64(unsigned); err == nil {
+			return strings.TrimPrefix(node.Value[:len(node.Value)-len(unsigned)], "+") + strconv.FormatInt(value, 10), nil
+		}
+	}
+	value, err := node.GetValueRep()
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -61,6 +61,97 @@ func (pe *propertiesEncoder) PrintLeadingContent(writer io.Writer, content strin
 	return nil
 }
 
+// propertiesOutput writes each property as it is encoded, with the comments around it.
+type propertiesOutput struct {
+	writer io.Writer
+	// properties checks the values, e.g. for circular references
+	properties *properties.Properties
+	// headComments are the comment lines, and blank lines, waiting for the next property
+	headComments []string
+	written      bool
+	// separate is whether the next property should be kept apart from the last by a blank line
+	separate bool
+}
+
+// commentLines turns a yaml comment into properties comment lines, keeping the blank lines between them.
+func commentLines(comment string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if len(lines) > 0 && lines[len(lines)-1] != "" {
+				lines = append(lines, "")
+			}
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "!") {
+			trimmed = "# " + trimmed
+		}
+		lines = append(lines, trimmed)
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (po *propertiesOutput) addHeadComment(comment string) {
+	po.headComments = append(po.headComments, commentLines(comment)...)
+}
+
+func (po *propertiesOutput) writeLines(lines []string) error {
+	for _, line := range lines {
+		if err := writeString(po.writer, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeadComments writes the waiting comments, after a blank line if the next property is to be
+// kept apart from the last.
+func (po *propertiesOutput) writeHeadComments() error {
+	if po.written && po.separate {
+		if err := writeString(po.writer, "\n"); err != nil {
+			return err
+		}
+	}
+	err := po.writeLines(po.headComments)
+	po.headComments = po.headComments[:0]
+	po.written = po.written || err == nil
+	po.separate = false
+	return err
+}
+
+// writeFootComment writes comments straight after the last property, and keeps them apart from the next one.
+func (po *propertiesOutput) writeFootComment(comment string) error {
+	lines := commentLines(comment)
+	if len(lines) == 0 {
+		return nil
+	}
+	po.separate = true
+	return po.writeLines(lines)
+}
+
+func (po *propertiesOutput) writeProperty(key string, value string, separator string) error {
+	if _, _, err := po.properties.Set(key, value); err != nil {
+		return err
+	}
+	if err := po.writeHeadComments(); err != nil {
+		return err
+	}
+	// a properties of its own escapes the key and value
+	property := properties.NewProperties()
+	property.DisableExpansion = true
+	property.WriteSeparator = separator
+	if _, _, err := property.Set(key, value); err != nil {
+		return err
+	}
+	_, err := property.Write(po.writer, properties.UTF8)
+	po.written = true
+	return err
+}
+
 func (pe *propertiesEncoder) Encode(writer io.Writer, node *CandidateNode) error {
 
 	if node.Kind == ScalarNode {
@@ -68,56 +159,93 @@ func (pe *propertiesEncoder) Encode(writer io.Writer, node *CandidateNode) error
 	}
 
 	mapKeysToStrings(node)
-	p := properties.NewProperties()
-	p.WriteSeparator = pe.prefs.KeyValueSeparator
-	err := pe.doEncode(p, node, "", nil)
-	if err != nil {
+	output := &propertiesOutput{writer: writer, properties: properties.NewProperties()}
+	// the document comment is kept apart from the first property
+	if err := output.writeLines(commentLines(node.HeadComment)); err != nil {
 		return err
 	}
+	output.written = node.HeadComment != ""
+	output.separate = output.written
 
-	_, err = p.WriteComment(writer, "#", properties.UTF8)
-	return err
+	var err error
+	switch node.Kind {
+	case SequenceNode:
+		err = pe.encodeArray(output, node.Content, "")
+	case MappingNode:
+		err = pe.encodeMap(output, node.Content, "")
+	case AliasNode:
+		err = pe.doEncode(output, node.Alias, "", nil)
+	default:
+		err = fmt.Errorf("Unsupported node %v", node.Tag)
+	}
+	if err != nil {
+		return err
+	}
+	// comments on empty maps and arrays at the end have no property to go with
+	if len(output.headComments) > 0 {
+		if err := output.writeHeadComments(); err != nil {
+			return err
+		}
+	}
+	return output.writeFootComment(joinComments([]string{node.LineComment, node.FootComment}, "\n"))
 }
 
-func (pe *propertiesEncoder) doEncode(p *properties.Properties, node *CandidateNode, path string, keyNode *CandidateNode) error {
-
-	comments := ""
+func (pe *propertiesEncoder) doEncode(output *propertiesOutput, node *CandidateNode, path string, keyNode *CandidateNode) error {
+	// comments on maps and arrays go with their first property
+	if keyNode != nil {
+		output.addHeadComment(keyNode.HeadComment)
+	}
+	output.addHeadComment(node.HeadComment)
 	if keyNode != nil {
-		// include the key node comments if present
-		comments = headAndLineComment(keyNode)
+		output.addHeadComment(keyNode.LineComment)
 	}
-	comments = comments + headAndLineComment(node)
-	commentsWithSpaces := strings.ReplaceAll(comments, "\n", "\n ")
-	p.SetComments(path, strings.Split(commentsWithSpaces, "\n"))
+	output.addHeadComment(node.LineComment)
 
+	var err error
 	switch node.Kind {
 	case ScalarNode:
+		// blank lines in the input are kept, to keep its properties grouped the same way
+		output.separate = output.separate || node.blankLineBefore
 		var nodeValue string
 		if pe.prefs.UnwrapScalar || !strings.Contains(node.Value, " ") {
 			nodeValue = node.Value
 		} else {
 			nodeValue = fmt.Sprintf("%q", node.Value)
 		}
-		_, _, err := p.Set(path, nodeValue)
-		return err
+		err = output.writeProperty(path, nodeValue, pe.prefs.KeyValueSeparator)
 	case SequenceNode:
-		return pe.encodeArray(p, node.Content, path)
+		err = pe.encodeArray(output, node.Content, path)
 	case MappingNode:
-		return pe.encodeMap(p, node.Content, path)
+		err = pe.encodeMap(output, node.Content, path)
 	case AliasNode:
-		return pe.doEncode(p, node.Alias, path, nil)
+		err = pe.doEncode(output, node.Alias, path, nil)
 	default:
-		return fmt.Errorf("Unsupported node %v", node.Tag)
+		err = fmt.Errorf("Unsupported node %v", node.Tag)
+	}
+	if err != nil {
+		return err
 	}
+
+	if keyNode != nil {
+		if err := output.writeFootComment(keyNode.FootComment); err != nil {
+			return err
+		}
+	}
+	return output.writeFootComment(node.FootComment)
 }
 
 func (pe *propertiesEncoder) appendPath(path string, key interface{}) string {
+	return appendFlattenedPath(path, key, pe.prefs.UseArrayBrackets)
+}
+
+// appendFlattenedPath builds the dotted path of a nested value, e.g. a.b.0 or a.b[0]
+func appendFlattenedPath(path string, key interface{}, useArrayBrackets bool) string {
 	if path == "" {
 		return fmt.Sprintf("%v", key)
 	}
 	switch key.(type) {
 	case int:
-		if pe.prefs.UseArrayBrackets {
+		if useArrayBrackets {
 			return fmt.Sprintf("%v[%v]", path, key)
 		}
 
@@ -125,9 +253,9 @@ func (pe *propertiesEncoder) appendPath(path string, key interface{}) string {
 	return fmt.Sprintf("%v.%v", path, key)
 }
 
-func (pe *propertiesEncoder) encodeArray(p *properties.Properties, kids []*CandidateNode, path string) error {
+func (pe *propertiesEncoder) encodeArray(output *propertiesOutput, kids []*CandidateNode, path string) error {
 	for index, child := range kids {
-		err := pe.doEncode(p, child, pe.appendPath(path, index), nil)
+		err := pe.doEncode(output, child, pe.appendPath(path, index), nil)
 		if err != nil {
 			return err
 		}
@@ -135,11 +263,11 @@ func (pe *propertiesEncoder) encodeArray(p *properties.Properties, kids []*Candi
 	return nil
 }
 
-func (pe *propertiesEncoder) encodeMap(p *properties.Properties, kids []*CandidateNode, path string) error {
+func (pe *propertiesEncoder) encodeMap(output *propertiesOutput, kids []*CandidateNode, path string) error {
 	for index := 0; index < len(kids); index = index + 2 {
 		key := kids[index]
 		value := kids[index+1]
-		err := pe.doEncode(p, value, pe.appendPath(path, key.Value), key)
+		err := pe.doEncode(output, value, pe.appendPath(path, key.Value), key)
 		if err != nil {
 			return err
 		}
@@ -153,7 +153,7 @@ func TestPropertiesEncoderDeepWithComments(t *testing.T) {
 				{
 					key:     "a.b",
 					value:   "bob cool",
-					comment: "# b thing",
+					comment: "# a thing\n# b thing",
 				},
 			},
 		},
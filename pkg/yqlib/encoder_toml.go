@@ -1,10 +1,20 @@
 package yqlib
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+var tomlIntRegex = regexp.MustCompile(`^([+-]?(0|[1-9](_?[0-9])*)|0x[0-9A-Fa-f](_?[0-9A-Fa-f])*|0o[0-7](_?[0-7])*|0b[01](_?[01])*)$`)
+var tomlFloatRegex = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+
 type tomlEncoder struct {
 }
 
@@ -16,17 +26,395 @@ func (te *tomlEncoder) Encode(writer io.Writer, node *CandidateNode) error {
 	if node.Kind == ScalarNode {
 		return writeString(writer, node.Value+"\n")
 	}
-	return fmt.Errorf("only scalars (e.g. strings, numbers, booleans) are supported for TOML output at the moment. Please use yaml output format (-oy) until the encoder has been fully implemented")
+	if node.Kind != MappingNode {
+		return fmt.Errorf("TOML documents must be a map at the top level, cannot encode %v", node.Tag)
+	}
+
+	var buf bytes.Buffer
+	if err := te.writeComment(&buf, node.HeadComment); err != nil {
+		return err
+	}
+	if node.HeadComment != "" {
+		// the document comment is kept apart from the first key or table
+		if err := writeString(&buf, "\n"); err != nil {
+			return err
+		}
+	}
+	var table bytes.Buffer
+	if err := te.encodeTable(&table, node, []string{}); err != nil {
+		return err
+	}
+	// table headers are separated by a blank line, drop the one in front of the first header.
+	buf.WriteString(strings.TrimLeft(table.String(), "\n"))
+	if err := te.writeComment(&buf, node.FootComment); err != nil {
+		return err
+	}
+	return writeString(writer, buf.String())
 }
 
 func (te *tomlEncoder) PrintDocumentSeparator(_ io.Writer) error {
 	return nil
 }
 
-func (te *tomlEncoder) PrintLeadingContent(_ io.Writer, _ string) error {
+func (te *tomlEncoder) PrintLeadingContent(writer io.Writer, content string) error {
+	reader := bufio.NewReader(strings.NewReader(content))
+	for {
+		readline, errReading := reader.ReadString('\n')
+		if errReading != nil && !errors.Is(errReading, io.EOF) {
+			return errReading
+		}
+		trimmed := strings.TrimSpace(readline)
+		// TOML has no document separators, only comments and blank lines are kept.
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			if err := writeString(writer, readline); err != nil {
+				return err
+			}
+			if errors.Is(errReading, io.EOF) && readline != "" {
+				// the last comment we read didn't have a newline, put one in
+				if err := writeString(writer, "\n"); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(errReading, io.EOF) {
+			break
+		}
+	}
 	return nil
 }
 
 func (te *tomlEncoder) CanHandleAliases() bool {
 	return false
 }
+
+func (te *tomlEncoder) isTable(node *CandidateNode) bool {
+	return node.Kind == MappingNode && node.Style != FlowStyle
+}
+
+func (te *tomlEncoder) isArrayOfTables(node *CandidateNode) bool {
+	if node.Kind != SequenceNode || node.Style == FlowStyle || len(node.Content) == 0 {
+		return false
+	}
+	for _, child := range node.Content {
+		if !te.isTable(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// a table header can be skipped when the table only contains other tables,
+// as those headers will define it implicitly.
+func (te *tomlEncoder) needsHeader(key *CandidateNode, table *CandidateNode) bool {
+	if len(table.Content) == 0 || key.HeadComment != "" || key.LineComment != "" ||
+		table.HeadComment != "" || table.LineComment != "" {
+		return true
+	}
+	for i := 1; i < len(table.Content); i = i + 2 {
+		value := table.Content[i]
+		if !te.isTable(value) && !te.isArrayOfTables(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (te *tomlEncoder) encodeTable(writer io.Writer, node *CandidateNode, path []string) error {
+	// key values must come before any sub tables
+	for i := 0; i < len(node.Content); i = i + 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		if te.isTable(value) || te.isArrayOfTables(value) {
+			continue
+		}
+		if err := te.encodeKeyValue(writer, key, value); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < len(node.Content); i = i + 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		childPath := append(append(make([]string, 0, len(path)+1), path...), key.Value)
+
+		if te.isTable(value) {
+			if te.needsHeader(key, value) {
+				if err := te.writeHeader(writer, "["+te.formatPath(childPath)+"]", key, value); err != nil {
+					return err
+				}
+			}
+			if err := te.encodeTable(writer, value, childPath); err != nil {
+				return err
+			}
+			if err := te.writeComment(writer, value.FootComment); err != nil {
+				return err
+			}
+		} else if te.isArrayOfTables(value) {
+			for index, child := range value.Content {
+				headerKey := key
+				if index > 0 {
+					// only the first entry carries the key comments
+					headerKey = &CandidateNode{}
+				}
+				if err := te.writeHeader(writer, "[["+te.formatPath(childPath)+"]]", headerKey, child); err != nil {
+					return err
+				}
+				if err := te.encodeTable(writer, child, childPath); err != nil {
+					return err
+				}
+				if err := te.writeComment(writer, child.FootComment); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (te *tomlEncoder) writeHeader(writer io.Writer, header string, key *CandidateNode, value *CandidateNode) error {
+	if err := writeString(writer, "\n"); err != nil {
+		return err
+	}
+	if err := te.writeComment(writer, key.HeadComment); err != nil {
+		return err
+	}
+	if err := te.writeComment(writer, value.HeadComment); err != nil {
+		return err
+	}
+	if err := writeString(writer, header); err != nil {
+		return err
+	}
+	return te.writeLineComment(writer, key.LineComment+value.LineComment)
+}
+
+func (te *tomlEncoder) encodeKeyValue(writer io.Writer, key *CandidateNode, value *CandidateNode) error {
+	if value.Tag == "!!null" {
+		// TOML has no null, so the key is left out.
+		return nil
+	}
+	if err := te.writeComment(writer, key.HeadComment); err != nil {
+		return err
+	}
+	if err := te.writeComment(writer, value.HeadComment); err != nil {
+		return err
+	}
+	encodedValue, err := te.encodeInline(value)
+	if err != nil {
+		return err
+	}
+	if err := writeString(writer, te.formatKey(key.Value)+" = "+encodedValue); err != nil {
+		return err
+	}
+	if err := te.writeLineComment(writer, value.LineComment+key.LineComment); err != nil {
+		return err
+	}
+	if err := te.writeComment(writer, key.FootComment); err != nil {
+		return err
+	}
+	return te.writeComment(writer, value.FootComment)
+}
+
+func (te *tomlEncoder) writeComment(writer io.Writer, comment string) error {
+	if comment == "" {
+		return nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(comment, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			trimmed = "# " + trimmed
+		}
+		if err := writeString(writer, trimmed+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (te *tomlEncoder) writeLineComment(writer io.Writer, comment string) error {
+	comment = strings.TrimSpace(strings.ReplaceAll(comment, "\n", " "))
+	if comment == "" {
+		return writeString(writer, "\n")
+	}
+	if !strings.HasPrefix(comment, "#") {
+		comment = "# " + comment
+	}
+	return writeString(writer, " "+comment+"\n")
+}
+
+func (te *tomlEncoder) formatKey(key string) string {
+	if tomlBareKeyRegex.MatchString(key) {
+		return key
+	}
+	return te.formatBasicString(key)
+}
+
+func (te *tomlEncoder) formatPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = te.formatKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+func (te *tomlEncoder) encodeInline(node *CandidateNode) (string, error) {
+	switch node.Kind {
+	case ScalarNode:
+		return te.encodeScalar(node)
+	case SequenceNode:
+		return te.encodeInlineArray(node)
+	case MappingNode:
+		return te.encodeInlineTable(node)
+	case AliasNode:
+		return te.encodeInline(node.Alias)
+	default:
+		return "", fmt.Errorf("unsupported node %v", node.Tag)
+	}
+}
+
+func (te *tomlEncoder) encodeInlineArray(node *CandidateNode) (string, error) {
+	values := make([]string, 0, len(node.Content))
+	for _, child := range node.Content {
+		if child.Tag == "!!null" {
+			return "", fmt.Errorf("TOML does not support null values, found one at %v", child.GetNicePath())
+		}
+		encoded, err := te.encodeInline(child)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, encoded)
+	}
+	return "[" + strings.Join(values, ", ") + "]", nil
+}
+
+func (te *tomlEncoder) encodeInlineTable(node *CandidateNode) (string, error) {
+	values := make([]string, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i = i + 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		if value.Tag == "!!null" {
+			continue
+		}
+		encoded, err := te.encodeInline(value)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, te.formatKey(key.Value)+" = "+encoded)
+	}
+	if len(values) == 0 {
+		return "{}", nil
+	}
+	return "{ " + strings.Join(values, ", ") + " }", nil
+}
+
+func (te *tomlEncoder) encodeScalar(node *CandidateNode) (string, error) {
+	switch node.guessTagFromCustomType() {
+	case "!!int":
+		return te.formatInt(node.Value)
+	case "!!float":
+		return te.formatFloat(node.Value)
+	case "!!bool":
+		return strings.ToLower(node.Value), nil
+	case "!!timestamp":
+		return node.Value, nil
+	case "!!null":
+		return "", fmt.Errorf("TOML does not support null values, found one at %v", node.GetNicePath())
+	}
+	return te.formatString(node), nil
+}
+
+func (te *tomlEncoder) formatInt(value string) (string, error) {
+	if tomlIntRegex.MatchString(value) {
+		return value, nil
+	}
+	_, num, err := parseInt64(value)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(num, 10), nil
+}
+
+func (te *tomlEncoder) formatFloat(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case ".inf", "+.inf":
+		return "inf", nil
+	case "-.inf":
+		return "-inf", nil
+	case ".nan":
+		return "nan", nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		return strings.ToLower(value), nil
+	}
+	if tomlFloatRegex.MatchString(value) && strings.ContainsAny(value, ".eE") {
+		return value, nil
+	}
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", err
+	}
+	formatted := strconv.FormatFloat(num, 'g', -1, 64)
+	if !strings.ContainsAny(formatted, ".eEn") {
+		formatted = formatted + ".0"
+	}
+	return formatted, nil
+}
+
+func (te *tomlEncoder) formatString(node *CandidateNode) string {
+	value := node.Value
+	switch node.Style {
+	case SingleQuotedStyle:
+		if !strings.Contains(value, "'") && !te.hasControlCharacters(value) {
+			return "'" + value + "'"
+		}
+	case LiteralStyle, FoldedStyle:
+		if strings.Contains(value, "\n") {
+			return "\"\"\"\n" + te.escapeString(value, true) + "\"\"\""
+		}
+	}
+	return te.formatBasicString(value)
+}
+
+func (te *tomlEncoder) formatBasicString(value string) string {
+	return "\"" + te.escapeString(value, false) + "\""
+}
+
+func (te *tomlEncoder) hasControlCharacters(value string) bool {
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func (te *tomlEncoder) escapeString(value string, multiline bool) string {
+	var sb strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			if multiline {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteString(`\n`)
+			}
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				sb.WriteString(fmt.Sprintf(`\u%04X`, r))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -18,6 +18,9 @@ type xmlEncoder struct {
 }
 
 func NewXMLEncoder(prefs XmlPreferences) Encoder {
+	if prefs.Lossless {
+		return &xmlLosslessEncoder{prefs: prefs}
+	}
 	var indentString = ""
 
 	for index := 0; index < prefs.Indent; index++ {
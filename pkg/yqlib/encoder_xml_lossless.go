@@ -0,0 +1,245 @@
+//go:build !yq_noxml
+
+package yqlib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlLosslessEncoder writes the ordered form read by xmlLosslessDecoder. It writes
+// exactly what is there, all the white space between elements is part of the content.
+type xmlLosslessEncoder struct {
+	prefs          XmlPreferences
+	leadingContent string
+}
+
+var xmlLosslessTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "]]>", "]]&gt;")
+var xmlLosslessAttributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;")
+var xmlLosslessSingleQuotedAttributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "'", "&apos;")
+
+// decodesTo is whether the raw text, as written in an element or in an attribute
+// value with the given quote, still decodes to the value.
+func (e *xmlLosslessEncoder) decodesTo(raw string, value string, quote string) bool {
+	if raw == value {
+		return true
+	}
+	var decoder *xml.Decoder
+	if quote == "" {
+		decoder = xml.NewDecoder(strings.NewReader(raw))
+	} else {
+		decoder = xml.NewDecoder(strings.NewReader("<a v=" + quote + raw + quote + "/>"))
+	}
+	decoder.Strict = e.prefs.StrictMode
+	var decoded strings.Builder
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			return decoded.String() == value
+		} else if err != nil {
+			return false
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			decoded.Write(token)
+		case xml.StartElement:
+			if len(token.Attr) != 1 {
+				return false
+			}
+			decoded.WriteString(token.Attr[0].Value)
+		case xml.EndElement:
+		default:
+			return false
+		}
+	}
+}
+
+// text is the node value escaped for the content of an element, written as it was in
+// the input while the value is unchanged.
+func (e *xmlLosslessEncoder) text(node *CandidateNode) string {
+	if node.rawValue != "" && e.decodesTo(node.rawValue, node.Value, "") {
+		return node.rawValue
+	}
+	return xmlLosslessTextEscaper.Replace(node.Value)
+}
+
+// attributeValue is the node value escaped for an attribute value with the given quote,
+// written as it was in the input while the value is unchanged.
+func (e *xmlLosslessEncoder) attributeValue(node *CandidateNode, quote string) string {
+	if node.rawValue != "" && !strings.Contains(node.rawValue, quote) && e.decodesTo(node.rawValue, node.Value, quote) {
+		return node.rawValue
+	}
+	if quote == "'" {
+		return xmlLosslessSingleQuotedAttributeEscaper.Replace(node.Value)
+	}
+	return xmlLosslessAttributeEscaper.Replace(node.Value)
+}
+
+func (e *xmlLosslessEncoder) CanHandleAliases() bool {
+	return false
+}
+
+func (e *xmlLosslessEncoder) PrintDocumentSeparator(_ io.Writer) error {
+	return nil
+}
+
+func (e *xmlLosslessEncoder) PrintLeadingContent(_ io.Writer, content string) error {
+	e.leadingContent = content
+	return nil
+}
+
+func (e *xmlLosslessEncoder) Encode(writer io.Writer, node *CandidateNode) error {
+	if e.leadingContent != "" {
+		encoder := xml.NewEncoder(writer)
+		if err := (&xmlEncoder{prefs: e.prefs}).encodeComment(encoder, e.leadingContent); err != nil {
+			return err
+		}
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		if err := writeString(writer, "\n"); err != nil {
+			return err
+		}
+	}
+
+	switch node.Kind {
+	case MappingNode:
+		return e.encodeEntries(writer, node)
+	case ScalarNode:
+		return writeString(writer, e.text(node))
+	}
+	return fmt.Errorf("cannot encode %v to XML - only maps can be encoded", node.Tag)
+}
+
+func (e *xmlLosslessEncoder) isAttribute(name string) bool {
+	return (&xmlEncoder{prefs: e.prefs}).isAttribute(name) &&
+		name != e.prefs.CDataName &&
+		name != e.prefs.CommentName
+}
+
+// encodeContent writes the mixed content of an element: text, and maps of the
+// elements, cdata, comments, processing instructions and directives in it.
+func (e *xmlLosslessEncoder) encodeContent(writer io.Writer, node *CandidateNode) error {
+	switch node.Kind {
+	case ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		return writeString(writer, e.text(node))
+	case SequenceNode:
+		for _, child := range node.Content {
+			if err := e.encodeContent(writer, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case MappingNode:
+		return e.encodeEntries(writer, node)
+	case AliasNode:
+		return e.encodeContent(writer, node.Alias)
+	}
+	return fmt.Errorf("unsupported type %v", node.Tag)
+}
+
+func (e *xmlLosslessEncoder) encodeEntries(writer io.Writer, node *CandidateNode) error {
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		value := node.Content[i+1]
+		var err error
+
+		switch {
+		case key == e.prefs.ContentName:
+			err = e.encodeContent(writer, value)
+		case key == e.prefs.CDataName:
+			// "]]>" cannot appear in cdata, so it is split over two sections
+			err = writeString(writer, "<![CDATA["+strings.ReplaceAll(value.Value, "]]>", "]]]]><![CDATA[>")+"]]>")
+		case key == e.prefs.CommentName:
+			err = writeString(writer, "<!--"+value.Value+"-->")
+		case key == e.prefs.DirectiveName:
+			err = writeString(writer, "<!"+value.Value+">")
+		case strings.HasPrefix(key, e.prefs.ProcInstPrefix):
+			target := strings.Replace(key, e.prefs.ProcInstPrefix, "", 1)
+			if value.Value == "" {
+				err = writeString(writer, "<?"+target+"?>")
+			} else {
+				err = writeString(writer, "<?"+target+" "+value.Value+"?>")
+			}
+		case e.isAttribute(key):
+			err = fmt.Errorf("cannot encode attribute %v outside of an element", key)
+		default:
+			err = e.encodeElement(writer, node.Content[i], value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *xmlLosslessEncoder) encodeElement(writer io.Writer, nameNode *CandidateNode, body *CandidateNode) error {
+	name := nameNode.Value
+	switch body.Kind {
+	case ScalarNode:
+		if body.Tag == "!!null" {
+			return writeString(writer, "<"+name+nameNode.tagSpace+"/>")
+		}
+		return writeString(writer, "<"+name+">"+e.text(body)+"</"+name+">")
+	case SequenceNode:
+		// like the default mode, a list is the same element repeated
+		for _, child := range body.Content {
+			if err := e.encodeElement(writer, nameNode, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case AliasNode:
+		return e.encodeElement(writer, nameNode, body.Alias)
+	case MappingNode:
+		return e.encodeElementMap(writer, nameNode, body)
+	}
+	return fmt.Errorf("unsupported type %v", body.Tag)
+}
+
+func (e *xmlLosslessEncoder) encodeElementMap(writer io.Writer, nameNode *CandidateNode, body *CandidateNode) error {
+	name := nameNode.Value
+	var start strings.Builder
+	start.WriteString("<" + name)
+	children := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+
+	for i := 0; i < len(body.Content); i += 2 {
+		key := body.Content[i]
+		value := body.Content[i+1]
+		if !e.isAttribute(key.Value) {
+			children.Content = append(children.Content, key, value)
+			continue
+		}
+		if value.Kind != ScalarNode {
+			return fmt.Errorf("cannot use %v as attribute, only scalars are supported", value.Tag)
+		}
+		space := key.tagSpace
+		if space == "" {
+			space = " "
+		}
+		attributeName := strings.Replace(key.Value, e.prefs.AttributePrefix, "", 1)
+		quote := "\""
+		if value.Style&SingleQuotedStyle != 0 {
+			quote = "'"
+		}
+		start.WriteString(space + attributeName + "=" + quote + e.attributeValue(value, quote) + quote)
+	}
+	start.WriteString(nameNode.tagSpace)
+
+	if len(children.Content) == 0 {
+		return writeString(writer, start.String()+"/>")
+	}
+	if err := writeString(writer, start.String()+">"); err != nil {
+		return err
+	}
+	if err := e.encodeEntries(writer, children); err != nil {
+		return err
+	}
+	return writeString(writer, "</"+name+">")
+}
@@ -50,6 +50,21 @@ func TestParserNoMatchingCloseBracketInCollectObject(t *testing.T) {
 	test.AssertResultComplex(t, "bad expression, could not find matching `)`", err.Error())
 }
 
+func TestParserNoMatchingEndForIf(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`if . then 1`)
+	test.AssertResultComplex(t, "bad expression, could not find matching 'end' for 'if'", err.Error())
+}
+
+func TestParserElseWithoutIf(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`. else 2 end`)
+	test.AssertResultComplex(t, "bad expression, 'else' without matching 'if ... then'", err.Error())
+}
+
+func TestParserIfWithoutThen(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`if . else 2 end`)
+	test.AssertResultComplex(t, "bad expression, 'else' without matching 'if ... then'", err.Error())
+}
+
 func TestParserNoArgsForTwoArgOp(t *testing.T) {
 	_, err := getExpressionParser().ParseExpression("=")
 	test.AssertResultComplex(t, "'=' expects 2 args but there is 0", err.Error())
@@ -71,8 +86,8 @@ func TestParserTwoArgsForTwoArgOp(t *testing.T) {
 }
 
 func TestParserNoArgsForOneArgOp(t *testing.T) {
-	_, err := getExpressionParser().ParseExpression("explode")
-	test.AssertResultComplex(t, "'explode' expects 1 arg but received none", err.Error())
+	_, err := getExpressionParser().ParseExpression("sortKeys")
+	test.AssertResultComplex(t, "'sortKeys' expects 1 arg but received none", err.Error())
 }
 
 func TestParserOneArgForOneArgOp(t *testing.T) {
@@ -84,3 +99,23 @@ func TestParserExtraArgs(t *testing.T) {
 	_, err := getExpressionParser().ParseExpression("sortKeys(.) explode(.)")
 	test.AssertResultComplex(t, "bad expression, please check expression syntax", err.Error())
 }
+
+func TestParserDefWithoutSemicolon(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`def f: .a`)
+	test.AssertResultComplex(t, "bad expression, the body of a def must end with ';'", err.Error())
+}
+
+func TestParserDefWithoutColon(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`def f .a; f`)
+	test.AssertResultComplex(t, "bad expression, def must be followed by ':' and a body, e.g. def f: .a;", err.Error())
+}
+
+func TestParserDefWithBadParams(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`def f(1): .a; f`)
+	test.AssertResultComplex(t, "bad expression, def parameters must be names like 'f' or '$a', separated by ';'", err.Error())
+}
+
+func TestParserBadMergeKey(t *testing.T) {
+	_, err := getExpressionParser().ParseExpression(`.a *k(.name |) .b`)
+	test.AssertResultComplex(t, "bad merge key expression .name |: '|' expects 2 args but there is 1", err.Error())
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package yqlib
+
+import "os"
+
+// file locking is not supported here, in place edits are still atomic but may race.
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
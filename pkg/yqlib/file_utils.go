@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 func tryRenameFile(from string, to string) error {
@@ -83,3 +84,14 @@ func createTempFile() (*os.File, error) {
 
 	return file, err
 }
+
+// createTempFileNextTo creates a temp file in the same directory as the given file, falling back
+// to the system temp directory when that directory cannot be written to.
+func createTempFileNextTo(filename string) (*os.File, error) {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		log.Debugf("could not create temp file next to %v, using the temp directory instead: %v", filename, err)
+		return createTempFile()
+	}
+	return file, nil
+}
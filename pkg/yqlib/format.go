@@ -26,6 +26,26 @@ var JSONFormat = &Format{"json", []string{"j"},
 	func() Decoder { return NewJSONDecoder() },
 }
 
+var JSON5Format = &Format{"json5", []string{},
+	func() Encoder { return NewJSON5Encoder(ConfiguredJSONPreferences) },
+	func() Decoder { return NewJSON5Decoder(ConfiguredJSONPreferences) },
+}
+
+var JSONCFormat = &Format{"jsonc", []string{},
+	func() Encoder { return NewJSONCEncoder(ConfiguredJSONPreferences) },
+	func() Decoder { return NewJSON5Decoder(ConfiguredJSONPreferences) },
+}
+
+var NDJSONFormat = &Format{"ndjson", []string{"jsonl"},
+	func() Encoder {
+		// one document per line
+		prefs := ConfiguredJSONPreferences.Copy()
+		prefs.Indent = 0
+		return NewJSONEncoder(prefs)
+	},
+	func() Decoder { return NewNDJSONDecoder(ConfiguredJSONPreferences) },
+}
+
 var PropertiesFormat = &Format{"props", []string{"p", "properties"},
 	func() Encoder { return NewPropertiesEncoder(ConfiguredPropertiesPreferences) },
 	func() Decoder { return NewPropertiesDecoder() },
@@ -79,6 +99,9 @@ var LuaFormat = &Format{"lua", []string{"l"},
 var Formats = []*Format{
 	YamlFormat,
 	JSONFormat,
+	JSON5Format,
+	JSONCFormat,
+	NDJSONFormat,
 	PropertiesFormat,
 	CSVFormat,
 	TSVFormat,
@@ -0,0 +1,148 @@
+// Package yamlparser is the event parser of gopkg.in/yaml.v3, which yaml.v3 does not
+// export. It reads YAML one event at a time, so documents can be processed without
+// holding them in memory. Other than the package name and gofmt, the files ported from
+// libyaml are as they are in yaml.v3 v3.0.1.
+package yamlparser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+type EventKind int
+
+const (
+	DocumentStartEvent EventKind = iota
+	DocumentEndEvent
+	ScalarEvent
+	AliasEvent
+	SequenceStartEvent
+	SequenceEndEvent
+	MappingStartEvent
+	MappingEndEvent
+)
+
+// Event is a parsing event. Scalars, aliases and the start of sequences and maps have
+// a node with their tag, style, anchor and position, but never any content. Aliases
+// have the name of their anchor as the value.
+type Event struct {
+	Kind EventKind
+	Node *yaml.Node
+}
+
+type Parser struct {
+	parser yaml_parser_t
+	event  yaml_event_t
+}
+
+func NewParser(reader io.Reader) *Parser {
+	p := &Parser{}
+	yaml_parser_initialize(&p.parser)
+	yaml_parser_set_input_reader(&p.parser, reader)
+	return p
+}
+
+func (p *Parser) fail() error {
+	var line int
+	if p.parser.context_mark.line != 0 {
+		line = p.parser.context_mark.line
+	} else if p.parser.problem_mark.line != 0 {
+		line = p.parser.problem_mark.line
+	}
+	// scanner errors do not move on to the line of the problem
+	if line != 0 && p.parser.error == yaml_SCANNER_ERROR {
+		line++
+	}
+	where := ""
+	if line != 0 {
+		where = "line " + strconv.Itoa(line) + ": "
+	}
+	msg := p.parser.problem
+	if msg == "" {
+		msg = "unknown problem parsing YAML content"
+	}
+	return fmt.Errorf("yaml: %v%v", where, msg)
+}
+
+func (p *Parser) node(kind yaml.Kind, tag string) *yaml.Node {
+	node := &yaml.Node{
+		Kind:   kind,
+		Anchor: string(p.event.anchor),
+		Line:   p.event.start_mark.line + 1,
+		Column: p.event.start_mark.column + 1,
+	}
+	if tag != "" && tag != "!" {
+		node.Tag = tag
+		node.Style = yaml.TaggedStyle
+	}
+	return node
+}
+
+func (p *Parser) scalar() *yaml.Node {
+	node := p.node(yaml.ScalarNode, string(p.event.tag))
+	node.Value = string(p.event.value)
+	style := p.event.scalar_style()
+	switch {
+	case style&yaml_DOUBLE_QUOTED_SCALAR_STYLE != 0:
+		node.Style |= yaml.DoubleQuotedStyle
+	case style&yaml_SINGLE_QUOTED_SCALAR_STYLE != 0:
+		node.Style |= yaml.SingleQuotedStyle
+	case style&yaml_LITERAL_SCALAR_STYLE != 0:
+		node.Style |= yaml.LiteralStyle
+	case style&yaml_FOLDED_SCALAR_STYLE != 0:
+		node.Style |= yaml.FoldedStyle
+	}
+	// resolves the tag of plain scalars, and shortens explicit tags
+	node.Tag = node.ShortTag()
+	return node
+}
+
+// Next returns the next event, or io.EOF at the end of the input.
+func (p *Parser) Next() (Event, error) {
+	for {
+		if !yaml_parser_parse(&p.parser, &p.event) || p.parser.error != yaml_NO_ERROR {
+			return Event{}, p.fail()
+		}
+		event := Event{}
+		switch p.event.typ {
+		case yaml_STREAM_END_EVENT:
+			yaml_event_delete(&p.event)
+			return Event{}, io.EOF
+		case yaml_DOCUMENT_START_EVENT:
+			event.Kind = DocumentStartEvent
+		case yaml_DOCUMENT_END_EVENT:
+			event.Kind = DocumentEndEvent
+		case yaml_SCALAR_EVENT:
+			event = Event{Kind: ScalarEvent, Node: p.scalar()}
+		case yaml_ALIAS_EVENT:
+			event = Event{Kind: AliasEvent, Node: p.node(yaml.AliasNode, "")}
+			event.Node.Value = event.Node.Anchor
+			event.Node.Anchor = ""
+		case yaml_SEQUENCE_START_EVENT:
+			event = Event{Kind: SequenceStartEvent, Node: p.node(yaml.SequenceNode, string(p.event.tag))}
+			if p.event.sequence_style()&yaml_FLOW_SEQUENCE_STYLE != 0 {
+				event.Node.Style |= yaml.FlowStyle
+			}
+			event.Node.Tag = event.Node.ShortTag()
+		case yaml_SEQUENCE_END_EVENT:
+			event.Kind = SequenceEndEvent
+		case yaml_MAPPING_START_EVENT:
+			event = Event{Kind: MappingStartEvent, Node: p.node(yaml.MappingNode, string(p.event.tag))}
+			if p.event.mapping_style()&yaml_FLOW_MAPPING_STYLE != 0 {
+				event.Node.Style |= yaml.FlowStyle
+			}
+			event.Node.Tag = event.Node.ShortTag()
+		case yaml_MAPPING_END_EVENT:
+			event.Kind = MappingEndEvent
+		default:
+			// stream start and comments
+			yaml_event_delete(&p.event)
+			continue
+		}
+		yaml_event_delete(&p.event)
+		return event, nil
+	}
+}
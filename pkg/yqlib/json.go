@@ -1,9 +1,35 @@
 package yqlib
 
+import "fmt"
+
+type JsonDuplicateKeys int
+
+const (
+	// JsonDuplicateKeysLast keeps the value of the last duplicate key, where the first key was
+	JsonDuplicateKeysLast JsonDuplicateKeys = iota
+	JsonDuplicateKeysFirst
+	JsonDuplicateKeysError
+)
+
+func JsonDuplicateKeysFromString(policy string) (JsonDuplicateKeys, error) {
+	switch policy {
+	case "last":
+		return JsonDuplicateKeysLast, nil
+	case "first":
+		return JsonDuplicateKeysFirst, nil
+	case "error":
+		return JsonDuplicateKeysError, nil
+	}
+	return JsonDuplicateKeysLast, fmt.Errorf("unknown json duplicate keys policy '%v' please use [error, first, last]", policy)
+}
+
 type JsonPreferences struct {
 	Indent        int
 	ColorsEnabled bool
 	UnwrapScalar  bool
+	DuplicateKeys JsonDuplicateKeys
+	// SkipInvalid logs and skips lines of ndjson input that are not valid
+	SkipInvalid bool
 }
 
 func NewDefaultJsonPreferences() JsonPreferences {
@@ -11,6 +37,8 @@ func NewDefaultJsonPreferences() JsonPreferences {
 		Indent:        2,
 		ColorsEnabled: true,
 		UnwrapScalar:  true,
+		DuplicateKeys: JsonDuplicateKeysLast,
+		SkipInvalid:   false,
 	}
 }
 
@@ -19,6 +47,8 @@ func (p *JsonPreferences) Copy() JsonPreferences {
 		Indent:        p.Indent,
 		ColorsEnabled: p.ColorsEnabled,
 		UnwrapScalar:  p.UnwrapScalar,
+		DuplicateKeys: p.DuplicateKeys,
+		SkipInvalid:   p.SkipInvalid,
 	}
 }
 
@@ -0,0 +1,286 @@
+//go:build !yq_nojson
+
+package yqlib
+
+import (
+	"bufio"
+	"fmt"
+	"testing"
+
+	"github.com/mikefarah/yq/v4/test"
+)
+
+const sampleJSON5 = `// settings for the app
+{
+  // the name
+  name: 'cat', // a line comment
+  count: 0x1F,
+  ratio: .5,
+  big: Infinity,
+  tags: [
+    "a", // first
+    "b",
+  ],
+  /* the end */
+}
+`
+
+const expectedJSON5Yaml = `# settings for the app
+# the name
+name: cat # a line comment
+count: 0x1F
+ratio: .5
+big: .inf
+tags:
+  - a # first
+  - b
+
+# the end
+`
+
+const expectedJSON5Roundtrip = `// settings for the app
+{
+  // the name
+  "name": "cat", // a line comment
+  "count": 0x1F,
+  "ratio": .5,
+  "big": Infinity,
+  "tags": [
+    "a", // first
+    "b"
+  ]
+  // the end
+}
+`
+
+const sampleJSONC = `{
+  /*
+   * compiler options
+   */
+  "compilerOptions": {
+    "strict": true, // always
+    "target": "es2022"
+  },
+}
+`
+
+var json5Scenarios = []formatScenario{
+	{
+		description:    "Parse JSON5",
+		subdescription: "Comments are kept, and JSON5 values like hex numbers and Infinity are read as their yaml equivalents.",
+		input:          sampleJSON5,
+		expected:       expectedJSON5Yaml,
+	},
+	{
+		description: "Parse JSONC",
+		input:       sampleJSONC,
+		expected:    "# compiler options\ncompilerOptions:\n  strict: true # always\n  target: es2022\n",
+	},
+	{
+		description:    "Roundtrip JSON5",
+		subdescription: "Keys are quoted and trailing commas are dropped, comments and numbers are kept as written.",
+		input:          sampleJSON5,
+		expected:       expectedJSON5Roundtrip,
+		scenarioType:   "roundtrip",
+	},
+	{
+		description:  "Update JSONC",
+		input:        sampleJSONC,
+		expression:   `.compilerOptions.target = "es2024"`,
+		expected:     "{\n  // compiler options\n  \"compilerOptions\": {\n    \"strict\": true, // always\n    \"target\": \"es2024\"\n  }\n}\n",
+		scenarioType: "roundtrip",
+	},
+	{
+		description:    "Roundtrip JSONC",
+		subdescription: "JSONC only has JSON numbers, so hex numbers are written in decimal and numbers like `.5` are written in full.",
+		input:          "{\n  \"count\": 0x1F, // hex\n  \"ratio\": .5,\n  \"sizes\": [1, /* then */ 1e3, -0xff],\n}\n",
+		expected:       "{\n  \"count\": 31, // hex\n  \"ratio\": 0.5,\n  \"sizes\": [\n    1,\n    /* then */ 1e3,\n    -255\n  ]\n}\n",
+		scenarioType:   "roundtrip-jsonc",
+	},
+	{
+		description:  "Block comments before a value stay before it",
+		skipDoc:      true,
+		input:        "{\"a\": [1, /* two */ 2, 3 /* three */, 4], /* b */ \"b\": 5, /* c */\n\"c\": 6}",
+		expected:     "{\n  \"a\": [\n    1,\n    /* two */ 2,\n    3, // three\n    4\n  ],\n  /* b */ \"b\": 5, // c\n  \"c\": 6\n}\n",
+		scenarioType: "roundtrip",
+	},
+	{
+		description: "Block comments before a value are head comments",
+		skipDoc:     true,
+		input:       "[1, /* two */ 2]",
+		expected:    "- 1\n# two\n- 2\n",
+	},
+	{
+		description:  "Roundtrip compact",
+		skipDoc:      true,
+		input:        sampleJSON5,
+		indent:       0,
+		expected:     "/* settings for the app */{/* the name */\"name\":\"cat\",/* a line comment */\"count\":0x1F,\"ratio\":.5,\"big\":Infinity,\"tags\":[\"a\",/* first */\"b\"]/* the end */}\n",
+		scenarioType: "roundtrip-compact",
+	},
+	{
+		description:  "Encode yaml with comments",
+		input:        "# things\na: 1 # one\nb:\n  - x\n  # after x\nc: .nan\n",
+		expected:     "// things\n{\n  \"a\": 1, // one\n  \"b\": [\n    \"x\"\n    // after x\n  ],\n  \"c\": NaN\n}\n",
+		scenarioType: "encode",
+	},
+	{
+		description:  "Encode empty collections",
+		skipDoc:      true,
+		input:        "a: {}\nb: []\n",
+		expected:     "{\n  \"a\": {},\n  \"b\": []\n}\n",
+		scenarioType: "encode",
+	},
+	{
+		description: "Single quotes and escapes",
+		skipDoc:     true,
+		input:       `['it\'s', "tab\tand\x41B", 'line \` + "\n" + `continued']`,
+		expected:    "- it's\n- \"tab\\tandAB\"\n- line continued\n",
+	},
+	{
+		description: "Signed numbers",
+		skipDoc:     true,
+		input:       `[+1, -.5, 5., -Infinity, NaN, -0xff]`,
+		expected:    "- +1\n- -.5\n- 5.\n- -.inf\n- .nan\n- -0xff\n",
+	},
+	{
+		description: "Identifier keys",
+		skipDoc:     true,
+		input:       `{$a: 1, _b2: 2}`,
+		expected:    "$a: 1\n_b2: 2\n",
+	},
+	{
+		description: "Comments in empty collections",
+		skipDoc:     true,
+		input:       "{a: [\n  // nothing\n]}",
+		expected:    "a: []\n# nothing\n",
+	},
+	{
+		description:   "Unterminated comment",
+		skipDoc:       true,
+		input:         `{"a": 1 /* x`,
+		expectedError: "bad file 'sample.yml': json: line 1, column 9: unterminated comment",
+		scenarioType:  "decode-error",
+	},
+	{
+		description:   "Single slash",
+		skipDoc:       true,
+		input:         `{"a": 1 / 2}`,
+		expectedError: "bad file 'sample.yml': json: line 1, column 9: unexpected character '/'",
+		scenarioType:  "decode-error",
+	},
+	{
+		description:   "Bad escape",
+		skipDoc:       true,
+		input:         `["\1"]`,
+		expectedError: "bad file 'sample.yml': json: line 1, column 4: invalid escape character '1' in string",
+		scenarioType:  "decode-error",
+	},
+	{
+		description:   "Strict json has no comments",
+		skipDoc:       true,
+		input:         sampleJSONC,
+		expectedError: "bad file 'sample.yml': json: line 2, column 3: expected a string key but found '/'",
+		scenarioType:  "decode-json-error",
+	},
+	{
+		description:   "Strict json has no trailing commas",
+		skipDoc:       true,
+		input:         `[1, 2,]`,
+		expectedError: "bad file 'sample.yml': json: line 1, column 7: unexpected trailing comma before ']'",
+		scenarioType:  "decode-json-error",
+	},
+}
+
+func testJSON5Scenario(t *testing.T, s formatScenario) {
+	switch s.scenarioType {
+	case "", "decode":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "roundtrip":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewJSON5Encoder(ConfiguredJSONPreferences)), s.description)
+	case "roundtrip-jsonc":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewJSONCEncoder(ConfiguredJSONPreferences)), s.description)
+	case "roundtrip-compact":
+		prefs := ConfiguredJSONPreferences.Copy()
+		prefs.Indent = 0
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSON5Decoder(prefs), NewJSON5Encoder(prefs)), s.description)
+	case "encode":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewJSON5Encoder(ConfiguredJSONPreferences)), s.description)
+	case "decode-error", "decode-json-error":
+		decoder := NewJSON5Decoder(ConfiguredJSONPreferences)
+		if s.scenarioType == "decode-json-error" {
+			decoder = NewJSONDecoder()
+		}
+		result, err := processFormatScenario(s, decoder, NewYamlEncoder(ConfiguredYamlPreferences))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
+	default:
+		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
+	}
+}
+
+func documentJSON5Scenario(_ *testing.T, w *bufio.Writer, i interface{}) {
+	s := i.(formatScenario)
+
+	if s.skipDoc {
+		return
+	}
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	expression := s.expression
+	if expression == "" {
+		expression = "."
+	}
+
+	switch s.scenarioType {
+	case "", "decode":
+		writeOrPanic(w, "Given a sample.json5 file of:\n")
+		writeOrPanic(w, fmt.Sprintf("```json5\n%v\n```\n", s.input))
+		writeOrPanic(w, "then\n")
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq -oy '%v' sample.json5\n```\n", expression))
+		writeOrPanic(w, "will output\n")
+		writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewYamlEncoder(ConfiguredYamlPreferences))))
+	case "roundtrip":
+		writeOrPanic(w, "Given a sample.json5 file of:\n")
+		writeOrPanic(w, fmt.Sprintf("```json5\n%v\n```\n", s.input))
+		writeOrPanic(w, "then\n")
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq '%v' sample.json5\n```\n", expression))
+		writeOrPanic(w, "will output\n")
+		writeOrPanic(w, fmt.Sprintf("```json5\n%v```\n\n", mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewJSON5Encoder(ConfiguredJSONPreferences))))
+	case "roundtrip-jsonc":
+		writeOrPanic(w, "Given a sample.jsonc file of:\n")
+		writeOrPanic(w, fmt.Sprintf("```jsonc\n%v\n```\n", s.input))
+		writeOrPanic(w, "then\n")
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq '%v' sample.jsonc\n```\n", expression))
+		writeOrPanic(w, "will output\n")
+		writeOrPanic(w, fmt.Sprintf("```jsonc\n%v```\n\n", mustProcessFormatScenario(s, NewJSON5Decoder(ConfiguredJSONPreferences), NewJSONCEncoder(ConfiguredJSONPreferences))))
+	case "encode":
+		writeOrPanic(w, "Given a sample.yml file of:\n")
+		writeOrPanic(w, fmt.Sprintf("```yaml\n%v\n```\n", s.input))
+		writeOrPanic(w, "then\n")
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq -o json5 '%v' sample.yml\n```\n", expression))
+		writeOrPanic(w, "will output\n")
+		writeOrPanic(w, fmt.Sprintf("```json5\n%v```\n\n", mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewJSON5Encoder(ConfiguredJSONPreferences))))
+	default:
+		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
+	}
+}
+
+func TestJSON5Scenarios(t *testing.T) {
+	for _, tt := range json5Scenarios {
+		testJSON5Scenario(t, tt)
+	}
+	genericScenarios := make([]interface{}, len(json5Scenarios))
+	for i, s := range json5Scenarios {
+		genericScenarios[i] = s
+	}
+	documentScenarios(t, "usage", "json5", genericScenarios, documentJSON5Scenario)
+}
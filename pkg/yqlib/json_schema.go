@@ -0,0 +1,680 @@
+package yqlib
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaValidationError describes where a document does not match a JSON Schema.
+type SchemaValidationError struct {
+	Filename string
+	// Path is the path of the offending node in yq syntax, e.g. .spec.containers[0].image
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e SchemaValidationError) Error() string {
+	location := ""
+	if e.Filename != "" {
+		location = e.Filename + ":"
+	}
+	if e.Line > 0 {
+		location = location + fmt.Sprintf("%v:%v:", e.Line, e.Column)
+	}
+	if location != "" {
+		location = location + " "
+	}
+	return fmt.Sprintf("%v%v: %v", location, e.Path, e.Message)
+}
+
+// SchemaValidator checks documents against a JSON Schema.
+// Supports the validation keywords of draft 7 through 2020-12, with $ref
+// limited to references within the schema. Formats are not checked.
+type SchemaValidator interface {
+	Validate(node *CandidateNode) ([]SchemaValidationError, error)
+	// ValidateFile validates every document in the file, use "-" for STDIN.
+	ValidateFile(filename string, decoder Decoder) ([]SchemaValidationError, error)
+}
+
+type jsonSchemaValidator struct {
+	root *CandidateNode
+	// compiled patterns, keyed by the pattern
+	patterns map[string]*regexp.Regexp
+}
+
+// the maximum number of $refs to follow without moving further into the document
+const maxSchemaRefDepth = 64
+
+func NewSchemaValidator(schema *CandidateNode) SchemaValidator {
+	return &jsonSchemaValidator{root: schema, patterns: make(map[string]*regexp.Regexp)}
+}
+
+// LoadSchemaFile reads a JSON Schema from a JSON or YAML file.
+func LoadSchemaFile(filename string) (*CandidateNode, error) {
+	return loadWithDecoder(filename, NewYamlDecoder(LoadYamlPreferences))
+}
+
+func (v *jsonSchemaValidator) Validate(node *CandidateNode) ([]SchemaValidationError, error) {
+	return v.validate(node, v.root, 0)
+}
+
+func (v *jsonSchemaValidator) ValidateFile(filename string, decoder Decoder) ([]SchemaValidationError, error) {
+	reader, err := readStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer SafelyCloseReader(reader)
+
+	documents, err := readDocuments(reader, filename, 0, decoder)
+	if err != nil {
+		return nil, err
+	}
+	errors := make([]SchemaValidationError, 0)
+	for el := documents.Front(); el != nil; el = el.Next() {
+		documentErrors, err := v.Validate(el.Value.(*CandidateNode))
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, documentErrors...)
+	}
+	return errors, nil
+}
+
+func newSchemaError(node *CandidateNode, format string, args ...interface{}) SchemaValidationError {
+	return SchemaValidationError{
+		Filename: node.GetFilename(),
+		Path:     expressionPath(node.GetPath()),
+		Line:     node.Line,
+		Column:   node.Column,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func schemaKeyword(schema *CandidateNode, keyword string) *CandidateNode {
+	for index := 0; index+1 < len(schema.Content); index = index + 2 {
+		if schema.Content[index].Value == keyword {
+			return schema.Content[index+1]
+		}
+	}
+	return nil
+}
+
+func schemaNumber(schemaNode *CandidateNode) (float64, bool) {
+	if schemaNode == nil || schemaNode.Kind != ScalarNode {
+		return 0, false
+	}
+	tag := schemaNode.guessTagFromCustomType()
+	if tag != "!!int" && tag != "!!float" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(schemaNode.Value, "_", ""), 64)
+	if err != nil {
+		_, intValue, intErr := parseInt64(schemaNode.Value)
+		if intErr != nil {
+			return 0, false
+		}
+		return float64(intValue), true
+	}
+	return number, true
+}
+
+// schemaType returns the JSON Schema type of the node
+func schemaType(node *CandidateNode) string {
+	if node.Kind == AliasNode {
+		return schemaType(node.Alias)
+	}
+	switch node.Kind {
+	case MappingNode:
+		return "object"
+	case SequenceNode:
+		return "array"
+	}
+	switch node.guessTagFromCustomType() {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		if number, ok := schemaNumber(node); ok && number == math.Trunc(number) && !math.IsInf(number, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "string"
+}
+
+func schemaTypeMatches(node *CandidateNode, expectedType string) bool {
+	actualType := schemaType(node)
+	return actualType == expectedType || (expectedType == "number" && actualType == "integer")
+}
+
+func schemaValueString(node *CandidateNode) string {
+	switch node.Kind {
+	case ScalarNode:
+		if node.Tag == "!!str" {
+			return strconv.Quote(node.Value)
+		}
+		return node.Value
+	case SequenceNode:
+		values := make([]string, len(node.Content))
+		for i, child := range node.Content {
+			values[i] = schemaValueString(child)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case MappingNode:
+		values := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i = i + 2 {
+			values = append(values, fmt.Sprintf("%v: %v", schemaValueString(node.Content[i]), schemaValueString(node.Content[i+1])))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	case AliasNode:
+		return schemaValueString(node.Alias)
+	}
+	return node.Value
+}
+
+func schemaNodeEqual(lhs *CandidateNode, rhs *CandidateNode) bool {
+	// 1 and 1.0 are the same number in JSON Schema
+	lhsNumber, lhsIsNumber := schemaNumber(lhs)
+	rhsNumber, rhsIsNumber := schemaNumber(rhs)
+	if lhsIsNumber && rhsIsNumber {
+		return lhsNumber == rhsNumber
+	}
+	return recursiveNodeEqual(lhs, rhs)
+}
+
+func (v *jsonSchemaValidator) resolveRef(ref string) (*CandidateNode, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %v, only references within the schema are supported", ref)
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("bad $ref %v: %w", ref, err)
+	}
+	current := v.root
+	if pointer == "" {
+		return current, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("unsupported $ref %v, only JSON pointers are supported", ref)
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		var next *CandidateNode
+		switch current.Kind {
+		case MappingNode:
+			next = schemaKeyword(current, token)
+		case SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err == nil && index >= 0 && index < len(current.Content) {
+				next = current.Content[index]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("could not resolve $ref %v", ref)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func (v *jsonSchemaValidator) pattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := v.patterns[pattern]; ok {
+		return compiled, nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("bad pattern %v in schema: %w", pattern, err)
+	}
+	v.patterns[pattern] = compiled
+	return compiled, nil
+}
+
+// matches returns true when the node matches the schema, ignoring the details of any errors
+func (v *jsonSchemaValidator) matches(node *CandidateNode, schema *CandidateNode, refDepth int) (bool, error) {
+	errors, err := v.validate(node, schema, refDepth)
+	return len(errors) == 0, err
+}
+
+func (v *jsonSchemaValidator) validate(node *CandidateNode, schema *CandidateNode, refDepth int) ([]SchemaValidationError, error) {
+	if node.Kind == AliasNode {
+		node = node.Alias
+	}
+	if schema.Kind == AliasNode {
+		schema = schema.Alias
+	}
+
+	// boolean schemas
+	if schema.Kind == ScalarNode && schema.Tag == "!!bool" {
+		if isTruthyNode(schema) {
+			return nil, nil
+		}
+		return []SchemaValidationError{newSchemaError(node, "no value is allowed here")}, nil
+	}
+	if schema.Kind != MappingNode {
+		return nil, fmt.Errorf("a schema must be a map or a boolean, but got %v", schema.Tag)
+	}
+
+	errors := make([]SchemaValidationError, 0)
+
+	if ref := schemaKeyword(schema, "$ref"); ref != nil {
+		if refDepth > maxSchemaRefDepth {
+			return nil, fmt.Errorf("too many nested $refs resolving %v", ref.Value)
+		}
+		refSchema, err := v.resolveRef(ref.Value)
+		if err != nil {
+			return nil, err
+		}
+		refErrors, err := v.validate(node, refSchema, refDepth+1)
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, refErrors...)
+	}
+
+	checks := []func(*CandidateNode, *CandidateNode, int) ([]SchemaValidationError, error){
+		v.validateType,
+		v.validateEnum,
+		v.validateCombinations,
+		v.validateNumber,
+		v.validateString,
+		v.validateArray,
+		v.validateObject,
+	}
+	for _, check := range checks {
+		checkErrors, err := check(node, schema, refDepth)
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, checkErrors...)
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateType(node *CandidateNode, schema *CandidateNode, _ int) ([]SchemaValidationError, error) {
+	typeNode := schemaKeyword(schema, "type")
+	if typeNode == nil {
+		return nil, nil
+	}
+	expectedTypes := []string{typeNode.Value}
+	if typeNode.Kind == SequenceNode {
+		expectedTypes = make([]string, len(typeNode.Content))
+		for i, child := range typeNode.Content {
+			expectedTypes[i] = child.Value
+		}
+	}
+	for _, expectedType := range expectedTypes {
+		if schemaTypeMatches(node, expectedType) {
+			return nil, nil
+		}
+	}
+	return []SchemaValidationError{newSchemaError(node, "expected %v, but got %v", strings.Join(expectedTypes, " or "), schemaType(node))}, nil
+}
+
+func (v *jsonSchemaValidator) validateEnum(node *CandidateNode, schema *CandidateNode, _ int) ([]SchemaValidationError, error) {
+	errors := make([]SchemaValidationError, 0)
+	if constNode := schemaKeyword(schema, "const"); constNode != nil && !schemaNodeEqual(node, constNode) {
+		errors = append(errors, newSchemaError(node, "must be %v", schemaValueString(constNode)))
+	}
+	if enumNode := schemaKeyword(schema, "enum"); enumNode != nil && enumNode.Kind == SequenceNode {
+		for _, allowed := range enumNode.Content {
+			if schemaNodeEqual(node, allowed) {
+				return errors, nil
+			}
+		}
+		errors = append(errors, newSchemaError(node, "must be one of %v, but got %v", schemaValueString(enumNode), schemaValueString(node)))
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateCombinations(node *CandidateNode, schema *CandidateNode, refDepth int) ([]SchemaValidationError, error) {
+	errors := make([]SchemaValidationError, 0)
+
+	if allOf := schemaKeyword(schema, "allOf"); allOf != nil {
+		for _, subSchema := range allOf.Content {
+			subErrors, err := v.validate(node, subSchema, refDepth)
+			if err != nil {
+				return nil, err
+			}
+			errors = append(errors, subErrors...)
+		}
+	}
+
+	if anyOf := schemaKeyword(schema, "anyOf"); anyOf != nil {
+		matched := false
+		for _, subSchema := range anyOf.Content {
+			ok, err := v.matches(node, subSchema, refDepth)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errors = append(errors, newSchemaError(node, "must match at least one of the schemas in anyOf"))
+		}
+	}
+
+	if oneOf := schemaKeyword(schema, "oneOf"); oneOf != nil {
+		matches := 0
+		for _, subSchema := range oneOf.Content {
+			ok, err := v.matches(node, subSchema, refDepth)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matches++
+			}
+		}
+		if matches != 1 {
+			errors = append(errors, newSchemaError(node, "must match exactly one of the schemas in oneOf, but matched %v", matches))
+		}
+	}
+
+	if not := schemaKeyword(schema, "not"); not != nil {
+		ok, err := v.matches(node, not, refDepth)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			errors = append(errors, newSchemaError(node, "must not match the schema in not"))
+		}
+	}
+
+	if ifSchema := schemaKeyword(schema, "if"); ifSchema != nil {
+		ok, err := v.matches(node, ifSchema, refDepth)
+		if err != nil {
+			return nil, err
+		}
+		branch := schemaKeyword(schema, "else")
+		if ok {
+			branch = schemaKeyword(schema, "then")
+		}
+		if branch != nil {
+			branchErrors, err := v.validate(node, branch, refDepth)
+			if err != nil {
+				return nil, err
+			}
+			errors = append(errors, branchErrors...)
+		}
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateNumber(node *CandidateNode, schema *CandidateNode, _ int) ([]SchemaValidationError, error) {
+	if !schemaTypeMatches(node, "number") {
+		return nil, nil
+	}
+	value, ok := schemaNumber(node)
+	if !ok {
+		return nil, nil
+	}
+	errors := make([]SchemaValidationError, 0)
+
+	if minimum, ok := schemaNumber(schemaKeyword(schema, "minimum")); ok {
+		// draft 4 used a boolean exclusiveMinimum
+		exclusive := schemaKeyword(schema, "exclusiveMinimum")
+		if exclusive != nil && exclusive.Tag == "!!bool" && isTruthyNode(exclusive) {
+			if value <= minimum {
+				errors = append(errors, newSchemaError(node, "must be greater than %v, but got %v", minimum, node.Value))
+			}
+		} else if value < minimum {
+			errors = append(errors, newSchemaError(node, "must be greater than or equal to %v, but got %v", minimum, node.Value))
+		}
+	}
+	if maximum, ok := schemaNumber(schemaKeyword(schema, "maximum")); ok {
+		exclusive := schemaKeyword(schema, "exclusiveMaximum")
+		if exclusive != nil && exclusive.Tag == "!!bool" && isTruthyNode(exclusive) {
+			if value >= maximum {
+				errors = append(errors, newSchemaError(node, "must be less than %v, but got %v", maximum, node.Value))
+			}
+		} else if value > maximum {
+			errors = append(errors, newSchemaError(node, "must be less than or equal to %v, but got %v", maximum, node.Value))
+		}
+	}
+	if minimum, ok := schemaNumber(schemaKeyword(schema, "exclusiveMinimum")); ok && value <= minimum {
+		errors = append(errors, newSchemaError(node, "must be greater than %v, but got %v", minimum, node.Value))
+	}
+	if maximum, ok := schemaNumber(schemaKeyword(schema, "exclusiveMaximum")); ok && value >= maximum {
+		errors = append(errors, newSchemaError(node, "must be less than %v, but got %v", maximum, node.Value))
+	}
+	if multipleOf, ok := schemaNumber(schemaKeyword(schema, "multipleOf")); ok && multipleOf > 0 {
+		quotient := value / multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			errors = append(errors, newSchemaError(node, "must be a multiple of %v, but got %v", multipleOf, node.Value))
+		}
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateString(node *CandidateNode, schema *CandidateNode, _ int) ([]SchemaValidationError, error) {
+	if schemaType(node) != "string" {
+		return nil, nil
+	}
+	errors := make([]SchemaValidationError, 0)
+	length := utf8.RuneCountInString(node.Value)
+
+	if minLength, ok := schemaNumber(schemaKeyword(schema, "minLength")); ok && float64(length) < minLength {
+		errors = append(errors, newSchemaError(node, "must be at least %v characters long, but was %v", minLength, length))
+	}
+	if maxLength, ok := schemaNumber(schemaKeyword(schema, "maxLength")); ok && float64(length) > maxLength {
+		errors = append(errors, newSchemaError(node, "must be at most %v characters long, but was %v", maxLength, length))
+	}
+	if patternNode := schemaKeyword(schema, "pattern"); patternNode != nil {
+		pattern, err := v.pattern(patternNode.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !pattern.MatchString(node.Value) {
+			errors = append(errors, newSchemaError(node, "must match the pattern %v", patternNode.Value))
+		}
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateArray(node *CandidateNode, schema *CandidateNode, _ int) ([]SchemaValidationError, error) {
+	if node.Kind != SequenceNode {
+		return nil, nil
+	}
+	errors := make([]SchemaValidationError, 0)
+	count := len(node.Content)
+
+	if minItems, ok := schemaNumber(schemaKeyword(schema, "minItems")); ok && float64(count) < minItems {
+		errors = append(errors, newSchemaError(node, "must have at least %v items, but has %v", minItems, count))
+	}
+	if maxItems, ok := schemaNumber(schemaKeyword(schema, "maxItems")); ok && float64(count) > maxItems {
+		errors = append(errors, newSchemaError(node, "must have at most %v items, but has %v", maxItems, count))
+	}
+	if uniqueItems := schemaKeyword(schema, "uniqueItems"); uniqueItems != nil && isTruthyNode(uniqueItems) {
+	unique:
+		for i := 0; i < count; i++ {
+			for j := i + 1; j < count; j++ {
+				if schemaNodeEqual(node.Content[i], node.Content[j]) {
+					errors = append(errors, newSchemaError(node, "must only have unique items, but [%v] and [%v] are the same", i, j))
+					break unique
+				}
+			}
+		}
+	}
+
+	// the schemas for the first items, and the schema for the rest
+	var prefixSchemas []*CandidateNode
+	restSchema := schemaKeyword(schema, "items")
+	if prefixItems := schemaKeyword(schema, "prefixItems"); prefixItems != nil {
+		prefixSchemas = prefixItems.Content
+	} else if restSchema != nil && restSchema.Kind == SequenceNode {
+		// draft 7 tuples
+		prefixSchemas = restSchema.Content
+		restSchema = schemaKeyword(schema, "additionalItems")
+	}
+
+	for index, item := range node.Content {
+		itemSchema := restSchema
+		if index < len(prefixSchemas) {
+			itemSchema = prefixSchemas[index]
+		}
+		if itemSchema == nil {
+			continue
+		}
+		itemErrors, err := v.validate(item, itemSchema, 0)
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, itemErrors...)
+	}
+
+	if contains := schemaKeyword(schema, "contains"); contains != nil {
+		matches := 0
+		for _, item := range node.Content {
+			ok, err := v.matches(item, contains, 0)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matches++
+			}
+		}
+		minContains := 1.0
+		if value, ok := schemaNumber(schemaKeyword(schema, "minContains")); ok {
+			minContains = value
+		}
+		if float64(matches) < minContains {
+			errors = append(errors, newSchemaError(node, "must contain at least %v items matching the schema in contains, but has %v", minContains, matches))
+		}
+		if maxContains, ok := schemaNumber(schemaKeyword(schema, "maxContains")); ok && float64(matches) > maxContains {
+			errors = append(errors, newSchemaError(node, "must contain at most %v items matching the schema in contains, but has %v", maxContains, matches))
+		}
+	}
+	return errors, nil
+}
+
+func (v *jsonSchemaValidator) validateObject(node *CandidateNode, schema *CandidateNode, refDepth int) ([]SchemaValidationError, error) {
+	if node.Kind != MappingNode {
+		return nil, nil
+	}
+	errors := make([]SchemaValidationError, 0)
+	count := len(node.Content) / 2
+
+	if minProperties, ok := schemaNumber(schemaKeyword(schema, "minProperties")); ok && float64(count) < minProperties {
+		errors = append(errors, newSchemaError(node, "must have at least %v properties, but has %v", minProperties, count))
+	}
+	if maxProperties, ok := schemaNumber(schemaKeyword(schema, "maxProperties")); ok && float64(count) > maxProperties {
+		errors = append(errors, newSchemaError(node, "must have at most %v properties, but has %v", maxProperties, count))
+	}
+
+	if required := schemaKeyword(schema, "required"); required != nil && required.Kind == SequenceNode {
+		for _, name := range required.Content {
+			if schemaKeyword(node, name.Value) == nil {
+				errors = append(errors, newSchemaError(node, "missing required property %v", strconv.Quote(name.Value)))
+			}
+		}
+	}
+
+	dependentRequired := schemaKeyword(schema, "dependentRequired")
+	if dependencies := schemaKeyword(schema, "dependencies"); dependentRequired == nil {
+		dependentRequired = dependencies
+	}
+	if dependentRequired != nil && dependentRequired.Kind == MappingNode {
+		for index := 0; index+1 < len(dependentRequired.Content); index = index + 2 {
+			property := dependentRequired.Content[index].Value
+			if schemaKeyword(node, property) == nil {
+				continue
+			}
+			dependency := dependentRequired.Content[index+1]
+			if dependency.Kind != SequenceNode {
+				// draft 7 schema dependencies
+				subErrors, err := v.validate(node, dependency, refDepth)
+				if err != nil {
+					return nil, err
+				}
+				errors = append(errors, subErrors...)
+				continue
+			}
+			for _, name := range dependency.Content {
+				if schemaKeyword(node, name.Value) == nil {
+					errors = append(errors, newSchemaError(node, "property %v requires property %v", strconv.Quote(property), strconv.Quote(name.Value)))
+				}
+			}
+		}
+	}
+
+	properties := schemaKeyword(schema, "properties")
+	patternProperties := schemaKeyword(schema, "patternProperties")
+	additionalProperties := schemaKeyword(schema, "additionalProperties")
+	propertyNames := schemaKeyword(schema, "propertyNames")
+
+	for index := 0; index+1 < len(node.Content); index = index + 2 {
+		key := node.Content[index]
+		value := node.Content[index+1]
+		matched := false
+
+		if propertyNames != nil {
+			keyErrors, err := v.validate(createStringScalarNode(key.Value), propertyNames, 0)
+			if err != nil {
+				return nil, err
+			}
+			if len(keyErrors) > 0 {
+				errors = append(errors, newSchemaError(value, "property name %v does not match the schema in propertyNames", strconv.Quote(key.Value)))
+			}
+		}
+
+		if properties != nil {
+			if propertySchema := schemaKeyword(properties, key.Value); propertySchema != nil {
+				matched = true
+				propertyErrors, err := v.validate(value, propertySchema, 0)
+				if err != nil {
+					return nil, err
+				}
+				errors = append(errors, propertyErrors...)
+			}
+		}
+
+		if patternProperties != nil {
+			for patternIndex := 0; patternIndex+1 < len(patternProperties.Content); patternIndex = patternIndex + 2 {
+				pattern, err := v.pattern(patternProperties.Content[patternIndex].Value)
+				if err != nil {
+					return nil, err
+				}
+				if !pattern.MatchString(key.Value) {
+					continue
+				}
+				matched = true
+				propertyErrors, err := v.validate(value, patternProperties.Content[patternIndex+1], 0)
+				if err != nil {
+					return nil, err
+				}
+				errors = append(errors, propertyErrors...)
+			}
+		}
+
+		if !matched && additionalProperties != nil {
+			if additionalProperties.Tag == "!!bool" && !isTruthyNode(additionalProperties) {
+				errors = append(errors, newSchemaError(value, "property %v is not allowed", strconv.Quote(key.Value)))
+				continue
+			}
+			propertyErrors, err := v.validate(value, additionalProperties, 0)
+			if err != nil {
+				return nil, err
+			}
+			errors = append(errors, propertyErrors...)
+		}
+	}
+
+	// report in document order
+	sort.SliceStable(errors, func(i, j int) bool {
+		return errors[i].Line < errors[j].Line || (errors[i].Line == errors[j].Line && errors[i].Column < errors[j].Column)
+	})
+	return errors, nil
+}
@@ -24,6 +24,11 @@ const sampleNdJson = `{"this": "is a multidoc json file"}
 {"a number": 4}
 `
 
+const sampleJsonLinesWithInvalid = `{"level": "info", "msg": "started"}
+{"level": "warn", "msg": "slow
+{"level": "error", "msg": "failed"}
+`
+
 const sampleNdJsonKey = `{"a": "first", "b": "next", "ab": "last"}`
 
 const expectedJsonKeysInOrder = `a: first
@@ -172,7 +177,7 @@ var jsonScenarios = []formatScenario{
 		description:   "bad json",
 		skipDoc:       true,
 		input:         `{"a": 1 b": 2}`,
-		expectedError: `bad file 'sample.yml': json: string of object unexpected end of JSON input`,
+		expectedError: `bad file 'sample.yml': json: line 1, column 9: expected ',' or '}' after object value but found 'b'`,
 		scenarioType:  "decode-error",
 	},
 	{
@@ -189,6 +194,14 @@ var jsonScenarios = []formatScenario{
 		expected:     "{\n  \"cat\": \"meow\"\n}\n",
 		scenarioType: "encode",
 	},
+	{
+		description:  "Encode json: NaN and infinity",
+		skipDoc:      true,
+		input:        `[.nan, .NaN, .inf, +.Inf, -.INF]`,
+		indent:       0,
+		expected:     "[null,null,1.7976931348623157e+308,1.7976931348623157e+308,-1.7976931348623157e+308]\n",
+		scenarioType: "encode",
+	},
 	{
 		description:  "Encode json: simple - in one line",
 		input:        `cat: meow # this is a comment, and it will be dropped.`,
@@ -256,6 +269,43 @@ var jsonScenarios = []formatScenario{
 		expected:     expectedNdJsonYaml,
 		scenarioType: "decode-ndjson",
 	},
+	{
+		description:    "Decode JSON Lines / NDJSON line by line",
+		subdescription: "The ndjson (or jsonl) format reads one document per line, and knows the line each came from. Blank lines are ignored.",
+		input:          sampleNdJson + "\n",
+		expression:     `line`,
+		expected:       "1\n2\n3\n",
+		scenarioType:   "decode-jsonl",
+	},
+	{
+		description:    "Skip invalid JSON Lines",
+		subdescription: "Use --skip-invalid to log lines that are not valid JSON and carry on, rather than stopping at the first one.",
+		input:          sampleJsonLinesWithInvalid,
+		expression:     `.msg`,
+		expected:       "started\n---\nfailed\n",
+		scenarioType:   "decode-jsonl-skip-invalid",
+	},
+	{
+		skipDoc:       true,
+		description:   "invalid JSON Lines report the line",
+		input:         sampleJsonLinesWithInvalid,
+		expectedError: "bad file 'sample.yml': json: line 2, column 31: unexpected end of JSON input",
+		scenarioType:  "decode-jsonl-error",
+	},
+	{
+		skipDoc:       true,
+		description:   "one value per JSON line",
+		input:         "{\"a\": 1} {\"a\": 2}\n",
+		expectedError: "bad file 'sample.yml': json: line 1: expected one value on the line but found more",
+		scenarioType:  "decode-jsonl-error",
+	},
+	{
+		skipDoc:      true,
+		description:  "JSON Lines without a final new line",
+		input:        "{\"a\": 1}\r\n\r\n{\"a\": 2}",
+		expected:     "a: 1\n---\na: 2\n",
+		scenarioType: "decode-jsonl",
+	},
 	{
 		description:  "Decode JSON Lines / NDJSON, maintain key order",
 		skipDoc:      true,
@@ -267,9 +317,103 @@ var jsonScenarios = []formatScenario{
 		description:  "numbers",
 		skipDoc:      true,
 		input:        "[3, 3.0, 3.1, -1, 999999, 1000000, 1000001, 1.1]",
-		expected:     "- 3\n- 3\n- 3.1\n- -1\n- 999999\n- 1000000\n- 1000001\n- 1.1\n",
+		expected:     "- 3\n- 3.0\n- 3.1\n- -1\n- 999999\n- 1000000\n- 1000001\n- 1.1\n",
 		scenarioType: "decode-ndjson",
 	},
+	{
+		description:    "Numbers are kept exactly as written",
+		subdescription: "Big integers and precise decimals are not rounded",
+		input:          `{"id": 123456789012345678901234, "price": 0.10000000000000000001, "total": 1.50}`,
+		scenarioType:   "roundtrip-ndjson",
+		expected:       "{\"id\":123456789012345678901234,\"price\":0.10000000000000000001,\"total\":1.50}\n",
+	},
+	{
+		skipDoc:      true,
+		description:  "number tags",
+		input:        `[1, -0, 1.5, 1e3, 123456789012345678901234]`,
+		expression:   `[.[] | tag]`,
+		scenarioType: "roundtrip-ndjson",
+		expected:     "[\"!!int\",\"!!int\",\"!!float\",\"!!float\",\"!!float\"]\n",
+	},
+	{
+		description:    "Line and column numbers",
+		subdescription: "The position of each value is recorded, so you can find where things are in the file",
+		input:          "{\n  \"name\": \"cat\",\n  \"toys\": [\"ball\", \"string\"]\n}",
+		expression:     `.toys[1] | {"line": line, "column": column}`,
+		scenarioType:   "decode-ndjson",
+		expected:       "line: 3\ncolumn: 20\n",
+	},
+	{
+		skipDoc:      true,
+		description:  "key line and column numbers",
+		input:        "{\n  \"name\": \"cat\"\n}",
+		expression:   `.name | key | [line, column]`,
+		scenarioType: "roundtrip-ndjson",
+		expected:     "[2,3]\n",
+	},
+	{
+		description:    "Duplicate keys",
+		subdescription: "By default the last value of a duplicate key is used, in the position of the first. Use `--json-duplicate-keys=first` to keep the first value, or `--json-duplicate-keys=error` to fail.",
+		input:          `{"a": 1, "b": 2, "a": 3}`,
+		scenarioType:   "decode-ndjson",
+		expected:       "a: 3\nb: 2\n",
+	},
+	{
+		skipDoc:      true,
+		description:  "Duplicate keys, keep first",
+		input:        `{"a": 1, "b": 2, "a": 3}`,
+		scenarioType: "decode-duplicate-first",
+		expected:     "a: 1\nb: 2\n",
+	},
+	{
+		skipDoc:       true,
+		description:   "Duplicate keys, error",
+		input:         "{\"a\": 1,\n \"b\": {\"c\": 2,\n  \"c\": 3}}",
+		scenarioType:  "decode-duplicate-error",
+		expectedError: `bad file 'sample.yml': json: line 3, column 3: duplicate key "c", first defined at line 2, column 8`,
+	},
+	{
+		skipDoc:      true,
+		description:  "string escapes",
+		input:        `["a\"b\\c\/d\n\t", "\u00e9\ud83d\ude00", "\ud800x"]`,
+		scenarioType: "roundtrip-ndjson",
+		expected:     "[\"a\\\"b\\\\c/d\\n\\t\",\"é😀\",\"\ufffdx\"]\n",
+	},
+	{
+		skipDoc:       true,
+		description:   "truncated json",
+		input:         "{\"a\": [1,\n2",
+		scenarioType:  "decode-error",
+		expectedError: `bad file 'sample.yml': json: line 2, column 2: unexpected end of JSON input`,
+	},
+	{
+		skipDoc:       true,
+		description:   "bad number",
+		input:         `[01]`,
+		scenarioType:  "decode-error",
+		expectedError: `bad file 'sample.yml': json: line 1, column 2: invalid number 01`,
+	},
+	{
+		skipDoc:       true,
+		description:   "bad literal",
+		input:         `[nope]`,
+		scenarioType:  "decode-error",
+		expectedError: `bad file 'sample.yml': json: line 1, column 2: invalid literal nope`,
+	},
+	{
+		skipDoc:       true,
+		description:   "number out of range",
+		input:         "[1,\n 1e400]",
+		scenarioType:  "decode-error",
+		expectedError: `bad file 'sample.yml': json: line 2, column 2: number 1e400 is out of range`,
+	},
+	{
+		description:  "very small numbers are kept",
+		skipDoc:      true,
+		input:        "[1e-400]",
+		expected:     "[1e-400]\n",
+		scenarioType: "roundtrip-ndjson",
+	},
 	{
 		description:  "number single",
 		skipDoc:      true,
@@ -305,6 +449,42 @@ var jsonScenarios = []formatScenario{
 		expected:     "- true\n- false\n",
 		scenarioType: "decode-ndjson",
 	},
+	{
+		description:    "Decode as a stream of events",
+		subdescription: "Use `--stream` to read JSON as a stream of `[path, leaf]` events, like `tostream` in jq. The input is never fully loaded, so this works on huge files in constant memory.",
+		input:          `{"a": [1, {"b": 2}], "c": {}}`,
+		scenarioType:   "decode-stream",
+		expected:       "[[\"a\",0],1]\n[[\"a\",1,\"b\"],2]\n[[\"a\",1,\"b\"]]\n[[\"a\",1]]\n[[\"c\"],{}]\n[[\"c\"]]\n",
+	},
+	{
+		description:    "Filter a stream of events",
+		subdescription: "Each event is evaluated separately, so you can select just the parts you need",
+		input:          `{"items": [{"name": "cat"}, {"name": "dog"}], "other": "stuff"}`,
+		expression:     `select(.[0][-1] == "name" and length == 2)`,
+		scenarioType:   "decode-stream",
+		expected:       "[[\"items\",0,\"name\"],\"cat\"]\n[[\"items\",1,\"name\"],\"dog\"]\n",
+	},
+	{
+		skipDoc:      true,
+		description:  "stream of several json documents",
+		input:        `1 [] {"a": null}`,
+		scenarioType: "decode-stream",
+		expected:     "[[],1]\n[[],[]]\n[[\"a\"],null]\n[[\"a\"]]\n",
+	},
+	{
+		skipDoc:      true,
+		description:  "stream keeps numbers as written",
+		input:        `{"a": 12345678901234567890, "b": 1.50, "c": 2}`,
+		scenarioType: "decode-stream",
+		expected:     "[[\"a\"],12345678901234567890]\n[[\"b\"],1.50]\n[[\"c\"],2]\n[[\"c\"]]\n",
+	},
+	{
+		skipDoc:       true,
+		description:   "stream of truncated json",
+		input:         `{"a": [1, 2`,
+		scenarioType:  "decode-stream-error",
+		expectedError: "bad file 'sample.yml': unexpected EOF",
+	},
 }
 
 func documentRoundtripNdJsonScenario(w *bufio.Writer, s formatScenario, indent int) {
@@ -360,6 +540,64 @@ func documentDecodeNdJsonScenario(w *bufio.Writer, s formatScenario) {
 	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewJSONDecoder(), NewYamlEncoder(ConfiguredYamlPreferences))))
 }
 
+func documentDecodeJSONLinesScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.jsonl file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```json\n%v\n```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+
+	prefs := ConfiguredJSONPreferences.Copy()
+	flags := "-p=ndjson -oy"
+	if s.scenarioType == "decode-jsonl-skip-invalid" {
+		prefs.SkipInvalid = true
+		flags = flags + " --skip-invalid"
+	}
+	expression := s.expression
+	if expression == "" {
+		expression = "."
+	}
+	writeOrPanic(w, fmt.Sprintf("```bash\nyq %v '%v' sample.jsonl\n```\n", flags, expression))
+
+	writeOrPanic(w, "will output\n")
+
+	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewNDJSONDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences))))
+}
+
+func documentDecodeStreamScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.json file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```json\n%v\n```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+
+	expression := s.expression
+	if expression != "" {
+		writeOrPanic(w, fmt.Sprintf("```bash\nyq -p=json -o=json -I=0 --stream '%v' sample.json\n```\n", expression))
+	} else {
+		writeOrPanic(w, "```bash\nyq -p=json -o=json -I=0 --stream sample.json\n```\n")
+	}
+
+	writeOrPanic(w, "will output\n")
+	prefs := ConfiguredJSONPreferences.Copy()
+	prefs.Indent = 0
+	prefs.UnwrapScalar = false
+
+	writeOrPanic(w, fmt.Sprintf("```json\n%v```\n\n", mustProcessFormatScenario(s, NewJSONStreamDecoder(), NewJSONEncoder(prefs))))
+}
+
 func decodeJSON(t *testing.T, jsonString string) *CandidateNode {
 	docs, err := readDocument(jsonString, "sample.json", 0)
 
@@ -396,11 +634,43 @@ func testJSONScenario(t *testing.T, s formatScenario) {
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONDecoder(), NewJSONEncoder(prefs)), s.description)
 	case "decode-ndjson":
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONDecoder(), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "decode-jsonl":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewNDJSONDecoder(ConfiguredJSONPreferences), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "decode-jsonl-skip-invalid":
+		prefs.SkipInvalid = true
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewNDJSONDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "decode-jsonl-error":
+		result, err := processFormatScenario(s, NewNDJSONDecoder(ConfiguredJSONPreferences), NewYamlEncoder(ConfiguredYamlPreferences))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
 	case "roundtrip-ndjson":
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONDecoder(), NewJSONEncoder(prefs)), s.description)
 	case "roundtrip-multi":
 		prefs.Indent = 2
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONDecoder(), NewJSONEncoder(prefs)), s.description)
+	case "decode-stream":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONStreamDecoder(), NewJSONEncoder(prefs)), s.description)
+	case "decode-duplicate-first":
+		prefs.DuplicateKeys = JsonDuplicateKeysFirst
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewJSONDecoderWithPreferences(prefs), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "decode-duplicate-error":
+		prefs.DuplicateKeys = JsonDuplicateKeysError
+		result, err := processFormatScenario(s, NewJSONDecoderWithPreferences(prefs), NewYamlEncoder(ConfiguredYamlPreferences))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
+	case "decode-stream-error":
+		result, err := processFormatScenario(s, NewJSONStreamDecoder(), NewJSONEncoder(prefs))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
 	case "decode-error":
 		result, err := processFormatScenario(s, NewJSONDecoder(), NewJSONEncoder(prefs))
 		if err == nil {
@@ -454,10 +724,14 @@ func documentJSONScenario(t *testing.T, w *bufio.Writer, i interface{}) {
 		documentJSONEncodeScenario(w, s)
 	case "decode-ndjson":
 		documentDecodeNdJsonScenario(w, s)
+	case "decode-jsonl", "decode-jsonl-skip-invalid":
+		documentDecodeJSONLinesScenario(w, s)
 	case "roundtrip-ndjson":
 		documentRoundtripNdJsonScenario(w, s, 0)
 	case "roundtrip-multi":
 		documentRoundtripNdJsonScenario(w, s, 2)
+	case "decode-stream":
+		documentDecodeStreamScenario(w, s)
 
 	default:
 		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
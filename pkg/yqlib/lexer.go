@@ -20,6 +20,11 @@ const (
 	openCollectObject
 	closeCollectObject
 	traverseArrayCollect
+	conditionalIf
+	conditionalThen
+	conditionalElif
+	conditionalElse
+	conditionalEnd
 )
 
 type token struct {
@@ -50,7 +55,16 @@ func (t *token) toString(detail bool) string {
 		return "}"
 	} else if t.TokenType == traverseArrayCollect {
 		return ".["
-
+	} else if t.TokenType == conditionalIf {
+		return "if"
+	} else if t.TokenType == conditionalThen {
+		return "then"
+	} else if t.TokenType == conditionalElif {
+		return "elif"
+	} else if t.TokenType == conditionalElse {
+		return "else"
+	} else if t.TokenType == conditionalEnd {
+		return "end"
 	}
 	return "NFI"
 }
@@ -80,6 +94,348 @@ func hasOptionParameter(value string, option string) bool {
 	return len(optionParser.FindStringSubmatch(parameterString)) > 0
 }
 
+type conditionalState uint32
+
+const (
+	conditionalExpectThen conditionalState = iota
+	conditionalExpectElseOrEnd
+	conditionalExpectEnd
+)
+
+type conditionalFrame struct {
+	state conditionalState
+	elifs int
+}
+
+// expandConditionals rewrites
+//
+//	if c1 then a elif c2 then b else e end
+//
+// into
+//
+//	CONDITIONAL ( (c1) ; (a) ; ( CONDITIONAL ( (c2) ; (b) ; (e) ) ) )
+//
+// so the postfixer can treat it like any other operator with block arguments.
+// A missing else branch defaults to `.`.
+func expandConditionals(tokens []*token) ([]*token, error) {
+	var expanded = make([]*token, 0, len(tokens))
+	var frames = make([]*conditionalFrame, 0)
+
+	open := func() *token { return &token{TokenType: openBracket} }
+	closeB := func() *token { return &token{TokenType: closeBracket, CheckForPostTraverse: true} }
+	block := func() *token {
+		return &token{TokenType: operationToken, Operation: &Operation{OperationType: blockOpType, Value: blockOpType.Type, StringValue: ";"}}
+	}
+	conditional := func() *token {
+		return &token{TokenType: operationToken, Operation: &Operation{OperationType: conditionalOpType, Value: conditionalOpType.Type, StringValue: "if"}}
+	}
+
+	for _, currentToken := range tokens {
+		var frame *conditionalFrame
+		if len(frames) > 0 {
+			frame = frames[len(frames)-1]
+		}
+
+		switch currentToken.TokenType {
+		case conditionalIf:
+			frames = append(frames, &conditionalFrame{state: conditionalExpectThen})
+			expanded = append(expanded, conditional(), open(), open())
+		case conditionalThen:
+			if frame == nil || frame.state != conditionalExpectThen {
+				return nil, fmt.Errorf("bad expression, 'then' without matching 'if' or 'elif'")
+			}
+			frame.state = conditionalExpectElseOrEnd
+			expanded = append(expanded, closeB(), block(), open())
+		case conditionalElif:
+			if frame == nil || frame.state != conditionalExpectElseOrEnd {
+				return nil, fmt.Errorf("bad expression, 'elif' without matching 'if ... then'")
+			}
+			frame.state = conditionalExpectThen
+			frame.elifs++
+			expanded = append(expanded, closeB(), block(), open(), conditional(), open(), open())
+		case conditionalElse:
+			if frame == nil || frame.state != conditionalExpectElseOrEnd {
+				return nil, fmt.Errorf("bad expression, 'else' without matching 'if ... then'")
+			}
+			frame.state = conditionalExpectEnd
+			expanded = append(expanded, closeB(), block(), open())
+		case conditionalEnd:
+			if frame == nil || frame.state == conditionalExpectThen {
+				return nil, fmt.Errorf("bad expression, 'end' without matching 'if ... then'")
+			}
+			if frame.state == conditionalExpectElseOrEnd {
+				selfToken := &token{TokenType: operationToken, Operation: &Operation{OperationType: selfReferenceOpType, StringValue: "SELF"}}
+				expanded = append(expanded, closeB(), block(), open(), selfToken)
+			}
+			expanded = append(expanded, closeB(), closeB())
+			for i := 0; i < frame.elifs; i++ {
+				expanded = append(expanded, closeB(), closeB())
+			}
+			frames = frames[:len(frames)-1]
+		default:
+			expanded = append(expanded, currentToken)
+		}
+	}
+	if len(frames) > 0 {
+		return nil, fmt.Errorf("bad expression, could not find matching 'end' for 'if'")
+	}
+	return expanded, nil
+}
+
+type functionFrameKind uint32
+
+const (
+	functionFrameBody functionFrameKind = iota
+	functionFrameRest
+	functionFrameArgs
+)
+
+type functionFrame struct {
+	kind  functionFrameKind
+	depth int
+	// index into the expanded tokens where the current section started
+	start int
+	call  *Operation
+	arity int
+	// the function defined by a def, in scope until the frame ends
+	name   string
+	params []string
+}
+
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+// isFunctionInScope is true when a def of that name and arity (or one of the
+// parameters of the def being defined) is in scope, so it can shadow a builtin
+// like in jq.
+func isFunctionInScope(frames []*functionFrame, name string, arity int) bool {
+	for _, frame := range frames {
+		if frame.kind == functionFrameArgs || frame.name == "" {
+			continue
+		}
+		if frame.name == name && frame.arity == arity {
+			return true
+		}
+		if frame.kind == functionFrameBody && arity == 0 {
+			for _, param := range frame.params {
+				if param == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// callArity counts the arguments of the function call whose brackets open at the
+// index, ignoring the ';' that end the defs and imports within the arguments.
+func callArity(tokens []*token, index int) int {
+	arity := 1
+	depth := 0
+	unterminated := 0
+	for ; index < len(tokens); index++ {
+		currentToken := tokens[index]
+		switch {
+		case currentToken.TokenType == openBracket || currentToken.TokenType == openCollect ||
+			currentToken.TokenType == openCollectObject || currentToken.TokenType == traverseArrayCollect:
+			depth++
+		case currentToken.TokenType == closeBracket || currentToken.TokenType == closeCollect ||
+			currentToken.TokenType == closeCollectObject:
+			depth--
+			if depth == 0 {
+				return arity
+			}
+		case depth == 1 && (tokenIsOpType(currentToken, defineFunctionOpType) || tokenIsOpType(currentToken, importOpType)):
+			unterminated++
+		case depth == 1 && tokenIsOpType(currentToken, blockOpType):
+			if unterminated > 0 {
+				unterminated--
+			} else {
+				arity++
+			}
+		}
+	}
+	return arity
+}
+
+// expandFunctions rewrites
+//
+//	def f($a; g): body; rest
+//
+// into
+//
+//	DEF ( (body) ; (rest) )
+//
+// where rest is everything up to the end of the enclosing brackets, imports
+// like `import "lib" as lib;` into IMPORT ( (rest) ), and function calls with
+// arguments like `f(x; y)` into CALL_FUNCTION ( (x) ; (y) ). Names has the
+// text of the tokens that look like function names, so that a def can shadow
+// the builtin of the same name.
+func expandFunctions(tokens []*token, names map[*token]string) ([]*token, error) {
+	var expanded = make([]*token, 0, len(tokens))
+	var frames = make([]*functionFrame, 0)
+	depth := 0
+
+	open := func() *token { return &token{TokenType: openBracket} }
+	closeB := func() *token { return &token{TokenType: closeBracket, CheckForPostTraverse: true} }
+	self := func() *token {
+		return &token{TokenType: operationToken, Operation: &Operation{OperationType: selfReferenceOpType, StringValue: "SELF"}}
+	}
+
+	// the rest of a def runs until the end of the brackets it was defined in
+	closeRestFrames := func() {
+		for len(frames) > 0 {
+			frame := frames[len(frames)-1]
+			if frame.kind != functionFrameRest || frame.depth != depth {
+				return
+			}
+			if len(expanded) == frame.start {
+				expanded = append(expanded, self())
+			}
+			expanded = append(expanded, closeB(), closeB())
+			frames = frames[:len(frames)-1]
+		}
+	}
+
+	for index := 0; index < len(tokens); index++ {
+		currentToken := tokens[index]
+
+		if name, ok := names[currentToken]; ok && currentToken.TokenType == operationToken &&
+			!tokenIsOpType(currentToken, callFunctionOpType) && !tokenIsOpType(currentToken, defineFunctionOpType) {
+			arity := 0
+			if index != len(tokens)-1 && tokens[index+1].TokenType == openBracket {
+				arity = callArity(tokens, index+1)
+			}
+			if isFunctionInScope(frames, name, arity) {
+				op := &Operation{OperationType: callFunctionOpType, Value: callFunctionOpType.Type, StringValue: name,
+					Preferences: functionCallPreferences{Name: name}}
+				currentToken = &token{TokenType: operationToken, Operation: op, CheckForPostTraverse: true}
+			}
+		}
+
+		switch {
+		case tokenIsOpType(currentToken, defineFunctionOpType):
+			params, nextIndex, err := readFunctionParams(tokens, names, index+1)
+			if err != nil {
+				return nil, err
+			}
+			prefs := currentToken.Operation.Preferences.(functionDefinitionPreferences)
+			prefs.Params = params
+			currentToken.Operation.Preferences = prefs
+			index = nextIndex
+
+			expanded = append(expanded, currentToken, open(), open())
+			frames = append(frames, &functionFrame{kind: functionFrameBody, depth: depth, name: prefs.Name, arity: len(params), params: params})
+
+		case tokenIsOpType(currentToken, importOpType):
+			// like the rest of a def, the imported functions can be used until
+			// the end of the enclosing brackets
+			expanded = append(expanded, currentToken, open(), open())
+			frames = append(frames, &functionFrame{kind: functionFrameRest, depth: depth, start: len(expanded)})
+
+		case tokenIsOpType(currentToken, callFunctionOpType) &&
+			index != len(tokens)-1 && tokens[index+1].TokenType == openBracket:
+			currentToken.Operation.OperationType = callFunctionWithArgsOpType
+			currentToken.CheckForPostTraverse = false
+			index++
+			depth++
+			expanded = append(expanded, currentToken, open(), open())
+			frames = append(frames, &functionFrame{kind: functionFrameArgs, depth: depth, call: currentToken.Operation, arity: 1})
+
+		case tokenIsOpType(currentToken, blockOpType):
+			closeRestFrames()
+			if len(frames) == 0 || frames[len(frames)-1].depth != depth {
+				expanded = append(expanded, currentToken)
+				continue
+			}
+			frame := frames[len(frames)-1]
+			switch frame.kind {
+			case functionFrameBody:
+				frame.kind = functionFrameRest
+				expanded = append(expanded, closeB(), currentToken, open())
+				frame.start = len(expanded)
+			case functionFrameArgs:
+				frame.arity++
+				expanded = append(expanded, closeB(), currentToken, open())
+			default:
+				expanded = append(expanded, currentToken)
+			}
+
+		case currentToken.TokenType == openBracket || currentToken.TokenType == openCollect ||
+			currentToken.TokenType == openCollectObject || currentToken.TokenType == traverseArrayCollect:
+			depth++
+			expanded = append(expanded, currentToken)
+
+		case currentToken.TokenType == closeBracket || currentToken.TokenType == closeCollect ||
+			currentToken.TokenType == closeCollectObject:
+			closeRestFrames()
+			if len(frames) > 0 && frames[len(frames)-1].depth == depth {
+				frame := frames[len(frames)-1]
+				if frame.kind == functionFrameBody {
+					return nil, fmt.Errorf("bad expression, the body of a def must end with ';'")
+				}
+				// must be the arguments of a function call
+				frame.call.Preferences = functionCallPreferences{Name: frame.call.Preferences.(functionCallPreferences).Name, Arity: frame.arity}
+				frame.call.StringValue = functionKey(frame.call.Preferences.(functionCallPreferences).Name, frame.arity)
+				expanded = append(expanded, closeB())
+				frames = frames[:len(frames)-1]
+			}
+			depth--
+			expanded = append(expanded, currentToken)
+
+		default:
+			expanded = append(expanded, currentToken)
+		}
+	}
+
+	closeRestFrames()
+	if len(frames) > 0 {
+		if frames[len(frames)-1].kind == functionFrameBody {
+			return nil, fmt.Errorf("bad expression, the body of a def must end with ';'")
+		}
+		return nil, fmt.Errorf("bad expression, could not find matching ')' for function arguments")
+	}
+	return expanded, nil
+}
+
+// readFunctionParams reads the optional `($a; f)` parameter list of a def,
+// up to and including the ':'. It returns the index of the ':' token.
+func readFunctionParams(tokens []*token, names map[*token]string, index int) ([]string, int, error) {
+	params := make([]string, 0)
+	if index < len(tokens) && tokens[index].TokenType == openBracket {
+		index++
+		for {
+			if index >= len(tokens) {
+				return nil, index, fmt.Errorf("bad expression, could not find matching ')' for def parameters")
+			}
+			currentToken := tokens[index]
+			switch {
+			case tokenIsOpType(currentToken, getVariableOpType):
+				params = append(params, "$"+currentToken.Operation.StringValue)
+			case tokenIsOpType(currentToken, callFunctionOpType):
+				params = append(params, currentToken.Operation.Preferences.(functionCallPreferences).Name)
+			case names[currentToken] != "":
+				// parameters can shadow builtins too
+				params = append(params, names[currentToken])
+			default:
+				return nil, index, fmt.Errorf("bad expression, def parameters must be names like 'f' or '$a', separated by ';'")
+			}
+			index++
+			if index < len(tokens) && tokens[index].TokenType == closeBracket {
+				index++
+				break
+			}
+			if index >= len(tokens) || !tokenIsOpType(tokens[index], blockOpType) {
+				return nil, index, fmt.Errorf("bad expression, def parameters must be separated by ';'")
+			}
+			index++
+		}
+	}
+	if index >= len(tokens) || !tokenIsOpType(tokens[index], createMapOpType) {
+		return nil, index, fmt.Errorf("bad expression, def must be followed by ':' and a body, e.g. def f: .a;")
+	}
+	return params, index, nil
+}
+
 func postProcessTokens(tokens []*token) []*token {
 	var postProcessedTokens = make([]*token, 0)
 
@@ -133,6 +489,13 @@ func handleToken(tokens []*token, index int, postProcessedTokens []*token) (toke
 		}
 	}
 
+	if tokenIsOpType(currentToken, explodeOpType) &&
+		(index == len(tokens)-1 || tokens[index+1].TokenType != openBracket) {
+		// without an argument, explode is jq's explode of a string into codepoints
+		currentToken.Operation.OperationType = explodeStringOpType
+		currentToken.CheckForPostTraverse = false
+	}
+
 	if index != len(tokens)-1 && currentToken.AssignOperation != nil &&
 		tokenIsOpType(tokens[index+1], assignOpType) {
 		log.Debug("its an update assign")
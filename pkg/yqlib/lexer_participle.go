@@ -1,6 +1,9 @@
 package yqlib
 
 import (
+	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -12,6 +15,18 @@ var participleYqRules = []*participleYqRule{
 	{"HEAD_COMMENT", `head_?comment|headComment`, opTokenWithPrefs(getCommentOpType, assignCommentOpType, commentOpPreferences{HeadComment: true}), 0},
 	{"FOOT_COMMENT", `foot_?comment|footComment`, opTokenWithPrefs(getCommentOpType, assignCommentOpType, commentOpPreferences{FootComment: true}), 0},
 
+	{"If", `if\b`, literalToken(conditionalIf, false), 0},
+	{"Then", `then\b`, literalToken(conditionalThen, false), 0},
+	{"Elif", `elif\b`, literalToken(conditionalElif, false), 0},
+	{"Else", `else\b`, literalToken(conditionalElse, false), 0},
+	{"End", `end\b`, literalToken(conditionalEnd, false), 0},
+	{"Try", `try\b`, opToken(tryOpType), 0},
+	{"Def", `def\s+[a-zA-Z_][a-zA-Z_0-9]*`, defineFunctionToken(), 0},
+	{"Import", `import\s+"[^"]*"\s+as\s+[a-zA-Z_][a-zA-Z_0-9]*\s*;`, importToken(), 0},
+	{"Include", `include\s+"[^"]*"\s*;`, importToken(), 0},
+	{"NamespacedIdentifier", `[a-zA-Z_][a-zA-Z_0-9]*::[a-zA-Z_][a-zA-Z_0-9]*`, callFunctionToken(), 0},
+	{"Catch", `catch\b`, opToken(catchOpType), 0},
+
 	{"OpenBracket", `\(`, literalToken(openBracket, false), 0},
 	{"CloseBracket", `\)`, literalToken(closeBracket, true), 0},
 	{"OpenTraverseArrayCollect", `\.\[`, literalToken(traverseArrayCollect, false), 0},
@@ -88,6 +103,13 @@ var participleYqRules = []*participleYqRule{
 	{"Uri", `@uri`, encodeWithIndent(UriFormat, 0), 0},
 	{"SH", `@sh`, encodeWithIndent(ShFormat, 0), 0},
 
+	{"MD5", `@md5`, opTokenWithPrefs(hashOpType, nil, hashPreferences{algorithm: "md5"}), 0},
+	{"SHA1", `@sha1`, opTokenWithPrefs(hashOpType, nil, hashPreferences{algorithm: "sha1"}), 0},
+	{"SHA256", `@sha256`, opTokenWithPrefs(hashOpType, nil, hashPreferences{algorithm: "sha256"}), 0},
+	{"SHA512", `@sha512`, opTokenWithPrefs(hashOpType, nil, hashPreferences{algorithm: "sha512"}), 0},
+	{"CRC32", `@crc32`, opTokenWithPrefs(hashOpType, nil, hashPreferences{algorithm: "crc32"}), 0},
+	{"Hash", `hash`, opToken(hashNodeOpType), 0},
+
 	{"LoadXML", `load_?xml|xml_?load`, loadOp(NewXMLDecoder(ConfiguredXMLPreferences)), 0},
 
 	{"LoadBase64", `load_?base64`, loadOp(NewBase64Decoder()), 0},
@@ -128,6 +150,7 @@ var participleYqRules = []*participleYqRule{
 	simpleOp("all", allOpType),
 
 	simpleOp("contains", containsOpType),
+	simpleOp("splits", splitsOpType),
 	simpleOp("split", splitStringOpType),
 
 	{"ParentWithLevel", `parent\([0-9]+\)`, parentWithLevel(), 0},
@@ -168,7 +191,18 @@ var participleYqRules = []*participleYqRule{
 
 	{"Uppercase", `upcase|ascii_?upcase`, opTokenWithPrefs(changeCaseOpType, nil, changeCasePrefs{ToUpperCase: true}), 0},
 	{"Downcase", `downcase|ascii_?downcase`, opTokenWithPrefs(changeCaseOpType, nil, changeCasePrefs{ToUpperCase: false}), 0},
-	simpleOp("trim", trimOpType),
+	{"Trim", `trim`, opTokenWithPrefs(trimOpType, nil, trimPreferences{Name: "trim", Left: true, Right: true}), 0},
+	{"LeftTrim", `ltrim`, opTokenWithPrefs(trimOpType, nil, trimPreferences{Name: "ltrim", Left: true}), 0},
+	{"RightTrim", `rtrim`, opTokenWithPrefs(trimOpType, nil, trimPreferences{Name: "rtrim", Right: true}), 0},
+	simpleOp("startswith", startsWithOpType),
+	simpleOp("endswith", endsWithOpType),
+	{"TrimPrefix", `ltrimstr`, opToken(trimPrefixOpType), 0},
+	{"TrimSuffix", `rtrimstr`, opToken(trimSuffixOpType), 0},
+	{"Index", `index`, opTokenWithPrefs(indexOpType, nil, indexPreferences{Name: "index", First: true}), 0},
+	{"RightIndex", `rindex`, opTokenWithPrefs(indexOpType, nil, indexPreferences{Name: "rindex", Last: true}), 0},
+	{"Indices", `indices`, opTokenWithPrefs(indexOpType, nil, indexPreferences{Name: "indices"}), 0},
+	simpleOp("implode", implodeOpType),
+	simpleOp("ascii", asciiOpType),
 	simpleOp("to_?string", toStringOpType),
 
 	{"HexValue", `0[xX][0-9A-Fa-f]+`, hexValue(), 0},
@@ -177,10 +211,10 @@ var participleYqRules = []*participleYqRule{
 
 	{"NumberValue", `-?\d+`, numberValue(), 0},
 
-	{"TrueBooleanValue", `[Tt][Rr][Uu][Ee]`, booleanValue(true), 0},
-	{"FalseBooleanValue", `[Ff][Aa][Ll][Ss][Ee]`, booleanValue(false), 0},
+	{"TrueBooleanValue", `[Tt][Rr][Uu][Ee]\b`, booleanValue(true), 0},
+	{"FalseBooleanValue", `[Ff][Aa][Ll][Ss][Ee]\b`, booleanValue(false), 0},
 
-	{"NullValue", `[Nn][Uu][Ll][Ll]|~`, nullValue(), 0},
+	{"NullValue", `[Nn][Uu][Ll][Ll]\b|~`, nullValue(), 0},
 
 	{"QuotedStringValue", `"([^"\\]*(\\.[^"\\]*)*)"`, stringValue(), 0},
 
@@ -202,6 +236,20 @@ var participleYqRules = []*participleYqRule{
 	simpleOp("min", minOpType),
 	simpleOp("max", maxOpType),
 
+	{"Floor", `floor\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "floor", apply: math.Floor, integral: true}), 0},
+	{"Ceil", `ceil\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "ceil", apply: math.Ceil, integral: true}), 0},
+	{"Round", `round\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "round", apply: math.Round, integral: true}), 0},
+	{"Trunc", `trunc\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "trunc", apply: math.Trunc, integral: true}), 0},
+	{"Sqrt", `sqrt\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "sqrt", apply: math.Sqrt}), 0},
+	{"Log", `log\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "log", apply: math.Log}), 0},
+	{"Exp", `exp\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "exp", apply: math.Exp}), 0},
+	{"Significand", `significand\b`, opTokenWithPrefs(mathOpType, nil, mathPreferences{name: "significand", apply: significand}), 0},
+	{"Abs", `f?abs\b`, opToken(absOpType), 0},
+	{"Pow", `pow\b`, opToken(powOpType), 0},
+	{"IsNan", `isnan\b`, opToken(isNanOpType), 0},
+	{"Infinite", `infinite\b`, opToken(infiniteOpType), 0},
+	{"Nan", `nan\b`, opToken(nanOpType), 0},
+
 	{"AssignRelative", `\|=[c]*`, assignOpToken(true), 0},
 	{"Assign", `=[c]*`, assignOpToken(false), 0},
 
@@ -214,8 +262,8 @@ var participleYqRules = []*participleYqRule{
 
 	{"Union", `,`, opToken(unionOpType), 0},
 
-	{"MultiplyAssign", `\*=[\+|\?cdn]*`, multiplyWithPrefs(multiplyAssignOpType), 0},
-	{"Multiply", `\*[\+|\?cdn]*`, multiplyWithPrefs(multiplyOpType), 0},
+	{"MultiplyAssign", `\*=(?:[\+|\?cdn]|k\((?:[^()]|\([^()]*\))*\))*`, multiplyWithPrefs(multiplyAssignOpType), 0},
+	{"Multiply", `\*(?:[\+|\?cdn]|k\((?:[^()]|\([^()]*\))*\))*`, multiplyWithPrefs(multiplyOpType), 0},
 
 	{"Divide", `\/`, opToken(divideOpType), 0},
 
@@ -227,8 +275,21 @@ var participleYqRules = []*participleYqRule{
 	{"SubtractAssign", `\-=`, opToken(subtractAssignOpType), 0},
 	{"Subtract", `\-`, opToken(subtractOpType), 0},
 	{"Comment", `#.*`, nil, 0},
+	{"Optional", `\?`, opToken(optionalOpType), 0},
 
 	simpleOp("pivot", pivotOpType),
+
+	simpleOp("to_?stream", toStreamOpType),
+	simpleOp("from_?stream", fromStreamOpType),
+	simpleOp("truncate_?stream", truncateStreamOpType),
+
+	simpleOp("validate", validateOpType),
+	simpleOp("diff", diffOpType),
+	{"Patch", `patch`, opToken(patchOpType), 0},
+	{"MergePatch", `merge_?patch`, opToken(mergePatchOpType), 0},
+
+	// anything else that looks like a name is a call to a user defined function
+	{"Identifier", `[a-zA-Z_][a-zA-Z_0-9]*`, callFunctionToken(), 0},
 }
 
 type yqAction func(lexer.Token) (*token, error)
@@ -252,10 +313,18 @@ func assignableOp(name string, opType *operationType, assignOpType *operationTyp
 	return &participleYqRule{strings.ToUpper(string(name[1])) + name[1:], name, opTokenWithPrefs(opType, assignOpType, nil), 0}
 }
 
+// keywords must end on a word boundary, otherwise a function name like
+// `selected` would be read as `select` followed by `ed`.
+var keywordPattern = regexp.MustCompile(`^[A-Za-z0-9_?|@]+$`)
+
 func newParticipleLexer() expressionTokeniser {
 	simpleRules := make([]lexer.SimpleRule, len(participleYqRules))
 	for i, yqRule := range participleYqRules {
-		simpleRules[i] = lexer.SimpleRule{Name: yqRule.Name, Pattern: yqRule.Pattern}
+		pattern := yqRule.Pattern
+		if keywordPattern.MatchString(pattern) {
+			pattern = "(?:" + pattern + `)\b`
+		}
+		simpleRules[i] = lexer.SimpleRule{Name: yqRule.Name, Pattern: pattern}
 	}
 	lexerDefinition := lexer.MustSimple(simpleRules)
 	symbols := lexerDefinition.Symbols()
@@ -430,11 +499,22 @@ func envSubstWithOptions() yqAction {
 	}
 }
 
+var multiplyKeyRegex = regexp.MustCompile(`k\(((?:[^()]|\([^()]*\))*)\)`)
+
 func multiplyWithPrefs(op *operationType) yqAction {
 	return func(rawToken lexer.Token) (*token, error) {
 		prefs := multiplyPreferences{}
 		prefs.AssignPrefs = assignPreferences{}
 		options := rawToken.Value
+		if match := multiplyKeyRegex.FindStringSubmatch(options); match != nil {
+			keyExpression, err := ExpressionParser.ParseExpression(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("bad merge key expression %v: %w", match[1], err)
+			}
+			prefs.KeyExpression = keyExpression
+			// the key expression may contain flag characters
+			options = strings.Replace(options, match[0], "", 1)
+		}
 		if strings.Contains(options, "+") {
 			prefs.AppendArrays = true
 		}
@@ -451,7 +531,7 @@ func multiplyWithPrefs(op *operationType) yqAction {
 			prefs.AssignPrefs.ClobberCustomTags = true
 		}
 		prefs.TraversePrefs.DontFollowAlias = true
-		op := &Operation{OperationType: op, Value: multiplyOpType.Type, StringValue: options, Preferences: prefs}
+		op := &Operation{OperationType: op, Value: multiplyOpType.Type, StringValue: rawToken.Value, Preferences: prefs}
 		return &token{TokenType: operationToken, Operation: op}, nil
 	}
 
@@ -470,6 +550,35 @@ func getVariableOpToken() yqAction {
 	}
 }
 
+func defineFunctionToken() yqAction {
+	return func(rawToken lexer.Token) (*token, error) {
+		name := strings.TrimSpace(strings.TrimPrefix(rawToken.Value, "def"))
+		op := &Operation{OperationType: defineFunctionOpType, Value: defineFunctionOpType.Type, StringValue: name,
+			Preferences: functionDefinitionPreferences{Name: name}}
+		return &token{TokenType: operationToken, Operation: op}, nil
+	}
+}
+
+var importRegex = regexp.MustCompile(`^(?:import|include)\s+"([^"]*)"(?:\s+as\s+([a-zA-Z_][a-zA-Z_0-9]*))?`)
+
+func importToken() yqAction {
+	return func(rawToken lexer.Token) (*token, error) {
+		matches := importRegex.FindStringSubmatch(rawToken.Value)
+		prefs := importPreferences{Path: matches[1], Namespace: matches[2]}
+		op := &Operation{OperationType: importOpType, Value: importOpType.Type, StringValue: rawToken.Value, Preferences: prefs}
+		return &token{TokenType: operationToken, Operation: op}, nil
+	}
+}
+
+func callFunctionToken() yqAction {
+	return func(rawToken lexer.Token) (*token, error) {
+		name := rawToken.Value
+		op := &Operation{OperationType: callFunctionOpType, Value: callFunctionOpType.Type, StringValue: name,
+			Preferences: functionCallPreferences{Name: name}}
+		return &token{TokenType: operationToken, Operation: op, CheckForPostTraverse: true}, nil
+	}
+}
+
 func hexValue() yqAction {
 	return func(rawToken lexer.Token) (*token, error) {
 		var originalString = rawToken.Value
@@ -583,12 +692,21 @@ func (p *participleLexer) Tokenise(expression string) ([]*token, error) {
 		return nil, err
 	}
 	tokens := make([]*token, 0)
+	names := make(map[*token]string)
 
 	for {
 		rawToken, e := myLexer.Next()
 		if e != nil {
 			return nil, e
 		} else if rawToken.Type == lexer.EOF {
+			tokens, err = expandConditionals(tokens)
+			if err != nil {
+				return nil, err
+			}
+			tokens, err = expandFunctions(tokens, names)
+			if err != nil {
+				return nil, err
+			}
 			return postProcessTokens(tokens), nil
 		}
 
@@ -598,6 +716,10 @@ func (p *participleLexer) Tokenise(expression string) ([]*token, error) {
 			if e != nil {
 				return nil, e
 			}
+			if identifierRegex.MatchString(rawToken.Value) {
+				// kept so a def can shadow the builtin
+				names[token] = rawToken.Value
+			}
 			tokens = append(tokens, token)
 		}
 
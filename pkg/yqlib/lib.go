@@ -5,6 +5,7 @@ import (
 	"container/list"
 	"fmt"
 	"math"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -175,6 +176,38 @@ func parseInt(numberString string) (int, error) {
 	return int(parsed), err
 }
 
+var simplePathKeyRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// expressionPath returns the path in yq syntax, e.g. .spec.containers[0].image, quoting
+// the keys that need it like .["x.y"]
+func expressionPath(path []interface{}) string {
+	if len(path) == 0 {
+		return "."
+	}
+	var sb strings.Builder
+	for index, element := range path {
+		switch element := element.(type) {
+		case int:
+			if index == 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(fmt.Sprintf("[%v]", element))
+		default:
+			elementStr := fmt.Sprintf("%v", element)
+			if simplePathKeyRegex.MatchString(elementStr) {
+				sb.WriteString("." + elementStr)
+			} else {
+				if index == 0 {
+					sb.WriteString(".")
+				}
+				escaped := strings.ReplaceAll(strings.ReplaceAll(elementStr, `\`, `\\`), `"`, `\"`)
+				sb.WriteString(`["` + escaped + `"]`)
+			}
+		}
+	}
+	return sb.String()
+}
+
 func headAndLineComment(node *CandidateNode) string {
 	return headComment(node) + lineComment(node)
 }
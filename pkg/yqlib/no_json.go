@@ -6,6 +6,30 @@ func NewJSONDecoder() Decoder {
 	return nil
 }
 
+func NewJSONDecoderWithPreferences(_ JsonPreferences) Decoder {
+	return nil
+}
+
 func NewJSONEncoder(indent int, colorise bool, unwrapScalar bool) Encoder {
 	return nil
 }
+
+func NewJSONStreamDecoder() Decoder {
+	return nil
+}
+
+func NewJSON5Decoder(_ JsonPreferences) Decoder {
+	return nil
+}
+
+func NewJSON5Encoder(_ JsonPreferences) Encoder {
+	return nil
+}
+
+func NewJSONCEncoder(_ JsonPreferences) Encoder {
+	return nil
+}
+
+func NewNDJSONDecoder(_ JsonPreferences) Decoder {
+	return nil
+}
@@ -9,3 +9,7 @@ func NewXMLDecoder(prefs XmlPreferences) Decoder {
 func NewXMLEncoder(indent int, prefs XmlPreferences) Encoder {
 	return nil
 }
+
+func NewXMLStreamDecoder(prefs XmlPreferences) Decoder {
+	return nil
+}
@@ -106,6 +106,8 @@ var toUnixOpType = &operationType{Type: "TO_UNIX", NumArgs: 0, Precedence: 50, H
 
 var encodeOpType = &operationType{Type: "ENCODE", NumArgs: 0, Precedence: 50, Handler: encodeOperator}
 var decodeOpType = &operationType{Type: "DECODE", NumArgs: 0, Precedence: 50, Handler: decodeOperator}
+var hashOpType = &operationType{Type: "HASH", NumArgs: 0, Precedence: 50, Handler: hashOperator}
+var hashNodeOpType = &operationType{Type: "HASH_NODE", NumArgs: 1, Precedence: 50, Handler: hashNodeOperator}
 
 var anyOpType = &operationType{Type: "ANY", NumArgs: 0, Precedence: 50, Handler: anyOperator}
 var allOpType = &operationType{Type: "ALL", NumArgs: 0, Precedence: 50, Handler: allOperator}
@@ -117,6 +119,28 @@ var toEntriesOpType = &operationType{Type: "TO_ENTRIES", NumArgs: 0, Precedence:
 var fromEntriesOpType = &operationType{Type: "FROM_ENTRIES", NumArgs: 0, Precedence: 50, Handler: fromEntriesOperator}
 var withEntriesOpType = &operationType{Type: "WITH_ENTRIES", NumArgs: 1, Precedence: 50, Handler: withEntriesOperator}
 
+var tryOpType = &operationType{Type: "TRY", NumArgs: 1, Precedence: 44, Handler: tryOperator}
+var catchOpType = &operationType{Type: "CATCH", NumArgs: 2, Precedence: 43, Handler: catchOperator}
+
+// postfix `?`, same as `try` without a `catch`
+var optionalOpType = &operationType{Type: "OPTIONAL", NumArgs: 1, Precedence: 44, Handler: tryOperator, CheckForPostTraverse: true}
+
+var conditionalOpType = &operationType{Type: "CONDITIONAL", NumArgs: 1, Precedence: 50, Handler: conditionalOperator}
+
+var defineFunctionOpType = &operationType{Type: "DEF", NumArgs: 1, Precedence: 50, Handler: defineFunctionOperator}
+var callFunctionOpType = &operationType{Type: "CALL_FUNCTION", NumArgs: 0, Precedence: 50, Handler: callFunctionOperator, CheckForPostTraverse: true}
+var toStreamOpType = &operationType{Type: "TO_STREAM", NumArgs: 0, Precedence: 50, Handler: toStreamOperator}
+var fromStreamOpType = &operationType{Type: "FROM_STREAM", NumArgs: 1, Precedence: 50, Handler: fromStreamOperator}
+var truncateStreamOpType = &operationType{Type: "TRUNCATE_STREAM", NumArgs: 1, Precedence: 50, Handler: truncateStreamOperator}
+
+var validateOpType = &operationType{Type: "VALIDATE", NumArgs: 1, Precedence: 50, Handler: validateOperator}
+var diffOpType = &operationType{Type: "DIFF", NumArgs: 1, Precedence: 50, Handler: diffOperator}
+var patchOpType = &operationType{Type: "PATCH", NumArgs: 1, Precedence: 50, Handler: patchOperator}
+var mergePatchOpType = &operationType{Type: "MERGE_PATCH", NumArgs: 1, Precedence: 50, Handler: mergePatchOperator}
+
+var importOpType = &operationType{Type: "IMPORT", NumArgs: 1, Precedence: 50, Handler: importOperator}
+var callFunctionWithArgsOpType = &operationType{Type: "CALL_FUNCTION", NumArgs: 1, Precedence: 52, Handler: callFunctionOperator, CheckForPostTraverse: true}
+
 var withOpType = &operationType{Type: "WITH", NumArgs: 1, Precedence: 52, Handler: withOperator, CheckForPostTraverse: true}
 
 var splitDocumentOpType = &operationType{Type: "SPLIT_DOC", NumArgs: 0, Precedence: 52, Handler: splitDocumentOperator, CheckForPostTraverse: true}
@@ -156,6 +180,15 @@ var testOpType = &operationType{Type: "TEST", NumArgs: 1, Precedence: 50, Handle
 var splitStringOpType = &operationType{Type: "SPLIT", NumArgs: 1, Precedence: 52, Handler: splitStringOperator, CheckForPostTraverse: true}
 var changeCaseOpType = &operationType{Type: "CHANGE_CASE", NumArgs: 0, Precedence: 50, Handler: changeCaseOperator}
 var trimOpType = &operationType{Type: "TRIM", NumArgs: 0, Precedence: 50, Handler: trimSpaceOperator}
+var startsWithOpType = &operationType{Type: "STARTS_WITH", NumArgs: 1, Precedence: 50, Handler: startsWithOperator}
+var endsWithOpType = &operationType{Type: "ENDS_WITH", NumArgs: 1, Precedence: 50, Handler: endsWithOperator}
+var trimPrefixOpType = &operationType{Type: "TRIM_PREFIX", NumArgs: 1, Precedence: 50, Handler: trimPrefixOperator}
+var trimSuffixOpType = &operationType{Type: "TRIM_SUFFIX", NumArgs: 1, Precedence: 50, Handler: trimSuffixOperator}
+var indexOpType = &operationType{Type: "INDEX", NumArgs: 1, Precedence: 50, Handler: indexOperator}
+var explodeStringOpType = &operationType{Type: "EXPLODE_STRING", NumArgs: 0, Precedence: 50, Handler: explodeStringOperator}
+var implodeOpType = &operationType{Type: "IMPLODE", NumArgs: 0, Precedence: 50, Handler: implodeOperator}
+var asciiOpType = &operationType{Type: "ASCII", NumArgs: 0, Precedence: 50, Handler: asciiOperator}
+var splitsOpType = &operationType{Type: "SPLITS", NumArgs: 1, Precedence: 50, Handler: splitsOperator}
 var toStringOpType = &operationType{Type: "TO_STRING", NumArgs: 0, Precedence: 50, Handler: toStringOperator}
 var stringInterpolationOpType = &operationType{Type: "STRING_INT", NumArgs: 0, Precedence: 50, Handler: stringInterpolationOperator, ToString: valueToStringFunc}
 
@@ -179,6 +212,12 @@ var referenceOpType = &operationType{Type: "REF", NumArgs: 0, Precedence: 50, Ha
 var envOpType = &operationType{Type: "ENV", NumArgs: 0, Precedence: 52, Handler: envOperator, CheckForPostTraverse: true}
 var notOpType = &operationType{Type: "NOT", NumArgs: 0, Precedence: 50, Handler: notOperator}
 var toNumberOpType = &operationType{Type: "TO_NUMBER", NumArgs: 0, Precedence: 50, Handler: toNumberOperator}
+var mathOpType = &operationType{Type: "MATH", NumArgs: 0, Precedence: 50, Handler: mathOperator}
+var absOpType = &operationType{Type: "ABS", NumArgs: 0, Precedence: 50, Handler: absOperator}
+var powOpType = &operationType{Type: "POW", NumArgs: 1, Precedence: 50, Handler: powOperator}
+var isNanOpType = &operationType{Type: "IS_NAN", NumArgs: 0, Precedence: 50, Handler: isNanOperator}
+var infiniteOpType = &operationType{Type: "INFINITE", NumArgs: 0, Precedence: 50, Handler: infiniteOperator}
+var nanOpType = &operationType{Type: "NAN", NumArgs: 0, Precedence: 50, Handler: nanOperator}
 var emptyOpType = &operationType{Type: "EMPTY", Precedence: 50, Handler: emptyOperator}
 
 var envsubstOpType = &operationType{Type: "ENVSUBST", NumArgs: 0, Precedence: 50, Handler: envsubstOperator}
@@ -0,0 +1,44 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+func conditionalOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("conditionalOperator")
+	// the lexer expands if/then/else into CONDITIONAL (condition; (then; else))
+	if expressionNode.RHS == nil || expressionNode.RHS.Operation.OperationType != blockOpType ||
+		expressionNode.RHS.RHS == nil || expressionNode.RHS.RHS.Operation.OperationType != blockOpType {
+		return Context{}, fmt.Errorf("if must be given a condition, a then branch and an else branch")
+	}
+
+	conditionExp := expressionNode.RHS.LHS
+	thenExp := expressionNode.RHS.RHS.LHS
+	elseExp := expressionNode.RHS.RHS.RHS
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		conditions, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), conditionExp)
+		if err != nil {
+			return Context{}, err
+		}
+
+		// like jq, each result of the condition produces its own branch result
+		for conditionEl := conditions.MatchingNodes.Front(); conditionEl != nil; conditionEl = conditionEl.Next() {
+			branchExp := elseExp
+			if isTruthyNode(conditionEl.Value.(*CandidateNode)) {
+				branchExp = thenExp
+			}
+			branchResults, err := d.GetMatchingNodes(context.SingleChildContext(candidate), branchExp)
+			if err != nil {
+				return Context{}, err
+			}
+			results.PushBackList(branchResults.MatchingNodes)
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
@@ -0,0 +1,92 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var conditionalOperatorScenarios = []expressionScenario{
+	{
+		description: "Basic if then else",
+		document:    `[1, 2, 3]`,
+		expression:  `.[] |= if . > 1 then "big" else "small" end`,
+		expected: []string{
+			"D0, P[], (!!seq)::[small, big, big]\n",
+		},
+	},
+	{
+		description:    "Multiple branches with elif",
+		subdescription: "Useful when there are more than two cases",
+		document:       `[{name: cat, legs: 4}, {name: bird, legs: 2}, {name: fish, legs: 0}]`,
+		expression:     `map(if .legs == 4 then "walks" elif .legs == 2 then "flies" else "swims" end)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[walks, flies, swims]\n",
+		},
+	},
+	{
+		description:    "Without an else branch",
+		subdescription: "Like jq, a missing else branch returns the input unchanged",
+		document:       `[1, null, 3]`,
+		expression:     `map(if . == null then 0 end)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[1, 0, 3]\n",
+		},
+	},
+	{
+		description:    "Falsy values",
+		subdescription: "Only false and null are falsy, unlike the `//` operator there is no need to worry about other values",
+		document:       `[false, null, 0, ""]`,
+		expression:     `map(if . then "truthy" else "falsy" end)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[falsy, falsy, truthy, truthy]\n",
+		},
+	},
+	{
+		description: "Update with conditional assignment",
+		document:    `{a: {env: prod, replicas: 1}, b: {env: dev, replicas: 1}}`,
+		expression:  `.[] |= (.replicas = if .env == "prod" then 3 else 1 end)`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: {env: prod, replicas: 3}, b: {env: dev, replicas: 1}}\n",
+		},
+	},
+	{
+		description: "Conditionals in with_entries",
+		document:    `{a: 1, b: null}`,
+		expression:  `with_entries(.value |= if . == null then "unset" else . end)`,
+		expected: []string{
+			"D0, P[], (!!map)::a: 1\nb: unset\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `a: 1`,
+		expression: `if true then if .a == 1 then "inner" else "other" end else "outer" end`,
+		expected: []string{
+			"D0, P[], (!!str)::inner\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "each condition result produces a branch result",
+		document:    `a: 1`,
+		expression:  `if (true, false) then "yes" else "no" end`,
+		expected: []string{
+			"D0, P[], (!!str)::yes\n",
+			"D0, P[], (!!str)::no\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: {end: 1, if: 2}}`,
+		expression: `.a | (if .if == 2 then .end else 0 end) + 1`,
+		expected: []string{
+			"D0, P[a end], (!!int)::2\n",
+		},
+	},
+}
+
+func TestConditionalOperatorScenarios(t *testing.T) {
+	for _, tt := range conditionalOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "conditional", conditionalOperatorScenarios)
+}
@@ -0,0 +1,45 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// diff(other) and diff(other; key) return the JSON Patch that turns the node into other,
+// matching the maps in sequences by their key when one is given.
+func diffOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("diffOperator")
+
+	otherExpression := expressionNode.RHS
+	var keyExpression *ExpressionNode
+	if expressionNode.RHS.Operation.OperationType == blockOpType {
+		otherExpression = expressionNode.RHS.LHS
+		keyExpression = expressionNode.RHS.RHS
+	}
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+
+		prefs := DiffPreferences{}
+		if keyExpression != nil {
+			keys, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), keyExpression)
+			if err != nil {
+				return Context{}, err
+			}
+			if keys.MatchingNodes.Len() != 1 || keys.MatchingNodes.Front().Value.(*CandidateNode).Kind != ScalarNode {
+				return Context{}, fmt.Errorf("diff key must be a single string, e.g. diff($other; \"name\")")
+			}
+			prefs.Key = keys.MatchingNodes.Front().Value.(*CandidateNode).Value
+		}
+
+		others, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), otherExpression)
+		if err != nil {
+			return Context{}, err
+		}
+		for otherEl := others.MatchingNodes.Front(); otherEl != nil; otherEl = otherEl.Next() {
+			results.PushBack(DiffToPatch(Diff(candidate, otherEl.Value.(*CandidateNode), prefs)))
+		}
+	}
+	return context.ChildContext(results), nil
+}
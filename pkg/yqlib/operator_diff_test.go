@@ -0,0 +1,100 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var diffOperatorScenarios = []expressionScenario{
+	{
+		description: "Diff against another value",
+		document:    "a: cat\nb: {c: 1, d: 2}\n",
+		expression:  `diff({"a": "dog", "b": {"c": 1, "e": 3}})`,
+		expected: []string{
+			"D0, P[], (!!seq)::- op: replace\n  path: /a\n  value: dog\n- op: remove\n  path: /b/d\n- op: add\n  path: /b/e\n  value: 3\n",
+		},
+	},
+	{
+		description:    "Diff sequences by index",
+		subdescription: "Items are compared with the item at the same index, extra items are removed from the end",
+		document:       "[a, b, c]",
+		expression:     `diff(["a", "x"])`,
+		expected: []string{
+			"D0, P[], (!!seq)::- op: replace\n  path: /1\n  value: x\n- op: remove\n  path: /2\n",
+		},
+	},
+	{
+		description:    "Diff sequences by key",
+		subdescription: "Match the maps in a sequence by a key, like Kubernetes lists are, so inserting an item does not change every item after it",
+		document:       "[{name: web, image: nginx}, {name: db, image: postgres}]",
+		expression:     `diff([{"name": "init", "image": "busybox"}, {"name": "web", "image": "nginx:1.25"}]; "name")`,
+		expected: []string{
+			"D0, P[], (!!seq)::- op: remove\n  path: /1\n- op: add\n  path: /0\n  value:\n    name: init\n    image: busybox\n- op: replace\n  path: /1/image\n  value: nginx:1.25\n",
+		},
+	},
+	{
+		description: "Check if two documents are the same",
+		document:    "{a: [1, 2], b: {c: cat}}",
+		expression:  `diff({"b": {"c": "cat"}, "a": [1, 2]}) | length == 0`,
+		expected: []string{
+			"D0, P[], (!!bool)::true\n",
+		},
+	},
+	{
+		description:    "Show the changed paths",
+		subdescription: "The paths are JSON Pointers (RFC 6901)",
+		document:       "{a: {x/y: 1, b: [1, 2]}}",
+		expression:     `diff({"a": {"x/y": 2, "b": [1]}}) | .[] | .op + " " + .path`,
+		expected: []string{
+			"D0, P[0 op], (!!str)::replace /a/x~1y\n",
+			"D0, P[1 op], (!!str)::remove /a/b/1\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "reordered keyed items are replaced",
+		document:    "[{name: a}, {name: b}]",
+		expression:  `diff([{"name": "b"}, {"name": "a"}]; "name") | .[] | .op + " " + .path`,
+		expected: []string{
+			"D0, P[0 op], (!!str)::replace \n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "items without the key are matched by index",
+		document:    "[{name: a}, cat]",
+		expression:  `diff([{"name": "b"}, "cat"]; "name") | .[] | .op + " " + .path`,
+		expected: []string{
+			"D0, P[0 op], (!!str)::replace /0/name\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1, b: &x {c: 1}, d: *x}",
+		expression: `diff({"a": 1, "b": {"c": 1}, "d": {"c": 2}}) | .[] | .op + " " + .path`,
+		expected: []string{
+			"D0, P[0 op], (!!str)::replace /d/c\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1, b: cat}",
+		expression: `diff({"a": "1", "b": ["cat"]}) | .[] | .op + " " + .path`,
+		expected: []string{
+			"D0, P[0 op], (!!str)::replace /a\n",
+			"D0, P[1 op], (!!str)::replace /b\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      "a: 1",
+		expression:    `diff({}; ["name"])`,
+		expectedError: "diff key must be a single string, e.g. diff($other; \"name\")",
+	},
+}
+
+func TestDiffOperatorScenarios(t *testing.T) {
+	for _, tt := range diffOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "diff", diffOperatorScenarios)
+}
@@ -4,7 +4,15 @@ import (
 	"bufio"
 	"bytes"
 	"container/list"
+	"crypto/md5"  // #nosec
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"regexp"
 	"strings"
 )
@@ -130,3 +138,103 @@ func decodeOperator(_ *dataTreeNavigator, context Context, expressionNode *Expre
 	}
 	return context.ChildContext(results), nil
 }
+
+type hashPreferences struct {
+	algorithm string
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil // #nosec
+	case "sha1":
+		return sha1.New(), nil // #nosec
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "crc32":
+		return crc32.NewIEEE(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm '%v', use one of md5, sha1, sha256, sha512 or crc32", algorithm)
+}
+
+func hashToHex(algorithm string, value string) (string, error) {
+	hasher, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	hasher.Write([]byte(value))
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+/* hashes the value of a scalar, e.g. @sha256 */
+func hashOperator(_ *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	preferences := expressionNode.Operation.Preferences.(hashPreferences)
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		if candidate.Kind != ScalarNode {
+			return Context{}, fmt.Errorf("cannot @%v %v, can only operate on scalars - use hash(\"%v\") for maps and arrays", preferences.algorithm, candidate.Tag, preferences.algorithm)
+		}
+		digest, err := hashToHex(preferences.algorithm, candidate.Value)
+		if err != nil {
+			return Context{}, err
+		}
+		results.PushBack(candidate.CreateReplacement(ScalarNode, "!!str", digest))
+	}
+	return context.ChildContext(results), nil
+}
+
+// canonicalCopy copies the node with its keys sorted and its comments removed, so
+// that only a change in the content itself changes its encoding.
+func canonicalCopy(node *CandidateNode) *CandidateNode {
+	canonical := node.Copy()
+	var canonicalise func(*CandidateNode)
+	canonicalise = func(n *CandidateNode) {
+		n.HeadComment = ""
+		n.LineComment = ""
+		n.FootComment = ""
+		if n.Kind == MappingNode {
+			sortKeys(n)
+		}
+		for _, child := range n.Content {
+			canonicalise(child)
+		}
+	}
+	canonicalise(canonical)
+	return canonical
+}
+
+/* hashes the canonical json encoding of the whole node, e.g. hash("sha256") */
+func hashNodeOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		algorithms, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+
+		encoded, err := encodeToString(canonicalCopy(candidate), encoderPreferences{format: JSONFormat, indent: 0})
+		if err != nil {
+			return Context{}, err
+		}
+		encoded = chomper.ReplaceAllString(encoded, "")
+
+		for algorithmEl := algorithms.MatchingNodes.Front(); algorithmEl != nil; algorithmEl = algorithmEl.Next() {
+			algorithm := algorithmEl.Value.(*CandidateNode)
+			if algorithm.guessTagFromCustomType() != "!!str" {
+				return Context{}, fmt.Errorf("hash needs the name of an algorithm, got %v", algorithm.Tag)
+			}
+			digest, err := hashToHex(algorithm.Value, encoded)
+			if err != nil {
+				return Context{}, err
+			}
+			results.PushBack(candidate.CreateReplacement(ScalarNode, "!!str", digest))
+		}
+	}
+	return context.ChildContext(results), nil
+}
@@ -39,6 +39,25 @@ var encoderDecoderOperatorScenarios = []expressionScenario{
 `,
 		},
 	},
+	{
+		requiresFormat: "json",
+		description:    "Encode NaN and infinity as json",
+		subdescription: "JSON has no NaN or infinity, so like jq, NaN is null and infinity is the largest float.",
+		document:       `[.nan, .inf, -.Inf]`,
+		expression:     `to_json(0)`,
+		expected: []string{
+			"D0, P[], (!!str)::[null,1.7976931348623157e+308,-1.7976931348623157e+308]\n",
+		},
+	},
+	{
+		requiresFormat: "json",
+		skipDoc:        true,
+		description:    "Encode nan as json",
+		expression:     `nan | tojson`,
+		expected: []string{
+			"D0, P[], (!!str)::null\n\n",
+		},
+	},
 	{
 		requiresFormat: "json",
 		description:    "Decode a json encoded string",
@@ -326,6 +345,81 @@ var encoderDecoderOperatorScenarios = []expressionScenario{
 			"D0, P[], (!!str)::cats\n",
 		},
 	},
+	{
+		description: "Hash a string",
+		document:    "password: hello",
+		expression:  ".password | @sha256",
+		expected: []string{
+			"D0, P[password], (!!str)::2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\n",
+		},
+	},
+	{
+		description:    "Checksum a string",
+		subdescription: "@md5, @sha1, @sha512 and @crc32 work the same way.",
+		document:       "[hello, hello]",
+		expression:     "[(.[0] | @md5), (.[1] | @crc32)]",
+		expected: []string{
+			"D0, P[], (!!seq)::- 5d41402abc4b2a76b9719d911017c592\n- 3610a686\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "[hello, 8080]",
+		expression: "[.[] | @sha1]",
+		expected: []string{
+			"D0, P[], (!!seq)::- aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d\n- 5e3274dc1e352e75268d4e2aa2b793eaf2765289\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `"hello" | @sha512`,
+		expected: []string{
+			"D0, P[], (!!str)::9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      "a: {b: c}",
+		expression:    ".a | @sha256",
+		expectedError: `cannot @sha256 !!map, can only operate on scalars - use hash("sha256") for maps and arrays`,
+	},
+	{
+		description:    "Hash a whole subtree",
+		subdescription: "Hashes the canonical json encoding of the node: keys are sorted, and comments and formatting are ignored. This is handy for Helm style `checksum/config` annotations.",
+		document:       "config:\n  b: [1, 2] # a comment\n  a: {x: s}\nannotations: {}\n",
+		expression:     `.annotations["checksum/config"] = (.config | hash("sha256"))`,
+		expected: []string{
+			"D0, P[], (!!map)::config:\n    b: [1, 2] # a comment\n    a: {x: s}\nannotations:\n    checksum/config: 3c185adda69c05c07bf1df6ea39c79b307487ddff1bd92c642a9735078a96b66\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Order, comments and styles do not change the hash",
+		document:    "config:\n  a:\n    x: \"s\"\n  b:\n    - 1\n    - 2\n",
+		expression:  `.config | hash("sha256")`,
+		expected: []string{
+			"D0, P[config], (!!str)::3c185adda69c05c07bf1df6ea39c79b307487ddff1bd92c642a9735078a96b66\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Hashing does not change the node",
+		document:    "{b: 1, a: 2} # hi",
+		expression:  `hash("md5") as $h | .`,
+		expected: []string{
+			"D0, P[], (!!map)::{b: 1, a: 2} # hi\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		expression:    `hash("sha3")`,
+		expectedError: "unknown hash algorithm 'sha3', use one of md5, sha1, sha256, sha512 or crc32",
+	},
+	{
+		skipDoc:       true,
+		expression:    `hash(1)`,
+		expectedError: "hash needs the name of an algorithm, got !!int",
+	},
 	{
 		requiresFormat: "xml",
 		description:    "empty xml decode",
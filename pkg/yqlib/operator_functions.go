@@ -0,0 +1,211 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+type functionDefinition struct {
+	name string
+	// value parameters start with a '$', everything else is a filter (closure) parameter
+	params []string
+	body   *ExpressionNode
+	// the functions and variables visible where the function was defined
+	scope Context
+}
+
+type functionDefinitionPreferences struct {
+	Name   string
+	Params []string
+}
+
+type functionCallPreferences struct {
+	Name  string
+	Arity int
+}
+
+// builtinFunction is a function written in go rather than with def. It passes on its
+// results one at a time, so that generators like limit can stop it early.
+type builtinFunction func(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error)
+
+// getBuiltinFunction finds the builtin with the given name and arity. These are only
+// used when there is no function defined with the same name and arity.
+func getBuiltinFunction(name string, arity int) builtinFunction {
+	switch functionKey(name, arity) {
+	case "limit/2":
+		return limitFunction
+	case "first/0", "first/1":
+		return firstFunction
+	case "last/0", "last/1":
+		return lastFunction
+	case "nth/1", "nth/2":
+		return nthFunction
+	case "range/1", "range/2", "range/3":
+		return rangeFunction
+	case "until/2":
+		return loopFunction(true, false)
+	case "while/2", "repeat/1":
+		return loopFunction(true, true)
+	case "recurse/0", "recurse/1", "recurse/2":
+		return recurseFunction
+	case "walk/1":
+		return walkFunction
+	}
+	return nil
+}
+
+func functionKey(name string, arity int) string {
+	return fmt.Sprintf("%v/%v", name, arity)
+}
+
+func defineFunctionOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(functionDefinitionPreferences)
+	log.Debugf("defineFunctionOperator %v", functionKey(prefs.Name, len(prefs.Params)))
+
+	// the lexer expands `def f: body; rest` into DEF (body; rest)
+	if expressionNode.RHS == nil || expressionNode.RHS.Operation.OperationType != blockOpType {
+		return Context{}, fmt.Errorf("def must be given a body and an expression to use it in")
+	}
+
+	definition := &functionDefinition{
+		name:   prefs.Name,
+		params: prefs.Params,
+		body:   expressionNode.RHS.LHS,
+	}
+	// lexical scope, including the function itself so it can recurse
+	definition.scope = context.ChildContext(list.New())
+	definition.scope.SetFunction(definition)
+
+	restContext := context.Clone()
+	restContext.SetFunction(definition)
+
+	result, err := d.GetMatchingNodes(restContext, expressionNode.RHS.RHS)
+	if err != nil {
+		return Context{}, err
+	}
+	return context.ChildContext(result.MatchingNodes), nil
+}
+
+func getFunctionArguments(argsNode *ExpressionNode, arity int) []*ExpressionNode {
+	args := make([]*ExpressionNode, 0, arity)
+	for i := 0; i < arity-1; i++ {
+		args = append(args, argsNode.LHS)
+		argsNode = argsNode.RHS
+	}
+	if arity > 0 {
+		args = append(args, argsNode)
+	}
+	return args
+}
+
+func callFunctionOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(functionCallPreferences)
+	log.Debugf("callFunctionOperator %v", functionKey(prefs.Name, prefs.Arity))
+
+	definition := context.GetFunction(prefs.Name, prefs.Arity)
+	args := getFunctionArguments(expressionNode.RHS, prefs.Arity)
+	if definition == nil {
+		builtin := getBuiltinFunction(prefs.Name, prefs.Arity)
+		if builtin == nil {
+			return Context{}, fmt.Errorf("%v is not defined", functionKey(prefs.Name, prefs.Arity))
+		}
+		results := list.New()
+		_, err := builtin(d, context, args, func(candidate *CandidateNode) (bool, error) {
+			results.PushBack(candidate)
+			return true, nil
+		})
+		if err != nil {
+			return Context{}, err
+		}
+		return context.ChildContext(results), nil
+	}
+
+	hasValueParams := false
+	for _, param := range definition.params {
+		hasValueParams = hasValueParams || strings.HasPrefix(param, "$")
+	}
+
+	if !hasValueParams {
+		results, err := callFunction(d, context, definition, args)
+		if err != nil {
+			return Context{}, err
+		}
+		return context.ChildContext(results), nil
+	}
+
+	// like variables, value parameters are evaluated against each node
+	// unless they are all to be evaluated together.
+	var evaluateAllTogether = true
+	for el := context.MatchingNodes.Front(); el != nil && evaluateAllTogether; el = el.Next() {
+		evaluateAllTogether = el.Value.(*CandidateNode).EvaluateTogether
+	}
+	if evaluateAllTogether {
+		results, err := callFunction(d, context, definition, args)
+		if err != nil {
+			return Context{}, err
+		}
+		return context.ChildContext(results), nil
+	}
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		result, err := callFunction(d, context.SingleChildContext(el.Value.(*CandidateNode)), definition, args)
+		if err != nil {
+			return Context{}, err
+		}
+		results.PushBackList(result)
+	}
+	return context.ChildContext(results), nil
+}
+
+func callFunction(d *dataTreeNavigator, callerContext Context, definition *functionDefinition, args []*ExpressionNode) (*list.List, error) {
+	bodyContext := definition.scope.ChildContext(callerContext.MatchingNodes)
+	bodyContext.DontAutoCreate = callerContext.DontAutoCreate
+	bodyContext.datetimeLayout = callerContext.datetimeLayout
+
+	// arguments are closures, evaluated in the scope of the caller
+	for i, param := range definition.params {
+		bodyContext.SetFunction(&functionDefinition{
+			name:  strings.TrimPrefix(param, "$"),
+			body:  args[i],
+			scope: callerContext,
+		})
+	}
+	return bindValueParameters(d, callerContext, bodyContext, definition, args, 0)
+}
+
+// bindValueParameters sets a variable for each value parameter, `def f($a): body` is the same as
+// `def f(a): a as $a | body`, so the body is evaluated once for each value of each argument.
+func bindValueParameters(d *dataTreeNavigator, callerContext Context, bodyContext Context, definition *functionDefinition, args []*ExpressionNode, paramIndex int) (*list.List, error) {
+	for paramIndex < len(definition.params) && !strings.HasPrefix(definition.params[paramIndex], "$") {
+		paramIndex++
+	}
+	if paramIndex == len(definition.params) {
+		result, err := d.GetMatchingNodes(bodyContext, definition.body)
+		if err != nil {
+			return nil, err
+		}
+		return result.MatchingNodes, nil
+	}
+
+	values, err := d.GetMatchingNodes(callerContext.ReadOnlyClone(), args[paramIndex])
+	if err != nil {
+		return nil, err
+	}
+
+	results := list.New()
+	for el := values.MatchingNodes.Front(); el != nil; el = el.Next() {
+		variableValue := list.New()
+		variableValue.PushBack(el.Value.(*CandidateNode).Copy())
+		valueContext := bodyContext.Clone()
+		valueContext.SetVariable(strings.TrimPrefix(definition.params[paramIndex], "$"), variableValue)
+
+		result, err := bindValueParameters(d, callerContext, valueContext, definition, args, paramIndex+1)
+		if err != nil {
+			return nil, err
+		}
+		results.PushBackList(result)
+	}
+	return results, nil
+}
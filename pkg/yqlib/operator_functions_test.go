@@ -0,0 +1,198 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var functionOperatorScenarios = []expressionScenario{
+	{
+		description: "Define a function",
+		document:    `{a: {name: cat}, b: {name: dog}}`,
+		expression:  `def name_of: .name; [.a, .b] | map(name_of)`,
+		expected: []string{
+			"D0, P[], (!!seq)::- cat\n- dog\n",
+		},
+	},
+	{
+		description:    "Function with value parameters",
+		subdescription: "Parameters starting with `$` are evaluated against the input and bound as variables",
+		document:       `[1, 2, 3]`,
+		expression:     `def add($n): . + $n; map(add(10))`,
+		expected: []string{
+			"D0, P[], (!!seq)::[11, 12, 13]\n",
+		},
+	},
+	{
+		description:    "Function with filter parameters",
+		subdescription: "Parameters without a `$` are expressions that are evaluated each time they are used, in the scope of the caller",
+		document:       `{a: 3}`,
+		expression:     `def twice(f): f | f; .a | twice(. * 2)`,
+		expected: []string{
+			"D0, P[a], (!!int)::12\n",
+		},
+	},
+	{
+		description:    "Multiple parameters",
+		subdescription: "Separate parameters with `;`",
+		document:       `{a: 1, b: 2}`,
+		expression:     `def sum($x; $y): $x + $y; sum(.a; .b)`,
+		expected: []string{
+			"D0, P[a], (!!int)::3\n",
+		},
+	},
+	{
+		description:    "Recursive functions",
+		subdescription: "A function can call itself",
+		document:       `5`,
+		expression:     `def fact: if . <= 1 then 1 else . * (. - 1 | fact) end; fact`,
+		expected: []string{
+			"D0, P[], (!!int)::120\n",
+		},
+	},
+	{
+		description:    "Functions are lexically scoped",
+		subdescription: "A function uses the definitions that were visible where it was defined, not where it is called",
+		document:       `{}`,
+		expression:     `def a: "outer"; def b: a; def a: "inner"; [a, b]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- inner\n- outer\n",
+		},
+	},
+	{
+		description: "Functions can be overloaded by the number of parameters",
+		document:    `{}`,
+		expression:  `def f: "none"; def f(x): "one"; [f, f(1)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- none\n- one\n",
+		},
+	},
+	{
+		description: "Functions can use variables in scope",
+		document:    `{factor: 3, values: [1, 2]}`,
+		expression:  `.factor as $factor | def scale: . * $factor; .values | map(scale)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[3, 6]\n",
+		},
+	},
+	{
+		description:    "Functions shadow builtins",
+		subdescription: "Like jq, a function with the same name and number of parameters as a builtin is used instead of it, for as long as it is in scope",
+		document:       `{a: 1}`,
+		expression:     `(def keys: "mine"; keys), keys`,
+		expected: []string{
+			"D0, P[], (!!str)::mine\n",
+			"D0, P[], (!!seq)::- a\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{}`,
+		expression: `def splits(x): x; splits(3)`,
+		expected: []string{
+			"D0, P[], (!!int)::3\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: 1}`,
+		expression: `def f(keys): keys; f(7), keys`,
+		expected: []string{
+			"D0, P[], (!!int)::7\n",
+			"D0, P[], (!!seq)::- a\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: 1}`,
+		expression: `def keys(f): f; keys`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "functions only shadow builtins with the same number of parameters",
+		document:    `{}`,
+		expression:  `def split(a; b): 1; "a,b" | split(","), split(","; "x")`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- b\n",
+			"D0, P[], (!!int)::1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{}`,
+		expression: `def f(a): a + 1; f(def g: 2; g)`,
+		expected: []string{
+			"D0, P[], (!!int)::3\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[1, 2]`,
+		expression: `def f($a): $a * 10; [f(.[])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 10\n- 20\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{}`,
+		expression: `def h: 2; def f(g): def h: 1; g; f(h)`,
+		expected: []string{
+			"D0, P[], (!!int)::2\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "function definitions end with the enclosing brackets",
+		document:    `{a: 1}`,
+		expression:  `(def f: .a; f) + 1`,
+		expected: []string{
+			"D0, P[a], (!!int)::2\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: 1}`,
+		expression:    `(def f: .a; f) | f`,
+		expectedError: "f/0 is not defined",
+	},
+	{
+		skipDoc:     true,
+		description: "path after a function call",
+		document:    `{a: {b: 2}}`,
+		expression:  `def f: .a; f.b`,
+		expected: []string{
+			"D0, P[a b], (!!int)::2\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "update with a function",
+		document:    `{a: 1, b: 2}`,
+		expression:  `def inc: . + 1; .[] |= inc`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: 2, b: 3}\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{}`,
+		expression:    `undefined_function`,
+		expectedError: "undefined_function/0 is not defined",
+	},
+	{
+		skipDoc:       true,
+		document:      `{}`,
+		expression:    `def f: 1; f(2)`,
+		expectedError: "f/1 is not defined",
+	},
+}
+
+func TestFunctionOperatorScenarios(t *testing.T) {
+	for _, tt := range functionOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "user-defined-functions", functionOperatorScenarios)
+}
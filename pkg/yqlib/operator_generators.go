@@ -0,0 +1,337 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// yieldFunc is given the results of a generator one at a time. It returns false
+// when it does not need any more, so the generator can stop early.
+type yieldFunc func(candidate *CandidateNode) (bool, error)
+
+// generate evaluates the expression, passing on each result as it is found. Pipes,
+// unions and the generator builtins are followed so that they only evaluate as
+// much as is needed, anything else is evaluated in full. It returns false if
+// yield asked to stop.
+func generate(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode, yield yieldFunc) (bool, error) {
+	if expressionNode == nil {
+		return yieldAll(context.MatchingNodes, yield)
+	}
+
+	switch expressionNode.Operation.OperationType {
+	case pipeOpType:
+		if expressionNode.LHS.Operation.OperationType == assignVariableOpType {
+			break
+		}
+		return generate(d, context, expressionNode.LHS, func(candidate *CandidateNode) (bool, error) {
+			return generate(d, context.SingleChildContext(candidate), expressionNode.RHS, yield)
+		})
+	case unionOpType:
+		more, err := generate(d, context, expressionNode.LHS, yield)
+		if !more || err != nil {
+			return more, err
+		}
+		return generate(d, context, expressionNode.RHS, yield)
+	}
+
+	if prefs, ok := expressionNode.Operation.Preferences.(functionCallPreferences); ok && context.GetFunction(prefs.Name, prefs.Arity) == nil {
+		if builtin := getBuiltinFunction(prefs.Name, prefs.Arity); builtin != nil {
+			return builtin(d, context, getFunctionArguments(expressionNode.RHS, prefs.Arity), yield)
+		}
+	}
+
+	result, err := d.GetMatchingNodes(context, expressionNode)
+	if err != nil {
+		return false, err
+	}
+	return yieldAll(result.MatchingNodes, yield)
+}
+
+func yieldAll(results *list.List, yield yieldFunc) (bool, error) {
+	for el := results.Front(); el != nil; el = el.Next() {
+		more, err := yield(el.Value.(*CandidateNode))
+		if !more || err != nil {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+// getCounts evaluates a count against the node, like the n of limit(n; f).
+func getCounts(d *dataTreeNavigator, context Context, candidate *CandidateNode, expressionNode *ExpressionNode, operation string) ([]int, error) {
+	result, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int, 0, result.MatchingNodes.Len())
+	for el := result.MatchingNodes.Front(); el != nil; el = el.Next() {
+		countNode := el.Value.(*CandidateNode)
+		tag, err := getNumberTag(countNode, operation)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseNumber(countNode, tag)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("%v does not support negative numbers, got %v", operation, countNode.Value)
+		}
+		counts = append(counts, int(math.Min(value, math.MaxInt32)))
+	}
+	return counts, nil
+}
+
+// limitGenerator passes on the first count results of the expression for each node
+func limitGenerator(d *dataTreeNavigator, context Context, operation string, countExpression *ExpressionNode, expressionNode *ExpressionNode, yield yieldFunc) (bool, error) {
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		counts := []int{1}
+		if countExpression != nil {
+			var err error
+			counts, err = getCounts(d, context, candidate, countExpression, operation)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		for _, count := range counts {
+			if count == 0 {
+				continue
+			}
+			found := 0
+			stopped := false
+			_, err := generate(d, context.SingleChildContext(candidate), expressionNode, func(result *CandidateNode) (bool, error) {
+				found++
+				more, err := yield(result)
+				stopped = !more
+				return more && found < count, err
+			})
+			if stopped || err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func limitFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("limitFunction")
+	return limitGenerator(d, context, "limit", args[0], args[1], yield)
+}
+
+func firstFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("firstFunction")
+	if len(args) == 0 {
+		return indexFunction(context, createScalarNode(0, "0"), yield)
+	}
+	return limitGenerator(d, context, "first", nil, args[0], yield)
+}
+
+func lastFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("lastFunction")
+	if len(args) == 0 {
+		return indexFunction(context, createScalarNode(-1, "-1"), yield)
+	}
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		// there is no knowing which is the last without finding them all
+		result, err := d.GetMatchingNodes(context.SingleChildContext(el.Value.(*CandidateNode)), args[0])
+		if err != nil {
+			return false, err
+		}
+		if result.MatchingNodes.Len() > 0 {
+			if more, err := yield(result.MatchingNodes.Back().Value.(*CandidateNode)); !more || err != nil {
+				return more, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func nthFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("nthFunction")
+	if len(args) == 1 {
+		for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+			candidate := el.Value.(*CandidateNode)
+			indices, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), args[0])
+			if err != nil {
+				return false, err
+			}
+			for indexEl := indices.MatchingNodes.Front(); indexEl != nil; indexEl = indexEl.Next() {
+				more, err := indexFunction(context.SingleChildContext(candidate), indexEl.Value.(*CandidateNode), yield)
+				if !more || err != nil {
+					return more, err
+				}
+			}
+		}
+		return true, nil
+	}
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		counts, err := getCounts(d, context, candidate, args[0], "nth")
+		if err != nil {
+			return false, err
+		}
+		for _, n := range counts {
+			var nth *CandidateNode
+			found := 0
+			_, err := generate(d, context.SingleChildContext(candidate), args[1], func(result *CandidateNode) (bool, error) {
+				if found == n {
+					nth = result
+				}
+				found++
+				return found <= n, nil
+			})
+			if err != nil {
+				return false, err
+			}
+			if nth != nil {
+				if more, err := yield(nth); !more || err != nil {
+					return more, err
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// indexFunction is .[index] for each node
+func indexFunction(context Context, index *CandidateNode, yield yieldFunc) (bool, error) {
+	result, err := traverseNodesWithArrayIndices(context, []*CandidateNode{index}, traversePreferences{})
+	if err != nil {
+		return false, err
+	}
+	return yieldAll(result.MatchingNodes, yield)
+}
+
+func getRangeArguments(d *dataTreeNavigator, context Context, candidate *CandidateNode, expressionNode *ExpressionNode) ([]float64, error) {
+	result, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode)
+	if err != nil {
+		return nil, err
+	}
+	arguments := make([]float64, 0, result.MatchingNodes.Len())
+	for el := result.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		tag, err := getNumberTag(node, "range")
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseNumber(node, tag)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, value)
+	}
+	return arguments, nil
+}
+
+func rangeFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("rangeFunction")
+	// range(upto) is range(0; upto), and the step defaults to 1
+	if len(args) == 1 {
+		args = []*ExpressionNode{nil, args[0]}
+	}
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		arguments := [][]float64{{0}, nil, {1}}
+		for i, arg := range args {
+			if arg == nil {
+				continue
+			}
+			values, err := getRangeArguments(d, context, candidate, arg)
+			if err != nil {
+				return false, err
+			}
+			arguments[i] = values
+		}
+
+		for _, from := range arguments[0] {
+			for _, upto := range arguments[1] {
+				for _, by := range arguments[2] {
+					more, err := rangeGenerator(from, upto, by, yield)
+					if !more || err != nil {
+						return more, err
+					}
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+func rangeGenerator(from float64, upto float64, by float64, yield yieldFunc) (bool, error) {
+	if by == 0 {
+		return false, fmt.Errorf("range cannot step by 0")
+	}
+	for i := 0; ; i++ {
+		value := from + float64(i)*by
+		if (by > 0 && !(value < upto)) || (by < 0 && !(value > upto)) {
+			return true, nil
+		}
+		var number *CandidateNode
+		// like jq, whole numbers are given as ints
+		if isWholeInt(value) {
+			number = createScalarNode(int64(value), strconv.FormatInt(int64(value), 10))
+		} else {
+			number = createScalarNode(value, formatFloat(value))
+		}
+		if more, err := yield(number); !more || err != nil {
+			return more, err
+		}
+	}
+}
+
+// loopGenerator is the loop behind until, while and repeat. For each result of the condition, it
+// passes on the node when it should, and then continues with the results of update if it should.
+func loopGenerator(d *dataTreeNavigator, context Context, candidate *CandidateNode, condition *ExpressionNode, update *ExpressionNode, emitWhen bool, continueWhen bool, yield yieldFunc) (bool, error) {
+	conditions := []bool{true}
+	if condition != nil {
+		result, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), condition)
+		if err != nil {
+			return false, err
+		}
+		conditions = make([]bool, 0, result.MatchingNodes.Len())
+		for el := result.MatchingNodes.Front(); el != nil; el = el.Next() {
+			conditions = append(conditions, isTruthyNode(el.Value.(*CandidateNode)))
+		}
+	}
+
+	for _, conditionMet := range conditions {
+		if conditionMet == emitWhen {
+			if more, err := yield(candidate); !more || err != nil {
+				return more, err
+			}
+		}
+		if conditionMet == continueWhen {
+			// update a copy, so the nodes already given are left as they were
+			more, err := generate(d, context.SingleChildContext(candidate.Copy()), update, func(next *CandidateNode) (bool, error) {
+				return loopGenerator(d, context, next, condition, update, emitWhen, continueWhen, yield)
+			})
+			if !more || err != nil {
+				return more, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func loopFunction(emitWhen bool, continueWhen bool) builtinFunction {
+	return func(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+		condition, update := args[0], args[0]
+		if len(args) == 2 {
+			update = args[1]
+		} else {
+			condition = nil
+		}
+		for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+			more, err := loopGenerator(d, context, el.Value.(*CandidateNode), condition, update, emitWhen, continueWhen, yield)
+			if !more || err != nil {
+				return more, err
+			}
+		}
+		return true, nil
+	}
+}
@@ -0,0 +1,261 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var generatorOperatorScenarios = []expressionScenario{
+	{
+		description:    "First match",
+		subdescription: "Stops as soon as it has found a match",
+		document:       `{items: [{name: a, ready: false}, {name: b, ready: true}, {name: c, ready: true}]}`,
+		expression:     `first(.items[] | select(.ready)) | .name`,
+		expected: []string{
+			"D0, P[items 1 name], (!!str)::b\n",
+		},
+	},
+	{
+		description: "First element of an array",
+		document:    `[cat, dog]`,
+		expression:  `first`,
+		expected: []string{
+			"D0, P[0], (!!str)::cat\n",
+		},
+	},
+	{
+		description: "Last element of an array",
+		document:    `[cat, dog]`,
+		expression:  `last`,
+		expected: []string{
+			"D0, P[1], (!!str)::dog\n",
+		},
+	},
+	{
+		description: "Last result",
+		document:    `[1, 2, 3]`,
+		expression:  `last(.[] | select(. < 3))`,
+		expected: []string{
+			"D0, P[1], (!!int)::2\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[]`,
+		expression: `first`,
+		expected: []string{
+			"D0, P[0], (!!null)::null\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[1, 2]`,
+		expression: `[first(.[] | select(. > 5))]`,
+		expected: []string{
+			"D0, P[], (!!seq)::[]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Later results are not evaluated",
+		document:    `[1, 2]`,
+		expression:  `first(.[], error("not evaluated"))`,
+		expected: []string{
+			"D0, P[0], (!!int)::1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[[1, 2], [3, 4]]`,
+		expression: `.[] | first(.[])`,
+		expected: []string{
+			"D0, P[0 0], (!!int)::1\n",
+			"D0, P[1 0], (!!int)::3\n",
+		},
+	},
+	{
+		description: "Limit",
+		document:    `[a, b, c, d]`,
+		expression:  `[limit(2; .[])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- b\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[a, b]`,
+		expression: `[limit(0; .[])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::[]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[a, b]`,
+		expression: `[limit(5; .[])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- b\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `[a, b]`,
+		expression:    `limit(-1; .[])`,
+		expectedError: "limit does not support negative numbers, got -1",
+	},
+	{
+		skipDoc:       true,
+		document:      `[a, b]`,
+		expression:    `limit("a"; .[])`,
+		expectedError: "cannot limit !!str (), only numbers are supported",
+	},
+	{
+		description: "Nth result",
+		document:    `[a, b, c, d]`,
+		expression:  `nth(2; .[] | select(. != "a"))`,
+		expected: []string{
+			"D0, P[3], (!!str)::d\n",
+		},
+	},
+	{
+		description: "Nth element of an array",
+		document:    `[a, b, c, d]`,
+		expression:  `nth(1)`,
+		expected: []string{
+			"D0, P[1], (!!str)::b\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[a, b]`,
+		expression: `[nth(5; .[])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::[]\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `[a, b]`,
+		expression:    `nth(-1; .[])`,
+		expectedError: "nth does not support negative numbers, got -1",
+	},
+	{
+		description: "Range",
+		expression:  `[range(4)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 0\n- 1\n- 2\n- 3\n",
+		},
+	},
+	{
+		description:    "Range with a step",
+		subdescription: "Counts from the first number, up to but not including the second",
+		expression:     `[range(0; 10; 3)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 0\n- 3\n- 6\n- 9\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[range(2; 4)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 2\n- 3\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[range(5; 0; -2)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 5\n- 3\n- 1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[range(0; 1; 0.25)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 0\n- 0.25\n- 0.5\n- 0.75\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[range(0, 1; 2)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 0\n- 1\n- 1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[range(3; 0)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::[]\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		expression:    `[range(0; 3; 0)]`,
+		expectedError: "range cannot step by 0",
+	},
+	{
+		description:    "Range without an end",
+		subdescription: "Generators can be endless, as long as something stops them",
+		expression:     `first(range(1; infinite) | select(. * . > 50))`,
+		expected: []string{
+			"D0, P[], (!!int)::8\n",
+		},
+	},
+	{
+		description:    "Until",
+		subdescription: "Updates the value until the condition is true",
+		document:       `{size: 3}`,
+		expression:     `.size | until(. > 100; . * 2)`,
+		expected: []string{
+			"D0, P[size], (!!int)::192\n",
+		},
+	},
+	{
+		description:    "While",
+		subdescription: "Gives each value while the condition is true",
+		expression:     `[1 | while(. < 50; . * 3)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n- 3\n- 9\n- 27\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Updates do not change the values already given",
+		document:    `{a: 1}`,
+		expression:  `[while(.a < 3; .a += 1) | .a]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n- 2\n",
+		},
+	},
+	{
+		description:    "Repeat",
+		subdescription: "Repeat is endless, use limit to stop it",
+		expression:     `[limit(5; 1 | repeat(. * 2))]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n- 2\n- 4\n- 8\n- 16\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[a, b]`,
+		expression: `[.[] | limit(2; repeat(.))]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- a\n- b\n- b\n",
+		},
+	},
+	{
+		description: "Functions with the same name are used instead",
+		document:    `[a, b]`,
+		expression:  `def first(f): "mine"; first(.[])`,
+		expected: []string{
+			"D0, P[], (!!str)::mine\n",
+		},
+	},
+}
+
+func TestGeneratorOperatorScenarios(t *testing.T) {
+	for _, tt := range generatorOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "generators", generatorOperatorScenarios)
+}
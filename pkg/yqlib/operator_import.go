@@ -0,0 +1,241 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LibraryPreferences struct {
+	// Paths are the directories searched for `import` and `include`, before
+	// those in YQ_LIBRARY_PATH.
+	Paths []string
+	// ExpressionDirectory is the directory of the expression file, if there
+	// is one. Its imports are looked for there first, like those of a library.
+	ExpressionDirectory string
+}
+
+func NewDefaultLibraryPreferences() LibraryPreferences {
+	return LibraryPreferences{Paths: []string{}}
+}
+
+var ConfiguredLibraryPreferences = NewDefaultLibraryPreferences()
+
+// libraries are only read and parsed once, rather than for every document,
+// unless they have changed since.
+var libraryCache = map[string]*loadedLibrary{}
+var libraryCacheLock sync.Mutex
+
+type libraryFileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+type loadedLibrary struct {
+	definitions []*functionDefinition
+	// the library and every file it imports, as they were when it was read
+	files map[string]libraryFileVersion
+}
+
+func readLibraryFileVersion(filename string) (libraryFileVersion, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return libraryFileVersion{}, err
+	}
+	return libraryFileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// isStale is true when the library, or one it imports, has been edited since it was read.
+func (library *loadedLibrary) isStale() bool {
+	for filename, version := range library.files {
+		current, err := readLibraryFileVersion(filename)
+		if err != nil || current.size != version.size || !current.modTime.Equal(version.modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+type importPreferences struct {
+	Path string
+	// empty for `include`
+	Namespace string
+}
+
+func getLibraryPaths() []string {
+	paths := append([]string{}, ConfiguredLibraryPreferences.Paths...)
+	for _, path := range filepath.SplitList(os.Getenv("YQ_LIBRARY_PATH")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, ".")
+	}
+	return paths
+}
+
+// findLibrary looks for the library relative to the directory of the file that
+// imports it first, and then in the library paths.
+func findLibrary(name string, fromDirectory string) (string, error) {
+	candidates := []string{name}
+	if filepath.Ext(name) != ".yq" {
+		candidates = []string{name + ".yq", name}
+	}
+	if filepath.IsAbs(name) {
+		for _, candidate := range candidates {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("could not find library %v", name)
+	}
+
+	searched := getLibraryPaths()
+	if fromDirectory != "" {
+		searched = append([]string{fromDirectory}, searched...)
+	}
+	for _, libraryPath := range searched {
+		for _, candidate := range candidates {
+			fullPath := filepath.Join(libraryPath, candidate)
+			if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+				return fullPath, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("could not find library %v in %v", name, strings.Join(searched, string(filepath.ListSeparator)))
+}
+
+// loadLibrary parses a library file, which may only contain function definitions
+// (and its own imports), and returns the functions it defines, including those
+// it includes.
+func loadLibrary(name string, fromDirectory string, loading []string) (*loadedLibrary, error) {
+	filename, err := findLibrary(name, fromDirectory)
+	if err != nil {
+		return nil, err
+	}
+	absoluteFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, alreadyLoading := range loading {
+		if alreadyLoading == absoluteFilename {
+			return nil, fmt.Errorf("circular import of library %v", name)
+		}
+	}
+	loading = append(loading, absoluteFilename)
+
+	libraryCacheLock.Lock()
+	cached, found := libraryCache[absoluteFilename]
+	libraryCacheLock.Unlock()
+	if found && !cached.isStale() {
+		return cached, nil
+	}
+
+	log.Debugf("loading library %v from %v", name, filename)
+	// read the version first, so an edit while loading is picked up next time
+	version, err := readLibraryFileVersion(absoluteFilename)
+	if err != nil {
+		return nil, err
+	}
+	libraryBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	node, err := ExpressionParser.ParseExpression(strings.ReplaceAll(string(libraryBytes), "\r\n", "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing library %v: %w", name, err)
+	}
+
+	scope := Context{MatchingNodes: list.New()}
+	definitions := make([]*functionDefinition, 0)
+	files := map[string]libraryFileVersion{absoluteFilename: version}
+	libraryDirectory := filepath.Dir(absoluteFilename)
+
+	for node != nil && node.Operation.OperationType != selfReferenceOpType {
+		// switch on the preferences rather than the operation type, as the
+		// import operation type refers back to this function.
+		switch prefs := node.Operation.Preferences.(type) {
+		case functionDefinitionPreferences:
+			definition := &functionDefinition{
+				name:   prefs.Name,
+				params: prefs.Params,
+				body:   node.RHS.LHS,
+			}
+			definition.scope = scope.Clone()
+			definition.scope.SetFunction(definition)
+			scope.SetFunction(definition)
+			definitions = append(definitions, definition)
+			node = node.RHS.RHS
+		case importPreferences:
+			imported, err := importLibrary(prefs, libraryDirectory, loading)
+			if err != nil {
+				return nil, err
+			}
+			for _, definition := range imported.definitions {
+				scope.SetFunction(definition)
+			}
+			for importedFilename, importedVersion := range imported.files {
+				files[importedFilename] = importedVersion
+			}
+			if prefs.Namespace == "" {
+				// like jq, included functions are part of the library
+				definitions = append(definitions, imported.definitions...)
+			}
+			node = node.RHS
+		default:
+			return nil, fmt.Errorf("library %v must only contain function definitions, found %v", name, node.Operation.toString())
+		}
+	}
+
+	library := &loadedLibrary{definitions: definitions, files: files}
+	libraryCacheLock.Lock()
+	libraryCache[absoluteFilename] = library
+	libraryCacheLock.Unlock()
+	return library, nil
+}
+
+// importLibrary returns the functions of the library as they are named in the importing scope.
+func importLibrary(prefs importPreferences, fromDirectory string, loading []string) (*loadedLibrary, error) {
+	library, err := loadLibrary(prefs.Path, fromDirectory, loading)
+	if err != nil {
+		return nil, err
+	}
+	if prefs.Namespace == "" {
+		return library, nil
+	}
+	namespaced := make([]*functionDefinition, len(library.definitions))
+	for i, definition := range library.definitions {
+		// the body still runs in the scope of the library, so it can call its
+		// own functions without the namespace.
+		namespacedDefinition := *definition
+		namespacedDefinition.name = prefs.Namespace + "::" + definition.name
+		namespaced[i] = &namespacedDefinition
+	}
+	return &loadedLibrary{definitions: namespaced, files: library.files}, nil
+}
+
+func importOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(importPreferences)
+	log.Debugf("importOperator %v as %v", prefs.Path, prefs.Namespace)
+
+	library, err := importLibrary(prefs, ConfiguredLibraryPreferences.ExpressionDirectory, []string{})
+	if err != nil {
+		return Context{}, err
+	}
+
+	restContext := context.Clone()
+	for _, definition := range library.definitions {
+		restContext.SetFunction(definition)
+	}
+
+	result, err := d.GetMatchingNodes(restContext, expressionNode.RHS)
+	if err != nil {
+		return Context{}, err
+	}
+	return context.ChildContext(result.MatchingNodes), nil
+}
@@ -0,0 +1,121 @@
+package yqlib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikefarah/yq/v4/test"
+)
+
+var importOperatorScenarios = []expressionScenario{
+	{
+		description:    "Include a library",
+		subdescription: "The functions in the library are defined as if they were written in the expression",
+		document:       `{kind: Deployment, spec: {template: {spec: {containers: [{name: app, image: "nginx:1.25"}]}}}}`,
+		expression:     `include "../../examples/lib/k8s"; images`,
+		expected: []string{
+			"D0, P[], (!!seq)::- \"nginx:1.25\"\n",
+		},
+	},
+	{
+		description:    "Import a library with a namespace",
+		subdescription: "Functions from the library are prefixed with the namespace",
+		document:       `{kind: HelmRelease, spec: {values: {replicas: 3}}}`,
+		expression:     `import "../../examples/lib/helm" as helm; helm::values.replicas`,
+		expected: []string{
+			"D0, P[spec values replicas], (!!int)::3\n",
+		},
+	},
+	{
+		description:    "Libraries can use their own functions",
+		subdescription: "Imported functions run in the scope of their library, so they don't need the namespace",
+		document:       `[{kind: Service}, {kind: Deployment}]`,
+		expression:     `import "../../examples/lib/helm" as helm; [.[] | helm::deployments]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- {kind: Deployment}\n",
+		},
+	},
+	{
+		description:    "Included functions are part of the library",
+		subdescription: "helm.yq includes k8s.yq, which is found next to it, so its functions are available through the helm namespace",
+		document:       `{kind: Deployment}`,
+		expression:     `import "../../examples/lib/helm" as helm; helm::is_kind("Deployment")`,
+		expected: []string{
+			"D0, P[kind], (!!bool)::true\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		description:   "namespaced functions are not available without the namespace",
+		document:      `{}`,
+		expression:    `import "../../examples/lib/helm" as helm; values`,
+		expectedError: "values/0 is not defined",
+	},
+	{
+		skipDoc:       true,
+		description:   "imports end with the enclosing brackets",
+		document:      `{}`,
+		expression:    `(include "../../examples/lib/k8s"; .) | images`,
+		expectedError: "images/0 is not defined",
+	},
+	{
+		skipDoc:       true,
+		document:      `{}`,
+		expression:    `include "../../examples/lib/missing"; .`,
+		expectedError: "could not find library ../../examples/lib/missing in .",
+	},
+	{
+		skipDoc:       true,
+		document:      `{}`,
+		expression:    `include "../../examples/sample.yaml"; .`,
+		expectedError: "library ../../examples/sample.yaml must only contain function definitions, found CREATE_MAP",
+	},
+}
+
+func TestImportOperatorScenarios(t *testing.T) {
+	for _, tt := range importOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "import", importOperatorScenarios)
+}
+
+func TestImportOperatorReloadsEditedLibrary(t *testing.T) {
+	directory := t.TempDir()
+	libraryFile := filepath.Join(directory, "edited.yq")
+	included := filepath.Join(directory, "included.yq")
+	expression := `include "` + libraryFile + `"; greeting`
+	evaluator := NewStringEvaluator()
+	encoder := NewYamlEncoder(ConfiguredYamlPreferences)
+	decoder := NewYamlDecoder(ConfiguredYamlPreferences)
+
+	writeLibrary := func(filename string, contents string, modTime time.Time) {
+		if err := os.WriteFile(filename, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// set the time explicitly, file systems may not be precise enough to tell the edits apart
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	evaluate := func(expected string) {
+		t.Helper()
+		result, err := evaluator.Evaluate(expression, "{}", encoder, decoder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.AssertResult(t, expected, result)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	writeLibrary(included, `def name: "cat";`, start)
+	writeLibrary(libraryFile, `include "included"; def greeting: "hello " + name;`, start)
+	evaluate("hello cat\n")
+
+	writeLibrary(libraryFile, `include "included"; def greeting: "bye " + name;`, start.Add(time.Second))
+	evaluate("bye cat\n")
+
+	writeLibrary(included, `def name: "dog";`, start.Add(time.Second))
+	evaluate("bye dog\n")
+}
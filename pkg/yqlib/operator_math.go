@@ -0,0 +1,222 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type mathPreferences struct {
+	name  string
+	apply func(float64) float64
+	// integral functions give whole numbers, so their results are ints
+	integral bool
+}
+
+func significand(value float64) float64 {
+	if value == 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return value
+	}
+	fraction, _ := math.Frexp(value)
+	return fraction * 2
+}
+
+// parseNumber reads the value of an int or float node, including the yaml .inf and .nan
+func parseNumber(node *CandidateNode, tag string) (float64, error) {
+	if tag == "!!int" {
+		_, value, err := parseInt64(node.Value)
+		return float64(value), err
+	}
+	switch strings.ToLower(node.Value) {
+	case ".inf", "+.inf":
+		return math.Inf(1), nil
+	case "-.inf":
+		return math.Inf(-1), nil
+	case ".nan":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(node.Value, 64)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return ".nan"
+	case math.IsInf(value, 1):
+		return ".inf"
+	case math.IsInf(value, -1):
+		return "-.inf"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// isWholeInt is true when the value is a whole number that is exactly represented as a float
+func isWholeInt(value float64) bool {
+	return value == math.Trunc(value) && math.Abs(value) <= 1<<53
+}
+
+func getNumberTag(candidate *CandidateNode, operation string) (string, error) {
+	tag := candidate.guessTagFromCustomType()
+	if candidate.Kind != ScalarNode || (tag != "!!int" && tag != "!!float") {
+		return "", fmt.Errorf("cannot %v %v (%v), only numbers are supported", operation, candidate.Tag, candidate.GetNicePath())
+	}
+	return tag, nil
+}
+
+// createNumber creates the result, keeping any custom tag of the original node
+func createNumber(candidate *CandidateNode, tag string, value string) *CandidateNode {
+	if !strings.HasPrefix(candidate.Tag, "!!") {
+		tag = candidate.Tag
+	}
+	result := candidate.CreateReplacement(ScalarNode, tag, value)
+	result.Style = candidate.Style
+	return result
+}
+
+func mathOperator(_ *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(mathPreferences)
+	log.Debugf("mathOperator %v", prefs.name)
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		tag, err := getNumberTag(candidate, prefs.name)
+		if err != nil {
+			return Context{}, err
+		}
+		if tag == "!!int" && prefs.integral {
+			// already a whole number, keep it as it was written
+			results.PushBack(candidate)
+			continue
+		}
+
+		value, err := parseNumber(candidate, tag)
+		if err != nil {
+			return Context{}, err
+		}
+		result := prefs.apply(value)
+		if prefs.integral && isWholeInt(result) {
+			results.PushBack(createNumber(candidate, "!!int", strconv.FormatInt(int64(result), 10)))
+		} else {
+			results.PushBack(createNumber(candidate, "!!float", formatFloat(result)))
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func absOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("absOperator")
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		tag, err := getNumberTag(candidate, "abs")
+		if err != nil {
+			return Context{}, err
+		}
+		if strings.HasPrefix(candidate.Value, "-") {
+			// dropping the sign keeps the number formatted as it was
+			results.PushBack(createNumber(candidate, tag, strings.TrimPrefix(candidate.Value, "-")))
+		} else {
+			results.PushBack(candidate)
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func powOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("powOperator")
+	//rhs  block operator
+	//lhs of block = base
+	//rhs of block = exponent
+	block := expressionNode.RHS
+	if block.Operation.OperationType != blockOpType {
+		return Context{}, fmt.Errorf("pow needs two arguments, e.g. pow(.a; 2)")
+	}
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		bases, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), block.LHS)
+		if err != nil {
+			return Context{}, err
+		}
+		exponents, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), block.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+
+		for baseEl := bases.MatchingNodes.Front(); baseEl != nil; baseEl = baseEl.Next() {
+			for exponentEl := exponents.MatchingNodes.Front(); exponentEl != nil; exponentEl = exponentEl.Next() {
+				result, err := pow(baseEl.Value.(*CandidateNode), exponentEl.Value.(*CandidateNode))
+				if err != nil {
+					return Context{}, err
+				}
+				results.PushBack(result)
+			}
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func pow(base *CandidateNode, exponent *CandidateNode) (*CandidateNode, error) {
+	baseTag, err := getNumberTag(base, "pow")
+	if err != nil {
+		return nil, err
+	}
+	exponentTag, err := getNumberTag(exponent, "pow")
+	if err != nil {
+		return nil, err
+	}
+	baseValue, err := parseNumber(base, baseTag)
+	if err != nil {
+		return nil, err
+	}
+	exponentValue, err := parseNumber(exponent, exponentTag)
+	if err != nil {
+		return nil, err
+	}
+
+	result := math.Pow(baseValue, exponentValue)
+	if baseTag == "!!int" && exponentTag == "!!int" && exponentValue >= 0 && isWholeInt(result) {
+		return createNumber(base, "!!int", strconv.FormatInt(int64(result), 10)), nil
+	}
+	return createNumber(base, "!!float", formatFloat(result)), nil
+}
+
+func isNanOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("isNanOperator")
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		tag, err := getNumberTag(candidate, "isnan")
+		if err != nil {
+			return Context{}, err
+		}
+		value, err := parseNumber(candidate, tag)
+		if err != nil {
+			return Context{}, err
+		}
+		results.PushBack(createBooleanCandidate(candidate, math.IsNaN(value)))
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func infiniteOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	return context.SingleChildContext(&CandidateNode{Kind: ScalarNode, Tag: "!!float", Value: ".inf"}), nil
+}
+
+func nanOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	return context.SingleChildContext(&CandidateNode{Kind: ScalarNode, Tag: "!!float", Value: ".nan"}), nil
+}
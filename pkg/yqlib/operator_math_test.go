@@ -0,0 +1,170 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var mathOperatorScenarios = []expressionScenario{
+	{
+		description:    "Round up",
+		subdescription: "Results of floor, ceil, round and trunc are ints",
+		document:       `{cpu: 1.2}`,
+		expression:     `.cpu * 1.5 | ceil`,
+		expected: []string{
+			"D0, P[cpu], (!!int)::2\n",
+		},
+	},
+	{
+		description: "Integer division",
+		document:    `{bytes: 5000, blockSize: 1024}`,
+		expression:  `.bytes / .blockSize | floor`,
+		expected: []string{
+			"D0, P[bytes], (!!int)::4\n",
+		},
+	},
+	{
+		description: "Floor, ceil, round and trunc",
+		document:    `[1.5, -1.5]`,
+		expression:  `.[] | [floor, ceil, round, trunc]`,
+		expected: []string{
+			"D0, P[0], (!!seq)::- 1\n- 2\n- 2\n- 1\n",
+			"D0, P[1], (!!seq)::- -2\n- -1\n- -2\n- -1\n",
+		},
+	},
+	{
+		description:    "Ints are left alone",
+		subdescription: "Ints are already whole numbers, so they keep their formatting",
+		document:       `{a: 0x10}`,
+		expression:     `.a | round`,
+		expected: []string{
+			"D0, P[a], (!!int)::0x10\n",
+		},
+	},
+	{
+		description:    "Absolute value",
+		subdescription: "Numbers keep their formatting",
+		document:       `[-3, -2.50, 4]`,
+		expression:     `.[] |= abs`,
+		expected: []string{
+			"D0, P[], (!!seq)::[3, 2.50, 4]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: -1.5}`,
+		expression: `.a | fabs`,
+		expected: []string{
+			"D0, P[a], (!!float)::1.5\n",
+		},
+	},
+	{
+		description:    "Power",
+		subdescription: "Ints raised to ints are ints",
+		document:       `{a: 2}`,
+		expression:     `[pow(.a; 10), pow(.a; 0.5), pow(.a; -1)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1024\n- 1.4142135623730951\n- 0.5\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: 2}`,
+		expression: `.a |= pow(.; 2)`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: 4}\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		description:   "pow needs two arguments",
+		document:      `{a: 2}`,
+		expression:    `pow(.a)`,
+		expectedError: "pow needs two arguments, e.g. pow(.a; 2)",
+	},
+	{
+		description: "Square root, log and exp",
+		document:    `{a: 2}`,
+		expression:  `[.a | (sqrt, log, exp)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1.4142135623730951\n- 0.6931471805599453\n- 7.38905609893065\n",
+		},
+	},
+	{
+		description:    "Significand",
+		subdescription: "The number scaled to between 1 and 2",
+		document:       `[8, 12]`,
+		expression:     `.[] | significand`,
+		expected: []string{
+			"D0, P[0], (!!float)::1\n",
+			"D0, P[1], (!!float)::1.5\n",
+		},
+	},
+	{
+		description: "Infinite and nan",
+		expression:  `[infinite, nan]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- .inf\n- .nan\n",
+		},
+	},
+	{
+		description: "Check for nan",
+		document:    `[1, .nan, .inf]`,
+		expression:  `.[] |= isnan`,
+		expected: []string{
+			"D0, P[], (!!seq)::[false, true, false]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: .inf}`,
+		expression: `.a | floor`,
+		expected: []string{
+			"D0, P[a], (!!float)::.inf\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: !custom 1.5}`,
+		expression: `.a | floor`,
+		expected: []string{
+			"D0, P[a], (!custom)::1\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		description:   "floor of a string",
+		document:      `{a: cat}`,
+		expression:    `.a | floor`,
+		expectedError: "cannot floor !!str (a), only numbers are supported",
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: 0}`,
+		expression: `.a | significand`,
+		expected: []string{
+			"D0, P[a], (!!float)::0\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		description:   "isnan of a map",
+		document:      `{a: {b: 1}}`,
+		expression:    `.a | isnan`,
+		expectedError: "cannot isnan !!map (a), only numbers are supported",
+	},
+	{
+		skipDoc:     true,
+		description: "names starting with math functions are still functions",
+		expression:  `def logger: "x"; def rounded: 1; [logger, rounded]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- x\n- 1\n",
+		},
+	},
+}
+
+func TestMathOperatorScenarios(t *testing.T) {
+	for _, tt := range mathOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "math", mathOperatorScenarios)
+}
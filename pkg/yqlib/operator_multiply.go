@@ -10,8 +10,10 @@ import (
 type multiplyPreferences struct {
 	AppendArrays    bool
 	DeepMergeArrays bool
-	TraversePrefs   traversePreferences
-	AssignPrefs     assignPreferences
+	// when set, sequences of maps are merged by matching the items on this key, e.g. .name
+	KeyExpression *ExpressionNode
+	TraversePrefs traversePreferences
+	AssignPrefs   assignPreferences
 }
 
 func createMultiplyOp(prefs interface{}) func(lhs *ExpressionNode, rhs *ExpressionNode) *ExpressionNode {
@@ -166,8 +168,9 @@ func repeatString(lhs *CandidateNode, rhs *CandidateNode) (*CandidateNode, error
 func mergeObjects(d *dataTreeNavigator, context Context, lhs *CandidateNode, rhs *CandidateNode, preferences multiplyPreferences) (*CandidateNode, error) {
 	var results = list.New()
 
-	// only need to recurse the array if we are doing a deep merge
-	prefs := recursiveDescentPreferences{RecurseArray: preferences.DeepMergeArrays,
+	// only need to recurse the array if we are doing a deep merge,
+	// keyed merges recurse into the matching items themselves.
+	prefs := recursiveDescentPreferences{RecurseArray: preferences.DeepMergeArrays && preferences.KeyExpression == nil,
 		TraversePreferences: traversePreferences{DontFollowAlias: true, IncludeMapKeys: true}}
 	log.Debugf("merge - preferences.DeepMergeArrays %v", preferences.DeepMergeArrays)
 	log.Debugf("merge - preferences.AppendArrays %v", preferences.AppendArrays)
@@ -207,11 +210,29 @@ func applyAssignment(d *dataTreeNavigator, context Context, pathIndexToStartFrom
 	lhsPath := rhs.GetPath()[pathIndexToStartFrom:]
 	log.Debugf("merge - lhsPath %v", lhsPath)
 
+	// keyed merges do not recurse into sequences, so a sequence that can't be merged by key
+	// is merged by index here for deep merges, or else replaced.
+	unmergedSequence := false
+	if preferences.KeyExpression != nil && rhs.Kind == SequenceNode {
+		merged, err := mergeSequenceByKey(d, context, lhs, lhsPath, rhs, preferences)
+		if err != nil || merged {
+			return err
+		}
+		if preferences.DeepMergeArrays && !shouldAppendArrays {
+			log.Debugf("merge - sequence items do not all have a key, merging by index")
+			merged, err = mergeSequenceByIndex(d, context, lhs, lhsPath, rhs, preferences)
+			if err != nil || merged {
+				return err
+			}
+		}
+		unmergedSequence = true
+	}
+
 	assignmentOp := &Operation{OperationType: assignAttributesOpType, Preferences: preferences.AssignPrefs}
 	if shouldAppendArrays && rhs.Kind == SequenceNode {
 		assignmentOp.OperationType = addAssignOpType
 		log.Debugf("merge - assignmentOp.OperationType = addAssignOpType")
-	} else if !preferences.DeepMergeArrays && rhs.Kind == SequenceNode ||
+	} else if !preferences.DeepMergeArrays && rhs.Kind == SequenceNode || unmergedSequence ||
 		(rhs.Kind == ScalarNode || rhs.Kind == AliasNode) {
 		assignmentOp.OperationType = assignOpType
 		assignmentOp.UpdateAssign = false
@@ -234,3 +255,131 @@ func applyAssignment(d *dataTreeNavigator, context Context, pathIndexToStartFrom
 
 	return err
 }
+
+func getMergeKey(d *dataTreeNavigator, context Context, item *CandidateNode, keyExpression *ExpressionNode) (string, bool) {
+	keys, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(item), keyExpression)
+	if err != nil {
+		// e.g. .name of a sequence, the item has no key
+		log.Debugf("merge - no key for item: %v", err)
+		return "", false
+	}
+	if keys.MatchingNodes.Len() == 0 {
+		return "", false
+	}
+	key := keys.MatchingNodes.Front().Value.(*CandidateNode)
+	if key.Kind != ScalarNode || key.Tag == "!!null" {
+		return "", false
+	}
+	return key.Value, true
+}
+
+// getMergeKeys returns the key of every item, if every item has one.
+func getMergeKeys(d *dataTreeNavigator, context Context, sequence *CandidateNode, keyExpression *ExpressionNode) ([]string, bool) {
+	keys := make([]string, len(sequence.Content))
+	for index, item := range sequence.Content {
+		key, hasKey := getMergeKey(d, context, item, keyExpression)
+		if !hasKey {
+			return nil, false
+		}
+		keys[index] = key
+	}
+	return keys, true
+}
+
+// getMergeTargetSequence returns the lhs sequence at the path, or nil if there isn't one.
+func getMergeTargetSequence(d *dataTreeNavigator, context Context, lhs *CandidateNode, lhsPath []interface{}, preferences multiplyPreferences) (*CandidateNode, error) {
+	targets, err := d.GetMatchingNodes(context.SingleChildContext(lhs), createTraversalTree(lhsPath, preferences.TraversePrefs, false))
+	if err != nil || targets.MatchingNodes.Len() != 1 {
+		return nil, err
+	}
+	target := targets.MatchingNodes.Front().Value.(*CandidateNode)
+	if target.Kind != SequenceNode {
+		return nil, nil
+	}
+	return target, nil
+}
+
+// mergeSequenceByKey merges each item of the rhs sequence into the lhs item with the same key,
+// adding the items that have no match to the end. Returns false when not all items have a key,
+// so the sequences can be merged as usual.
+func mergeSequenceByKey(d *dataTreeNavigator, context Context, lhs *CandidateNode, lhsPath []interface{}, rhs *CandidateNode, preferences multiplyPreferences) (bool, error) {
+	target, err := getMergeTargetSequence(d, context, lhs, lhsPath, preferences)
+	if err != nil || target == nil {
+		return false, err
+	}
+
+	targetKeys, targetHasKeys := getMergeKeys(d, context, target, preferences.KeyExpression)
+	if !targetHasKeys {
+		return false, nil
+	}
+	rhsKeys, rhsHasKeys := getMergeKeys(d, context, rhs, preferences.KeyExpression)
+	if !rhsHasKeys {
+		return false, nil
+	}
+
+	for rhsIndex, rhsItem := range rhs.Content {
+		targetIndex := -1
+		for index, key := range targetKeys {
+			if key == rhsKeys[rhsIndex] {
+				targetIndex = index
+				break
+			}
+		}
+
+		if targetIndex == -1 {
+			if !preferences.TraversePrefs.DontAutoCreate {
+				// the item needs a new index key in the target, rather than its rhs one
+				added := rhsItem.Copy()
+				added.Key = nil
+				target.AddChild(added)
+				targetKeys = append(targetKeys, rhsKeys[rhsIndex])
+			}
+			continue
+		}
+
+		existing := target.Content[targetIndex]
+		merged, err := multiply(preferences)(d, context, existing, rhsItem)
+		if err != nil {
+			return false, err
+		}
+		merged.Key = existing.Key
+		merged.Parent = target
+		target.Content[targetIndex] = merged
+	}
+	return true, nil
+}
+
+// mergeSequenceByIndex deep merges each item of the rhs sequence into the lhs item at the same
+// index, like *d, for keyed merges of sequences whose items have no key. Maps and sequences
+// are merged (by key again, where their items have one), other items are replaced.
+// Returns false when there is no lhs sequence to merge into.
+func mergeSequenceByIndex(d *dataTreeNavigator, context Context, lhs *CandidateNode, lhsPath []interface{}, rhs *CandidateNode, preferences multiplyPreferences) (bool, error) {
+	target, err := getMergeTargetSequence(d, context, lhs, lhsPath, preferences)
+	if err != nil || target == nil {
+		return false, err
+	}
+
+	for index, rhsItem := range rhs.Content {
+		if index >= len(target.Content) {
+			added := rhsItem.Copy()
+			added.Key = nil
+			target.AddChild(added)
+			continue
+		}
+
+		existing := target.Content[index]
+		var merged *CandidateNode
+		if (existing.Kind == MappingNode || existing.Kind == SequenceNode) && existing.Kind == rhsItem.Kind {
+			merged, err = multiply(preferences)(d, context, existing, rhsItem)
+			if err != nil {
+				return false, err
+			}
+		} else {
+			merged = rhsItem.Copy()
+		}
+		merged.Key = existing.Key
+		merged.Parent = target
+		target.Content[index] = merged
+	}
+	return true, nil
+}
@@ -489,6 +489,107 @@ var multiplyOperatorScenarios = []expressionScenario{
 			"D0, P[], (!!map)::{myArray: [{a: apple, b: appleB2}, {a: kiwi, b: kiwiB}, {a: banana, b: bananaB, c: bananaC}, {a: dingo, c: dingoC}], something: else}\n",
 		},
 	},
+	{
+		description:    "Merge arrays of objects together, matching on a key with the k flag",
+		subdescription: "The `k(expression)` flag matches the items of sequences on a key, merging the matching items and adding the rest to the end. This is the same as the example above, without the need for a `reduce`.",
+		document:       `{myArray: [{a: apple, b: appleB}, {a: kiwi, b: kiwiB}, {a: banana, b: bananaB}], something: else}`,
+		expression:     `.myArray *=k(.a) [{"a": "banana", "c": "bananaC"}, {"a": "apple", "b": "appleB2"}, {"a": "dingo", "c": "dingoC"}]`,
+		expected: []string{
+			"D0, P[], (!!map)::{myArray: [{a: apple, b: appleB2}, {a: kiwi, b: kiwiB}, {a: banana, b: bananaB, c: bananaC}, {a: dingo, c: dingoC}], something: else}\n",
+		},
+	},
+	{
+		description:    "Merge Kubernetes containers by name",
+		subdescription: "Nested sequences are merged by the same key, use `//` to give a key for each kind of item. Sequences where an item has no key are merged as usual.",
+		document:       "containers:\n  - name: web # the app\n    image: nginx:1.24\n    ports:\n      - containerPort: 80\n  - name: sidecar\n    image: envoy\n",
+		document2:      "containers:\n  - name: web\n    image: nginx:1.25\n    ports:\n      - containerPort: 80\n        protocol: TCP\n      - containerPort: 443\n",
+		expression:     `select(fi == 0) *k(.name // .containerPort) select(fi == 1)`,
+		expected: []string{
+			"D0, P[], (!!map)::containers:\n    - name: web # the app\n      image: nginx:1.25\n      ports:\n        - containerPort: 80\n          protocol: TCP\n        - containerPort: 443\n    - name: sidecar\n      image: envoy\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "keyed merge with only existing fields does not add items",
+		document:    `[{name: x, v: 1}]`,
+		expression:  `. *?k(.name) [{"name": "x", "v": 2}, {"name": "y"}]`,
+		expected: []string{
+			"D0, P[], (!!seq)::[{name: x, v: 2}]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "keyed merge with append flag",
+		document:    `{a: [{name: x, v: [1]}], b: [{name: x, v: [2]}]}`,
+		expression:  `.a *+k(.name) .b`,
+		expected: []string{
+			"D0, P[a], (!!seq)::[{name: x, v: [1, 2]}]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "keyed merge gives added items a new index",
+		document:    `{a: [{name: x}, {name: y}], b: [{name: z}]}`,
+		expression:  `(.a *k(.name) .b) | .[] | path`,
+		expected: []string{
+			"D0, P[a 0], (!!seq)::- a\n- 0\n",
+			"D0, P[a 1], (!!seq)::- a\n- 1\n",
+			"D0, P[a 2], (!!seq)::- a\n- 2\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: [{name: x}], b: [{name: z}]}`,
+		expression: `.a *k(.name) .b | .[1] | key | tag`,
+		expected: []string{
+			"D0, P[], (!!str)::!!int\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "items without a key are merged as usual",
+		document:    `{a: [{name: x}, cat], b: [{name: y}]}`,
+		expression:  `.a *k(.name) .b`,
+		expected: []string{
+			"D0, P[a], (!!seq)::[{name: y}]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "deep keyed merge of items without a key merges by index",
+		document:    `{a: {p: [1]}, b: {p: [2, 3]}}`,
+		expression:  `.a *dk(.name) .b`,
+		expected: []string{
+			"D0, P[a], (!!map)::{p: [2, 3]}\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "deep keyed merge of nested sequences without a key",
+		document:    `{a: [{name: x, ports: [{n: 1, p: tcp}]}], b: [{name: x, ports: [{n: 2}, {n: 3}]}]}`,
+		expression:  `.a *dk(.name) .b`,
+		expected: []string{
+			"D0, P[a], (!!seq)::[{name: x, ports: [{n: 2, p: tcp}, {n: 3}]}]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "deep keyed merge by index keeps merging by key",
+		document:    `{a: [[{name: x, v: 1}]], b: [[{name: x, w: 2}, {name: y}]]}`,
+		expression:  `.a *dk(.name) .b`,
+		expected: []string{
+			"D0, P[a], (!!seq)::[[{name: x, v: 1, w: 2}, {name: y}]]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "key expression with flag characters and brackets",
+		document:    `{a: [{id: {cd: 1}, v: 1}], b: [{id: {cd: 1}, v: 2}]}`,
+		expression:  `.a *dk((.id.cd)) .b`,
+		expected: []string{
+			"D0, P[a], (!!seq)::[{id: {cd: 1}, v: 2}]\n",
+		},
+	},
 	{
 		description: "Merge to prefix an element",
 		document:    `{a: cat, b: dog}`,
@@ -0,0 +1,411 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var jsonPatchIndexRegex = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+
+type jsonPatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value *CandidateNode
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) like /a/b~1c/0 into its tokens: a, b/c and 0
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return make([]string, 0), nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON Pointer %v, it must be empty or start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func parseJSONPatchOperation(node *CandidateNode) (jsonPatchOperation, error) {
+	operation := jsonPatchOperation{}
+	if node.Kind != MappingNode {
+		return operation, fmt.Errorf("a patch operation must be a map, but got %v", node.Tag)
+	}
+	hasValue := false
+	for index := 0; index+1 < len(node.Content); index = index + 2 {
+		value := node.Content[index+1]
+		switch node.Content[index].Value {
+		case "op":
+			operation.Op = value.Value
+		case "path":
+			operation.Path = value.Value
+		case "from":
+			operation.From = value.Value
+		case "value":
+			operation.Value = value
+			hasValue = true
+		}
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if !hasValue {
+			return operation, fmt.Errorf("patch operation %v at %v must have a value", operation.Op, operation.Path)
+		}
+	case "remove", "move", "copy":
+	default:
+		return operation, fmt.Errorf("unknown patch operation '%v', must be one of add, remove, replace, move, copy or test", operation.Op)
+	}
+	return operation, nil
+}
+
+func jsonPatchIndex(node *CandidateNode, token string, pointer string) (int, error) {
+	if !jsonPatchIndexRegex.MatchString(token) {
+		return 0, fmt.Errorf("path %v does not exist, '%v' is not an index", pointer, token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, err
+	}
+	if index >= len(node.Content) {
+		return 0, fmt.Errorf("path %v does not exist, index %v is out of bounds", pointer, index)
+	}
+	return index, nil
+}
+
+// jsonPatchChild finds the child of a map or sequence, aliases are only followed when reading
+// as they point outside the node being patched.
+func jsonPatchChild(node *CandidateNode, token string, pointer string, followAliases bool) (*CandidateNode, error) {
+	if followAliases && node.Kind == AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case MappingNode:
+		for index := 0; index+1 < len(node.Content); index = index + 2 {
+			if node.Content[index].Value == token {
+				return node.Content[index+1], nil
+			}
+		}
+		return nil, fmt.Errorf("path %v does not exist", pointer)
+	case SequenceNode:
+		index, err := jsonPatchIndex(node, token, pointer)
+		if err != nil {
+			return nil, err
+		}
+		return node.Content[index], nil
+	}
+	return nil, fmt.Errorf("path %v does not exist, cannot find '%v' in %v", pointer, token, node.Tag)
+}
+
+func resolveJSONPointer(root *CandidateNode, tokens []string, pointer string, followAliases bool) (*CandidateNode, error) {
+	node := root
+	for _, token := range tokens {
+		child, err := jsonPatchChild(node, token, pointer, followAliases)
+		if err != nil {
+			return nil, err
+		}
+		node = child
+	}
+	if followAliases && node.Kind == AliasNode {
+		node = node.Alias
+	}
+	return node, nil
+}
+
+// jsonPatchPath turns the tokens of a pointer into a yq path, with indices into
+// sequences as ints.
+func jsonPatchPath(root *CandidateNode, tokens []string, pointer string) ([]interface{}, error) {
+	path := make([]interface{}, len(tokens))
+	node := root
+	for i, token := range tokens {
+		if node.Kind == SequenceNode && token != "-" {
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, fmt.Errorf("path %v does not exist, '%v' is not an index", pointer, token)
+			}
+			path[i] = index
+		} else {
+			path[i] = token
+		}
+		if i < len(tokens)-1 {
+			child, err := jsonPatchChild(node, token, pointer, false)
+			if err != nil {
+				return nil, err
+			}
+			node = child
+		}
+	}
+	return path, nil
+}
+
+// assignJSONPatchPath sets the value at the path like `=` does. DeeplyAssign is not used
+// as it merges maps into any existing map, where a patch replaces them.
+func assignJSONPatchPath(d *dataTreeNavigator, context Context, path []interface{}, value *CandidateNode) error {
+	assignmentOpNode := &ExpressionNode{
+		Operation: &Operation{OperationType: assignOpType, Preferences: assignPreferences{}},
+		LHS:       createTraversalTree(path, traversePreferences{DontFollowAlias: true}, false),
+		RHS:       &ExpressionNode{Operation: &Operation{OperationType: valueOpType, CandidateNode: value}},
+	}
+	_, err := d.GetMatchingNodes(context, assignmentOpNode)
+	return err
+}
+
+// deleteJSONPatchPath removes the value at the path like `del` does
+func deleteJSONPatchPath(d *dataTreeNavigator, context Context, path []interface{}) error {
+	deleteOpNode := &ExpressionNode{
+		Operation: &Operation{OperationType: deleteChildOpType},
+		RHS:       createTraversalTree(path, traversePreferences{DontFollowAlias: true}, false),
+	}
+	_, err := deleteChildOperator(d, context, deleteOpNode)
+	return err
+}
+
+func insertIntoSequence(node *CandidateNode, index int, value *CandidateNode) {
+	inserted := value.Copy()
+	inserted.Key = nil
+	node.AddChild(inserted)
+	inserted = node.Content[len(node.Content)-1]
+	copy(node.Content[index+1:], node.Content[index:len(node.Content)-1])
+	node.Content[index] = inserted
+	// the items after it have moved, so they need new index keys
+	for i, child := range node.Content {
+		child.Key = createScalarNode(i, fmt.Sprintf("%v", i))
+		child.Key.SetParent(node)
+	}
+}
+
+func jsonPatchAdd(d *dataTreeNavigator, context Context, root *CandidateNode, pointer string, value *CandidateNode) error {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return err
+	}
+	parent, err := resolveJSONPointer(root, tokens[:max(len(tokens)-1, 0)], pointer, false)
+	if err != nil {
+		return err
+	}
+	path, err := jsonPatchPath(root, tokens, pointer)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 || parent.Kind == MappingNode {
+		return assignJSONPatchPath(d, context, path, value)
+	}
+	token := tokens[len(tokens)-1]
+
+	if parent.Kind != SequenceNode {
+		return fmt.Errorf("path %v does not exist, cannot add '%v' to %v", pointer, token, parent.Tag)
+	}
+	if token == "-" {
+		path[len(path)-1] = len(parent.Content)
+		return assignJSONPatchPath(d, context, path, value)
+	}
+	if !jsonPatchIndexRegex.MatchString(token) {
+		return fmt.Errorf("path %v does not exist, '%v' is not an index", pointer, token)
+	}
+	index := path[len(path)-1].(int)
+	if index > len(parent.Content) {
+		return fmt.Errorf("path %v does not exist, index %v is out of bounds", pointer, index)
+	}
+	if index == len(parent.Content) {
+		return assignJSONPatchPath(d, context, path, value)
+	}
+	insertIntoSequence(parent, index, value)
+	return nil
+}
+
+func jsonPatchRemove(d *dataTreeNavigator, context Context, root *CandidateNode, pointer string) (*CandidateNode, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	removed, err := resolveJSONPointer(root, tokens, pointer, false)
+	if err != nil {
+		return nil, err
+	}
+	path, err := jsonPatchPath(root, tokens, pointer)
+	if err != nil {
+		return nil, err
+	}
+	if err := deleteJSONPatchPath(d, context, path); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func applyJSONPatchOperation(d *dataTreeNavigator, context Context, root *CandidateNode, operation jsonPatchOperation) error {
+	log.Debugf("applyJSONPatchOperation %v %v", operation.Op, operation.Path)
+	switch operation.Op {
+	case "add":
+		return jsonPatchAdd(d, context, root, operation.Path, operation.Value)
+	case "remove":
+		_, err := jsonPatchRemove(d, context, root, operation.Path)
+		return err
+	case "replace":
+		tokens, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := resolveJSONPointer(root, tokens, operation.Path, false); err != nil {
+			return err
+		}
+		path, err := jsonPatchPath(root, tokens, operation.Path)
+		if err != nil {
+			return err
+		}
+		return assignJSONPatchPath(d, context, path, operation.Value)
+	case "move":
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return fmt.Errorf("cannot move %v into one of its children %v", operation.From, operation.Path)
+		}
+		removed, err := jsonPatchRemove(d, context, root, operation.From)
+		if err != nil {
+			return err
+		}
+		return jsonPatchAdd(d, context, root, operation.Path, removed)
+	case "copy":
+		tokens, err := parseJSONPointer(operation.From)
+		if err != nil {
+			return err
+		}
+		value, err := resolveJSONPointer(root, tokens, operation.From, true)
+		if err != nil {
+			return err
+		}
+		return jsonPatchAdd(d, context, root, operation.Path, value.Copy())
+	case "test":
+		tokens, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return err
+		}
+		value, err := resolveJSONPointer(root, tokens, operation.Path, true)
+		if err != nil {
+			return err
+		}
+		if !recursiveNodeEqual(value, operation.Value) {
+			return fmt.Errorf("patch test failed, the value at %v is not the expected value", operation.Path)
+		}
+	}
+	return nil
+}
+
+// applyJSONPatch applies each operation in turn to a copy of the node, so either all of
+// them succeed or the node is left untouched.
+func applyJSONPatch(d *dataTreeNavigator, context Context, candidate *CandidateNode, patch *CandidateNode) (*CandidateNode, error) {
+	if patch.Kind != SequenceNode {
+		return nil, fmt.Errorf("a JSON Patch must be a list of operations, but got %v", patch.Tag)
+	}
+	root := candidate.Copy()
+	rootContext := context.SingleChildContext(root)
+	for index, operationNode := range patch.Content {
+		operation, err := parseJSONPatchOperation(operationNode)
+		if err != nil {
+			return nil, fmt.Errorf("bad patch operation [%v]: %w", index, err)
+		}
+		if err := applyJSONPatchOperation(d, rootContext, root, operation); err != nil {
+			return nil, fmt.Errorf("could not apply patch operation [%v] (%v %v): %w", index, operation.Op, operation.Path, err)
+		}
+	}
+	return root, nil
+}
+
+func patchOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("patchOperator")
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+
+		patches, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+		for patchEl := patches.MatchingNodes.Front(); patchEl != nil; patchEl = patchEl.Next() {
+			result, err := applyJSONPatch(d, context, candidate, patchEl.Value.(*CandidateNode))
+			if err != nil {
+				return Context{}, err
+			}
+			results.PushBack(result)
+		}
+	}
+	return context.ChildContext(results), nil
+}
+
+// applyMergePatch merges the patch into the target at the path as described by RFC 7396: maps
+// are merged recursively, null values delete keys and anything else replaces the target.
+func applyMergePatch(d *dataTreeNavigator, context Context, path []interface{}, target *CandidateNode, patch *CandidateNode) error {
+	if patch.Kind == AliasNode {
+		patch = patch.Alias
+	}
+	if patch.Kind != MappingNode {
+		return assignJSONPatchPath(d, context, path, patch)
+	}
+	if target.Kind != MappingNode {
+		if err := assignJSONPatchPath(d, context, path, &CandidateNode{Kind: MappingNode, Tag: "!!map"}); err != nil {
+			return err
+		}
+	}
+
+	for index := 0; index+1 < len(patch.Content); index = index + 2 {
+		key := patch.Content[index]
+		value := patch.Content[index+1]
+		keyPath := appendDiffPath(path, key.Value)
+		existing, err := jsonPatchChild(target, key.Value, key.Value, false)
+
+		switch {
+		case value.Tag == "!!null":
+			if err == nil {
+				if err := deleteJSONPatchPath(d, context, keyPath); err != nil {
+					return err
+				}
+			}
+		case err == nil:
+			if err := applyMergePatch(d, context, keyPath, existing, value); err != nil {
+				return err
+			}
+		default:
+			// added maps are merged into an empty map, to remove any nulls in them
+			if err := assignJSONPatchPath(d, context, keyPath, &CandidateNode{Kind: MappingNode, Tag: "!!map"}); err != nil {
+				return err
+			}
+			added, err := jsonPatchChild(target, key.Value, key.Value, false)
+			if err != nil {
+				return err
+			}
+			if err := applyMergePatch(d, context, keyPath, added, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func mergePatchOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("mergePatchOperator")
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+
+		patches, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+		for patchEl := patches.MatchingNodes.Front(); patchEl != nil; patchEl = patchEl.Next() {
+			result := candidate.Copy()
+			if err := applyMergePatch(d, context.SingleChildContext(result), make([]interface{}, 0), result, patchEl.Value.(*CandidateNode)); err != nil {
+				return Context{}, err
+			}
+			results.PushBack(result)
+		}
+	}
+	return context.ChildContext(results), nil
+}
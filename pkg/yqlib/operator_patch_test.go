@@ -0,0 +1,191 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var patchOperatorScenarios = []expressionScenario{
+	{
+		description:    "Apply a JSON Patch",
+		subdescription: "Comments and styles of the untouched nodes are kept",
+		document:       "# the app\nname: app\nreplicas: 1 # how many\ntags: [a, b]\n",
+		expression:     `patch([{"op": "replace", "path": "/replicas", "value": 3}, {"op": "add", "path": "/tags/1", "value": "x"}, {"op": "remove", "path": "/name"}])`,
+		expected: []string{
+			"D0, P[], (!!map)::# the app\nreplicas: 3 # how many\ntags: [a, x, b]\n",
+		},
+	},
+	{
+		description:    "Apply a JSON Patch from a file",
+		subdescription: "Patches can be in any format yq reads, like JSON or YAML",
+		document:       "spec:\n    containers:\n        - name: web\n          image: nginx:1.24\n",
+		expression:     `patch(load("../../examples/patch.json"))`,
+		expected: []string{
+			"D0, P[], (!!map)::spec:\n    containers:\n        - name: web\n          image: \"nginx:1.25\"\n          ports: [80]\n",
+		},
+	},
+	{
+		description:    "Move, copy and test",
+		subdescription: "A failed test stops the patch, and the node is left unchanged",
+		document:       "{a: 1, b: {c: 2}}",
+		expression:     `patch([{"op": "test", "path": "/a", "value": 1}, {"op": "move", "from": "/a", "path": "/b/a"}, {"op": "copy", "from": "/b", "path": "/d"}])`,
+		expected: []string{
+			"D0, P[], (!!map)::{b: {c: 2, a: 1}, d: {c: 2, a: 1}}\n",
+		},
+	},
+	{
+		description:    "Append to a sequence",
+		subdescription: "Use '-' as the index to add to the end of a sequence",
+		document:       "[a, b]",
+		expression:     `patch([{"op": "add", "path": "/-", "value": "c"}])`,
+		expected: []string{
+			"D0, P[], (!!seq)::[a, b, c]\n",
+		},
+	},
+	{
+		description:    "Apply the patch from diff",
+		subdescription: "`diff` generates the JSON Patch that `patch` applies",
+		document:       "{a: cat, b: [1, 2, 3]}",
+		expression:     `patch(diff({"a": "dog", "b": [1, 4]}))`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: dog, b: [1, 4]}\n",
+		},
+	},
+	{
+		description:    "Apply a JSON Merge Patch",
+		subdescription: "Maps are merged, null removes a key and anything else (including sequences) is replaced",
+		document:       "# the app\nname: app # the name\nreplicas: 1\nlabels:\n  tier: web\n  old: true\nports: [80, 443]\n",
+		expression:     `merge_patch({"replicas": 3, "labels": {"old": null, "team": "a"}, "ports": [8080]})`,
+		expected: []string{
+			"D0, P[], (!!map)::# the app\nname: app # the name\nreplicas: 3\nlabels:\n    tier: web\n    team: a\nports: [8080]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1}",
+		expression: `merge_patch({"b": {"c": 1, "d": null}})`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: 1, b: {c: 1}}\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1}",
+		expression: `merge_patch("cat")`,
+		expected: []string{
+			"D0, P[], (!!str)::cat\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: [1, 2]}",
+		expression: `.a | merge_patch({"b": 1})`,
+		expected: []string{
+			"D0, P[a], (!!map)::b: 1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a/b: {m~n: 1}}",
+		expression: `patch([{"op": "replace", "path": "/a~1b/m~0n", "value": 2}])`,
+		expected: []string{
+			"D0, P[], (!!map)::{a/b: {m~n: 2}}\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1}",
+		expression: `patch([{"op": "replace", "path": "", "value": [1]}])`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "[a, b, c]",
+		expression: `patch([{"op": "remove", "path": "/0"}, {"op": "add", "path": "/2", "value": "d"}])`,
+		expected: []string{
+			"D0, P[], (!!seq)::[b, c, d]\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "added items have index keys",
+		document:    "{l: [a, b]}",
+		expression:  `patch([{"op": "add", "path": "/l/-", "value": "c"}, {"op": "add", "path": "/l/0", "value": "z"}]) | [.l[] | path | join(".")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- l.0\n- l.1\n- l.2\n- l.3\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{l: [a, b]}",
+		expression: `patch([{"op": "add", "path": "/l/1", "value": "z"}]) | [.l[] | key | tag]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- '!!int'\n- '!!int'\n- '!!int'\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "replace does not merge maps",
+		document:    "{a: {b: 1, c: 2}}",
+		expression:  `patch([{"op": "replace", "path": "/a", "value": {"d": 1}}])`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: {d: 1}}\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch([{"op": "test", "path": "/a", "value": 2}])`,
+		expectedError: "could not apply patch operation [0] (test /a): patch test failed, the value at /a is not the expected value",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch([{"op": "remove", "path": "/b"}])`,
+		expectedError: "could not apply patch operation [0] (remove /b): path /b does not exist",
+	},
+	{
+		skipDoc:       true,
+		document:      "[1]",
+		expression:    `patch([{"op": "add", "path": "/3", "value": 2}])`,
+		expectedError: "could not apply patch operation [0] (add /3): path /3 does not exist, index 3 is out of bounds",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch([{"op": "add", "path": "/b/c", "value": 2}])`,
+		expectedError: "could not apply patch operation [0] (add /b/c): path /b/c does not exist",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch([{"op": "jump", "path": "/a"}])`,
+		expectedError: "bad patch operation [0]: unknown patch operation 'jump', must be one of add, remove, replace, move, copy or test",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch([{"op": "add", "path": "a", "value": 1}])`,
+		expectedError: "could not apply patch operation [0] (add a): invalid JSON Pointer a, it must be empty or start with '/'",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: {b: 1}}",
+		expression:    `patch([{"op": "move", "from": "/a", "path": "/a/b/c"}])`,
+		expectedError: "could not apply patch operation [0] (move /a/b/c): cannot move /a into one of its children /a/b/c",
+	},
+	{
+		skipDoc:       true,
+		document:      "{a: 1}",
+		expression:    `patch({"op": "remove", "path": "/a"})`,
+		expectedError: "a JSON Patch must be a list of operations, but got !!map",
+	},
+}
+
+func TestPatchOperatorScenarios(t *testing.T) {
+	for _, tt := range patchOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "patch", patchOperatorScenarios)
+}
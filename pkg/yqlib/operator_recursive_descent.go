@@ -2,6 +2,7 @@ package yqlib
 
 import (
 	"container/list"
+	"fmt"
 )
 
 type recursiveDescentPreferences struct {
@@ -44,3 +45,136 @@ func recursiveDecent(results *list.List, context Context, preferences recursiveD
 	}
 	return nil
 }
+
+// recurseFunction is recurse, recurse(f) and recurse(f; cond). Without f it is the
+// same as '..', otherwise it gives each node and then recurses into the results of f.
+func recurseFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("recurseFunction")
+	if len(args) == 0 {
+		results := list.New()
+		preferences := recursiveDescentPreferences{RecurseArray: true, TraversePreferences: traversePreferences{DontFollowAlias: true}}
+		if err := recursiveDecent(results, context, preferences); err != nil {
+			return false, err
+		}
+		return yieldAll(results, yield)
+	}
+
+	var condition *ExpressionNode
+	if len(args) == 2 {
+		condition = args[1]
+	}
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		more, err := recurseGenerator(d, context, el.Value.(*CandidateNode), args[0], condition, yield)
+		if !more || err != nil {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+func recurseGenerator(d *dataTreeNavigator, context Context, candidate *CandidateNode, expressionNode *ExpressionNode, condition *ExpressionNode, yield yieldFunc) (bool, error) {
+	if more, err := yield(candidate); !more || err != nil {
+		return more, err
+	}
+	return generate(d, context.SingleReadonlyChildContext(candidate), expressionNode, func(child *CandidateNode) (bool, error) {
+		if condition != nil {
+			// like select, only recurse into the children that match
+			matches, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(child), condition)
+			if err != nil {
+				return false, err
+			}
+			includeChild := false
+			for matchEl := matches.MatchingNodes.Front(); matchEl != nil && !includeChild; matchEl = matchEl.Next() {
+				includeChild = isTruthyNode(matchEl.Value.(*CandidateNode))
+			}
+			if !includeChild {
+				return true, nil
+			}
+		}
+		return recurseGenerator(d, context, child, expressionNode, condition, yield)
+	})
+}
+
+func walkFunction(d *dataTreeNavigator, context Context, args []*ExpressionNode, yield yieldFunc) (bool, error) {
+	log.Debugf("walkFunction")
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		results, err := walk(d, context, el.Value.(*CandidateNode), args[0])
+		if err != nil {
+			return false, err
+		}
+		more, err := yieldAll(results, yield)
+		if !more || err != nil {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+// walk rebuilds the node bottom up, applying the expression to each child before
+// its parent. The copies keep their comments, styles and anchors; aliases are left
+// as they are. Map entries whose value gives no results are removed.
+func walk(d *dataTreeNavigator, context Context, node *CandidateNode, expressionNode *ExpressionNode) (*list.List, error) {
+	if node.Kind == AliasNode {
+		results := list.New()
+		results.PushBack(node)
+		return results, nil
+	}
+
+	rebuilt := node.CopyWithoutContent()
+	switch node.Kind {
+	case MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			values, err := walk(d, context, node.Content[i+1], expressionNode)
+			if err != nil {
+				return nil, err
+			}
+			if values.Len() > 0 {
+				rebuilt.AddKeyValueChild(node.Content[i], values.Front().Value.(*CandidateNode))
+			}
+		}
+	case SequenceNode:
+		for _, child := range node.Content {
+			values, err := walk(d, context, child, expressionNode)
+			if err != nil {
+				return nil, err
+			}
+			for valueEl := values.Front(); valueEl != nil; valueEl = valueEl.Next() {
+				value := valueEl.Value.(*CandidateNode).Copy()
+				value.SetParent(rebuilt)
+				value.IsMapKey = false
+				index := len(rebuilt.Content)
+				value.Key = createScalarNode(index, fmt.Sprintf("%v", index))
+				value.Key.SetParent(rebuilt)
+				rebuilt.Content = append(rebuilt.Content, value)
+			}
+		}
+	}
+
+	result, err := d.GetMatchingNodes(context.SingleChildContext(rebuilt), expressionNode)
+	if err != nil {
+		return nil, err
+	}
+	results := list.New()
+	for el := result.MatchingNodes.Front(); el != nil; el = el.Next() {
+		results.PushBack(keepWalkedLayout(rebuilt, el.Value.(*CandidateNode)))
+	}
+	return results, nil
+}
+
+// keepWalkedLayout keeps the style, anchor and comments of the walked node when the
+// expression replaced it with a new node of the same kind, e.g. with with_entries.
+func keepWalkedLayout(walked *CandidateNode, result *CandidateNode) *CandidateNode {
+	if result == walked || result.Kind != walked.Kind || result.Kind == ScalarNode {
+		return result
+	}
+	if result.Style != 0 || result.Anchor != "" || result.HeadComment != "" || result.LineComment != "" || result.FootComment != "" {
+		return result
+	}
+	result = result.Copy()
+	result.Style = walked.Style
+	result.Anchor = walked.Anchor
+	result.HeadComment = walked.HeadComment
+	result.LineComment = walked.LineComment
+	result.FootComment = walked.FootComment
+	return result
+}
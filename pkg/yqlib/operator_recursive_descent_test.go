@@ -229,6 +229,122 @@ var recursiveDescentOperatorScenarios = []expressionScenario{
 	},
 }
 
+var walkRecurseOperatorScenarios = []expressionScenario{
+	{
+		description:    "Lowercase all keys",
+		subdescription: "Comments, styles and anchors are kept",
+		document:       "# people\nName: Bob # the boss\nDetails: &details {Age: 42}\nOther: *details\n",
+		expression:     `walk(if tag == "!!map" then with_entries(.key |= downcase) else . end)`,
+		expected: []string{
+			"D0, P[], (!!map)::# people\nname: Bob # the boss\ndetails: &details {age: 42}\nother: *details\n",
+		},
+	},
+	{
+		description: "Convert numeric strings to numbers",
+		document:    `{a: "1", b: ["2", "two"]}`,
+		expression:  `walk(if tag == "!!str" and test("^[0-9]+$") then to_number else . end)`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: 1, b: [2, \"two\"]}\n",
+		},
+	},
+	{
+		description:    "Remove nulls",
+		subdescription: "Map entries and array elements are removed when the expression gives no results",
+		document:       `{a: null, b: [1, null], c: {d: null, e: 2}}`,
+		expression:     `walk(select(. != null))`,
+		expected: []string{
+			"D0, P[], (!!map)::{b: [1], c: {e: 2}}\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Walk is bottom up",
+		document:    `{a: {b: 1}}`,
+		expression:  `[walk(if tag == "!!map" then length else . end)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Walk does not change the original",
+		document:    `{a: [1, 2]}`,
+		expression:  `walk(if tag == "!!int" then . + 1 else . end), .`,
+		expected: []string{
+			"D0, P[], (!!map)::{a: [2, 3]}\n",
+			"D0, P[], (!!map)::{a: [1, 2]}\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[1, 2]`,
+		expression: `.[] |= walk(. * 10)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[10, 20]\n",
+		},
+	},
+	{
+		description:    "Recurse",
+		subdescription: "Without an expression, recurse is the same as `..`",
+		document:       `{a: {b: 1}}`,
+		expression:     `[recurse | path | join(".")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- \"\"\n- a\n- a.b\n",
+		},
+	},
+	{
+		description:    "Recurse with a child expression",
+		subdescription: "Gives each node and then recurses into the results of the expression",
+		document:       `{name: root, children: [{name: a, children: [{name: b}]}, {name: c}]}`,
+		expression:     `[recurse(.children[]) | .name]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- root\n- a\n- b\n- c\n",
+		},
+	},
+	{
+		description:    "Recurse while a condition is true",
+		subdescription: "Only recurses into the results of the expression that match the condition",
+		expression:     `[2 | recurse(. * .; . < 100)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 2\n- 4\n- 16\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: {b: {c: 1}}}`,
+		expression: `first(recurse(.[]?) | select(tag == "!!int"))`,
+		expected: []string{
+			"D0, P[a b c], (!!int)::1\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "Recursing does not create missing entries",
+		document:    `{a: {b: null}}`,
+		expression:  `[recurse(.a)] | length, .`,
+		expected: []string{
+			"D0, P[], (!!int)::2\n",
+			"D0, P[], (!!map)::{a: {b: null}}\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{a: null}`,
+		expression: `[recurse(.[]?)] | length, .`,
+		expected: []string{
+			"D0, P[], (!!int)::2\n",
+			"D0, P[], (!!map)::{a: null}\n",
+		},
+	},
+}
+
+func TestWalkRecurseOperatorScenarios(t *testing.T) {
+	for _, tt := range walkRecurseOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "walk-and-recurse", walkRecurseOperatorScenarios)
+}
+
 func TestRecursiveDescentOperatorScenarios(t *testing.T) {
 	for _, tt := range recursiveDescentOperatorScenarios {
 		testScenario(t, &tt)
@@ -0,0 +1,272 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+func createStreamPathElement(pathElement *CandidateNode) *CandidateNode {
+	return &CandidateNode{Kind: ScalarNode, Tag: pathElement.Tag, Value: pathElement.Value}
+}
+
+// createStreamEvent creates a jq style stream event: [path, leaf] for a leaf value,
+// or [path] to mark the end of a map or sequence, where path is the path of its last entry.
+func createStreamEvent(path []*CandidateNode, leaf *CandidateNode) *CandidateNode {
+	pathNode := &CandidateNode{Kind: SequenceNode, Tag: "!!seq", Style: FlowStyle}
+	for _, pathElement := range path {
+		pathNode.AddChild(createStreamPathElement(pathElement))
+	}
+	event := &CandidateNode{Kind: SequenceNode, Tag: "!!seq", Style: FlowStyle}
+	event.AddChild(pathNode)
+	if leaf != nil {
+		leafCopy := leaf.Copy()
+		leafCopy.Key = nil
+		event.AddChild(leafCopy)
+	}
+	return event
+}
+
+func parseStreamEvent(event *CandidateNode) ([]*CandidateNode, *CandidateNode, error) {
+	if event.Kind != SequenceNode || len(event.Content) < 1 || len(event.Content) > 2 || event.Content[0].Kind != SequenceNode {
+		return nil, nil, fmt.Errorf("expected a stream event like [path, leaf] or [path], but got %v", event.Tag)
+	}
+	path := event.Content[0].Content
+	for _, pathElement := range path {
+		if pathElement.Kind != ScalarNode {
+			return nil, nil, fmt.Errorf("expected a stream event path of keys and indices, but got %v", pathElement.Tag)
+		}
+	}
+	if len(event.Content) == 1 {
+		return path, nil, nil
+	}
+	return path, event.Content[1], nil
+}
+
+func streamEvents(node *CandidateNode, path []*CandidateNode, emit func(*CandidateNode) error) error {
+	if node.Kind == AliasNode {
+		return streamEvents(node.Alias, path, emit)
+	}
+	if (node.Kind != MappingNode && node.Kind != SequenceNode) || len(node.Content) == 0 {
+		return emit(createStreamEvent(path, node))
+	}
+
+	var childPath []*CandidateNode
+	if node.Kind == MappingNode {
+		for index := 0; index+1 < len(node.Content); index = index + 2 {
+			childPath = append(path[:len(path):len(path)], node.Content[index])
+			if err := streamEvents(node.Content[index+1], childPath, emit); err != nil {
+				return err
+			}
+		}
+	} else {
+		for index, child := range node.Content {
+			childPath = append(path[:len(path):len(path)], createScalarNode(index, fmt.Sprintf("%v", index)))
+			if err := streamEvents(child, childPath, emit); err != nil {
+				return err
+			}
+		}
+	}
+	return emit(createStreamEvent(childPath, nil))
+}
+
+func toStreamOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("toStreamOperator")
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		err := streamEvents(el.Value.(*CandidateNode), []*CandidateNode{}, func(event *CandidateNode) error {
+			results.PushBack(event)
+			return nil
+		})
+		if err != nil {
+			return Context{}, err
+		}
+	}
+	return context.ChildContext(results), nil
+}
+
+// streamBuilder puts values back together from stream events
+type streamBuilder struct {
+	root *CandidateNode
+}
+
+func newStreamContainer(pathElement *CandidateNode) *CandidateNode {
+	if pathElement.Tag == "!!int" {
+		return &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+	}
+	return &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+}
+
+func getStreamChild(parent *CandidateNode, pathElement *CandidateNode) (*CandidateNode, error) {
+	if parent.Kind == SequenceNode {
+		index, err := parseInt(pathElement.Value)
+		if err != nil || pathElement.Tag != "!!int" {
+			return nil, fmt.Errorf("cannot index a sequence with %v", pathElement.Value)
+		}
+		if index < len(parent.Content) {
+			return parent.Content[index], nil
+		}
+		return nil, nil
+	}
+	if parent.Kind != MappingNode {
+		return nil, fmt.Errorf("cannot set %v on a %v", pathElement.Value, parent.Tag)
+	}
+	// events for a map come in order, so the key is most likely the last one
+	for index := len(parent.Content) - 2; index >= 0; index = index - 2 {
+		if parent.Content[index].Value == pathElement.Value {
+			return parent.Content[index+1], nil
+		}
+	}
+	return nil, nil
+}
+
+func setStreamChild(parent *CandidateNode, pathElement *CandidateNode, value *CandidateNode) {
+	value.Parent = parent
+	if parent.Kind == SequenceNode {
+		index, _ := parseInt(pathElement.Value)
+		for len(parent.Content) < index {
+			parent.AddChild(createScalarNode(nil, "null"))
+		}
+		value.Key = createScalarNode(index, pathElement.Value)
+		value.Key.Parent = parent
+		if index < len(parent.Content) {
+			parent.Content[index] = value
+		} else {
+			parent.Content = append(parent.Content, value)
+		}
+		return
+	}
+	for index := len(parent.Content) - 2; index >= 0; index = index - 2 {
+		if parent.Content[index].Value == pathElement.Value {
+			value.Key = parent.Content[index]
+			parent.Content[index+1] = value
+			return
+		}
+	}
+	key := createStreamPathElement(pathElement)
+	key.Parent = parent
+	key.IsMapKey = true
+	value.Key = key
+	parent.Content = append(parent.Content, key, value)
+}
+
+// add applies the event, returning the value when it is complete.
+func (b *streamBuilder) add(path []*CandidateNode, leaf *CandidateNode) (*CandidateNode, error) {
+	if len(path) == 0 {
+		if leaf == nil {
+			return nil, nil
+		}
+		b.root = nil
+		value := leaf.Copy()
+		value.Key = nil
+		value.Parent = nil
+		return value, nil
+	}
+
+	if leaf != nil {
+		if b.root == nil {
+			b.root = newStreamContainer(path[0])
+		}
+		current := b.root
+		for index, pathElement := range path[:len(path)-1] {
+			child, err := getStreamChild(current, pathElement)
+			if err != nil {
+				return nil, err
+			}
+			if child == nil {
+				child = newStreamContainer(path[index+1])
+				setStreamChild(current, pathElement, child)
+			}
+			current = child
+		}
+		if _, err := getStreamChild(current, path[len(path)-1]); err != nil {
+			return nil, err
+		}
+		value := leaf.Copy()
+		setStreamChild(current, path[len(path)-1], value)
+	}
+
+	// the closing event of the top level map or sequence
+	if leaf == nil && len(path) == 1 {
+		value := b.root
+		b.root = nil
+		if value == nil {
+			return nil, nil
+		}
+		return value, nil
+	}
+	return nil, nil
+}
+
+func fromStreamOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("fromStreamOperator")
+	// events for a single value may come from many documents, so evaluate them all together
+	eventsContext, err := d.GetMatchingNodes(context.ReadOnlyClone(), expressionNode.RHS)
+	if err != nil {
+		return Context{}, err
+	}
+
+	builder := &streamBuilder{}
+	results := list.New()
+	for el := eventsContext.MatchingNodes.Front(); el != nil; el = el.Next() {
+		path, leaf, err := parseStreamEvent(el.Value.(*CandidateNode))
+		if err != nil {
+			return Context{}, err
+		}
+		value, err := builder.add(path, leaf)
+		if err != nil {
+			return Context{}, err
+		}
+		if value != nil {
+			results.PushBack(value)
+		}
+	}
+	return context.ChildContext(results), nil
+}
+
+func truncateStreamEvents(depthNode *CandidateNode, events *list.List, results *list.List) error {
+	depth, err := parseInt(depthNode.Value)
+	if err != nil || depthNode.Tag != "!!int" {
+		return fmt.Errorf("truncate_stream depth must be an int, got %v", depthNode.Value)
+	}
+	for el := events.Front(); el != nil; el = el.Next() {
+		path, leaf, err := parseStreamEvent(el.Value.(*CandidateNode))
+		if err != nil {
+			return err
+		}
+		if len(path) > depth {
+			results.PushBack(createStreamEvent(path[depth:], leaf))
+		}
+	}
+	return nil
+}
+
+// truncate_stream(depth; events), or depth | truncate_stream(events) like jq
+func truncateStreamOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("truncateStreamOperator")
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		depthExpression := &ExpressionNode{Operation: &Operation{OperationType: selfReferenceOpType}}
+		eventsExpression := expressionNode.RHS
+		if expressionNode.RHS.Operation.OperationType == blockOpType {
+			depthExpression = expressionNode.RHS.LHS
+			eventsExpression = expressionNode.RHS.RHS
+		}
+
+		depths, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), depthExpression)
+		if err != nil {
+			return Context{}, err
+		}
+		events, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), eventsExpression)
+		if err != nil {
+			return Context{}, err
+		}
+		for depthEl := depths.MatchingNodes.Front(); depthEl != nil; depthEl = depthEl.Next() {
+			if err := truncateStreamEvents(depthEl.Value.(*CandidateNode), events.MatchingNodes, results); err != nil {
+				return Context{}, err
+			}
+		}
+	}
+	return context.ChildContext(results), nil
+}
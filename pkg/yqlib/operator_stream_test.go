@@ -0,0 +1,105 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var streamOperatorScenarios = []expressionScenario{
+	{
+		description:    "To stream",
+		subdescription: "Each leaf is returned as [path, leaf], and the end of each map or sequence is marked with [path of its last entry]",
+		document:       `{a: [1, {b: 2}]}`,
+		expression:     `tostream`,
+		expected: []string{
+			"D0, P[], (!!seq)::[[a, 0], 1]\n",
+			"D0, P[], (!!seq)::[[a, 1, b], 2]\n",
+			"D0, P[], (!!seq)::[[a, 1, b]]\n",
+			"D0, P[], (!!seq)::[[a, 1]]\n",
+			"D0, P[], (!!seq)::[[a]]\n",
+		},
+	},
+	{
+		description: "Empty maps and sequences are leaves",
+		document:    `{a: {}, b: []}`,
+		expression:  `tostream`,
+		expected: []string{
+			"D0, P[], (!!seq)::[[a], {}]\n",
+			"D0, P[], (!!seq)::[[b], []]\n",
+			"D0, P[], (!!seq)::[[b]]\n",
+		},
+	},
+	{
+		description:    "From stream",
+		subdescription: "Puts the values back together from the events",
+		document:       `{a: [1, {b: 2}], c: cat}`,
+		expression:     `fromstream(tostream)`,
+		expected: []string{
+			"D0, P[], (!!map)::a:\n    - 1\n    - b: 2\nc: cat\n",
+		},
+	},
+	{
+		description: "Update the leaves of a stream",
+		document:    `{a: [1, 2], b: 3}`,
+		expression:  `fromstream(tostream | select(length == 2) |= (.[1] |= . * 10))`,
+		expected: []string{
+			"D0, P[], (!!map)::a:\n    - 10\n    - 20\nb: 30\n",
+		},
+	},
+	{
+		description:    "Truncate stream",
+		subdescription: "Removes the given number of path elements from the front of each event, dropping events that are not deep enough. Like jq, the depth can also be given as the input: `1 | truncate_stream(events)`",
+		document:       `{a: [1, {b: 2}]}`,
+		expression:     `[truncate_stream(1; tostream)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- [[0], 1]\n- [[1, b], 2]\n- [[1, b]]\n- [[1]]\n",
+		},
+	},
+	{
+		description:    "Get the entries of a sequence one at a time",
+		subdescription: "Truncating the stream of a sequence and putting it back together returns each entry",
+		document:       `{a: [{name: cat}, {name: dog}]}`,
+		expression:     `fromstream(truncate_stream(2; tostream))`,
+		expected: []string{
+			"D0, P[], (!!map)::name: cat\n",
+			"D0, P[], (!!map)::name: dog\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `{}`,
+		expression: `[1 | truncate_stream([[0], 1], [[1, 0], 2], [[1, 0]], [[1]])]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- [[0], 2]\n- [[0]]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `cat`,
+		expression: `[tostream], [fromstream(tostream)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- [[], cat]\n",
+			"D0, P[], (!!seq)::- cat\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[]`,
+		expression: `fromstream(tostream)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[]\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{}`,
+		expression:    `fromstream("cat")`,
+		expectedError: "expected a stream event like [path, leaf] or [path], but got !!str",
+	},
+}
+
+func TestStreamOperatorScenarios(t *testing.T) {
+	for _, tt := range streamOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "stream", streamOperatorScenarios)
+}
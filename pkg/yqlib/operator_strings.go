@@ -4,7 +4,10 @@ import (
 	"container/list"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var StringInterpolationEnabled = true
@@ -13,6 +16,12 @@ type changeCasePrefs struct {
 	ToUpperCase bool
 }
 
+type trimPreferences struct {
+	Name  string
+	Left  bool
+	Right bool
+}
+
 func encodeToYamlString(node *CandidateNode) (string, error) {
 	encoderPrefs := encoderPreferences{
 		format: YamlFormat,
@@ -138,16 +147,24 @@ func stringInterpolationOperator(d *dataTreeNavigator, context Context, expressi
 	return context.ChildContext(results), nil
 }
 
-func trimSpaceOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+func trimSpaceOperator(_ *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(trimPreferences)
 	results := list.New()
 	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
 		node := el.Value.(*CandidateNode)
 
 		if node.guessTagFromCustomType() != "!!str" {
-			return Context{}, fmt.Errorf("cannot trim %v, can only operate on strings. ", node.Tag)
+			return Context{}, fmt.Errorf("cannot %v %v, can only operate on strings. ", prefs.Name, node.Tag)
 		}
 
-		newStringNode := node.CreateReplacement(ScalarNode, node.Tag, strings.TrimSpace(node.Value))
+		value := node.Value
+		if prefs.Left {
+			value = strings.TrimLeftFunc(value, unicode.IsSpace)
+		}
+		if prefs.Right {
+			value = strings.TrimRightFunc(value, unicode.IsSpace)
+		}
+		newStringNode := node.CreateReplacement(ScalarNode, node.Tag, value)
 		newStringNode.Style = node.Style
 		results.PushBack(newStringNode)
 
@@ -575,3 +592,274 @@ func split(value string, spltStr string) (Kind, string, []*CandidateNode) {
 
 	return SequenceNode, "!!seq", contents
 }
+
+// stringArgumentOperator evaluates the argument against each node, and gives the
+// result of the function for the node and each of the argument's results.
+func stringArgumentOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode, apply func(node *CandidateNode, arg *CandidateNode) (*CandidateNode, error)) (Context, error) {
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		args, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(node), expressionNode.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+		for argEl := args.MatchingNodes.Front(); argEl != nil; argEl = argEl.Next() {
+			result, err := apply(node, argEl.Value.(*CandidateNode))
+			if err != nil {
+				return Context{}, err
+			}
+			results.PushBack(result)
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func checkStringArgument(operation string, node *CandidateNode, arg *CandidateNode) error {
+	if node.guessTagFromCustomType() != "!!str" {
+		return fmt.Errorf("cannot %v %v, can only operate on strings", operation, node.Tag)
+	}
+	if arg.guessTagFromCustomType() != "!!str" {
+		return fmt.Errorf("%v needs a string, got %v", operation, arg.Tag)
+	}
+	return nil
+}
+
+func startsWithOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("startsWithOperator")
+	return stringArgumentOperator(d, context, expressionNode, func(node *CandidateNode, prefix *CandidateNode) (*CandidateNode, error) {
+		if err := checkStringArgument("startswith", node, prefix); err != nil {
+			return nil, err
+		}
+		return createBooleanCandidate(node, strings.HasPrefix(node.Value, prefix.Value)), nil
+	})
+}
+
+func endsWithOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("endsWithOperator")
+	return stringArgumentOperator(d, context, expressionNode, func(node *CandidateNode, suffix *CandidateNode) (*CandidateNode, error) {
+		if err := checkStringArgument("endswith", node, suffix); err != nil {
+			return nil, err
+		}
+		return createBooleanCandidate(node, strings.HasSuffix(node.Value, suffix.Value)), nil
+	})
+}
+
+// trimAffix removes the prefix (or suffix) when the node starts (or ends) with it. Like jq,
+// anything that is not a string is left as it is.
+func trimAffix(node *CandidateNode, affix *CandidateNode, trim func(string, string) string) *CandidateNode {
+	if node.guessTagFromCustomType() != "!!str" || affix.guessTagFromCustomType() != "!!str" {
+		return node
+	}
+	trimmed := trim(node.Value, affix.Value)
+	if trimmed == node.Value {
+		return node
+	}
+	result := node.CreateReplacement(ScalarNode, node.Tag, trimmed)
+	result.Style = node.Style
+	return result
+}
+
+func trimPrefixOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("trimPrefixOperator")
+	return stringArgumentOperator(d, context, expressionNode, func(node *CandidateNode, prefix *CandidateNode) (*CandidateNode, error) {
+		return trimAffix(node, prefix, strings.TrimPrefix), nil
+	})
+}
+
+func trimSuffixOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("trimSuffixOperator")
+	return stringArgumentOperator(d, context, expressionNode, func(node *CandidateNode, suffix *CandidateNode) (*CandidateNode, error) {
+		return trimAffix(node, suffix, strings.TrimSuffix), nil
+	})
+}
+
+type indexPreferences struct {
+	Name string
+	// index gives the first, rindex the last and indices all of them
+	First bool
+	Last  bool
+}
+
+// stringIndices finds where the substring is, including where the matches overlap. The
+// indices count codepoints rather than bytes.
+func stringIndices(value string, substring string) []int {
+	indices := make([]int, 0)
+	if substring == "" {
+		return indices
+	}
+	for offset := 0; offset < len(value); {
+		found := strings.Index(value[offset:], substring)
+		if found < 0 {
+			break
+		}
+		position := offset + found
+		indices = append(indices, utf8.RuneCountInString(value[:position]))
+		_, size := utf8.DecodeRuneInString(value[position:])
+		offset = position + size
+	}
+	return indices
+}
+
+// arrayIndices finds where the array contains the given sequence of items, or the item
+// when it is not an array.
+func arrayIndices(node *CandidateNode, item *CandidateNode) []int {
+	wanted := []*CandidateNode{item}
+	if item.Kind == SequenceNode {
+		wanted = item.Content
+	}
+	indices := make([]int, 0)
+	if len(wanted) == 0 {
+		return indices
+	}
+	for start := 0; start+len(wanted) <= len(node.Content); start++ {
+		matches := true
+		for i := 0; i < len(wanted) && matches; i++ {
+			matches = recursiveNodeEqual(node.Content[start+i], wanted[i])
+		}
+		if matches {
+			indices = append(indices, start)
+		}
+	}
+	return indices
+}
+
+func indexOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	prefs := expressionNode.Operation.Preferences.(indexPreferences)
+	log.Debugf("indexOperator %v", prefs.Name)
+
+	return stringArgumentOperator(d, context, expressionNode, func(node *CandidateNode, arg *CandidateNode) (*CandidateNode, error) {
+		var indices []int
+		switch {
+		case node.Tag == "!!null":
+			return node.CreateReplacement(ScalarNode, "!!null", "null"), nil
+		case node.Kind == SequenceNode:
+			indices = arrayIndices(node, arg)
+		case node.guessTagFromCustomType() == "!!str":
+			if arg.guessTagFromCustomType() != "!!str" {
+				return nil, fmt.Errorf("cannot %v %v in a string, can only find strings", prefs.Name, arg.Tag)
+			}
+			indices = stringIndices(node.Value, arg.Value)
+		default:
+			return nil, fmt.Errorf("cannot %v in %v, can only operate on strings and arrays", prefs.Name, node.Tag)
+		}
+
+		if prefs.First || prefs.Last {
+			if len(indices) == 0 {
+				return node.CreateReplacement(ScalarNode, "!!null", "null"), nil
+			}
+			index := indices[0]
+			if prefs.Last {
+				index = indices[len(indices)-1]
+			}
+			return node.CreateReplacement(ScalarNode, "!!int", strconv.Itoa(index)), nil
+		}
+
+		result := node.CreateReplacement(SequenceNode, "!!seq", "")
+		for _, index := range indices {
+			result.AddChild(createScalarNode(index, strconv.Itoa(index)))
+		}
+		return result, nil
+	})
+}
+
+func explodeStringOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("explodeStringOperator")
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		if node.guessTagFromCustomType() != "!!str" {
+			return Context{}, fmt.Errorf("cannot explode %v, can only explode strings into codepoints. To explode anchors, use explode(.)", node.Tag)
+		}
+		result := node.CreateReplacement(SequenceNode, "!!seq", "")
+		for _, codepoint := range node.Value {
+			result.AddChild(createScalarNode(int(codepoint), strconv.Itoa(int(codepoint))))
+		}
+		results.PushBack(result)
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func getCodepoint(node *CandidateNode, operation string) (rune, error) {
+	if node.Kind != ScalarNode || node.guessTagFromCustomType() != "!!int" {
+		return 0, fmt.Errorf("cannot %v %v, codepoints must be ints", operation, node.Tag)
+	}
+	_, codepoint, err := parseInt64(node.Value)
+	if err != nil {
+		return 0, err
+	}
+	if codepoint < 0 || codepoint > unicode.MaxRune || !utf8.ValidRune(rune(codepoint)) {
+		return 0, fmt.Errorf("cannot %v %v, it is not a valid codepoint", operation, node.Value)
+	}
+	return rune(codepoint), nil
+}
+
+func implodeOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("implodeOperator")
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		if node.Kind != SequenceNode {
+			return Context{}, fmt.Errorf("cannot implode %v, can only implode arrays of codepoints", node.Tag)
+		}
+		var builder strings.Builder
+		for _, child := range node.Content {
+			codepoint, err := getCodepoint(child, "implode")
+			if err != nil {
+				return Context{}, err
+			}
+			builder.WriteRune(codepoint)
+		}
+		results.PushBack(node.CreateReplacement(ScalarNode, "!!str", builder.String()))
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func asciiOperator(_ *dataTreeNavigator, context Context, _ *ExpressionNode) (Context, error) {
+	log.Debugf("asciiOperator")
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		codepoint, err := getCodepoint(node, "ascii")
+		if err != nil {
+			return Context{}, err
+		}
+		if codepoint > unicode.MaxASCII {
+			return Context{}, fmt.Errorf("cannot ascii %v, only 0 to 127 are ascii", node.Value)
+		}
+		results.PushBack(node.CreateReplacement(ScalarNode, "!!str", string(codepoint)))
+	}
+
+	return context.ChildContext(results), nil
+}
+
+func splitsOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("splitsOperator")
+	regEx, _, err := extractMatchArguments(d, context, expressionNode)
+	if err != nil {
+		return Context{}, err
+	}
+
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		node := el.Value.(*CandidateNode)
+		if node.Tag == "!!null" {
+			continue
+		}
+		if node.guessTagFromCustomType() != "!!str" {
+			return Context{}, fmt.Errorf("cannot split %v, can only split strings", node.Tag)
+		}
+		for _, part := range regEx.Split(node.Value, -1) {
+			results.PushBack(createStringScalarNode(part))
+		}
+	}
+
+	return context.ChildContext(results), nil
+}
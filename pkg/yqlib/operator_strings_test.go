@@ -136,6 +136,206 @@ var stringsOperatorScenarios = []expressionScenario{
 			"D0, P[3], (!!str)::horse\n",
 		},
 	},
+	{
+		description: "Trim the start or end of strings",
+		document:    `[" cat ", "dog "]`,
+		expression:  `[.[] | ltrim], [.[] | rtrim]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- \"cat \"\n- \"dog \"\n",
+			"D0, P[], (!!seq)::- \" cat\"\n- \"dog\"\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: 1}`,
+		expression:    `.a | ltrim`,
+		expectedError: "cannot ltrim !!int, can only operate on strings. ",
+	},
+	{
+		description:    "Starts with",
+		subdescription: "A plain string match, without the need to escape a regex",
+		document:       `[gcr.io/app:1.0, docker.io/app:2.0]`,
+		expression:     `.[] | select(startswith("gcr.io/"))`,
+		expected: []string{
+			"D0, P[0], (!!str)::gcr.io/app:1.0\n",
+		},
+	},
+	{
+		description: "Ends with",
+		document:    `[app.yaml, app.json]`,
+		expression:  `.[] | endswith(".yaml")`,
+		expected: []string{
+			"D0, P[0], (!!bool)::true\n",
+			"D0, P[1], (!!bool)::false\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "The argument is evaluated against each string",
+		document:    `[{name: catdog, prefix: cat}, {name: dog, prefix: cat}]`,
+		expression:  `.[] | .name | startswith(parent | .prefix)`,
+		expected: []string{
+			"D0, P[0 name], (!!bool)::true\n",
+			"D0, P[1 name], (!!bool)::false\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: 1}`,
+		expression:    `.a | startswith("1")`,
+		expectedError: "cannot startswith !!int, can only operate on strings",
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: cat}`,
+		expression:    `.a | endswith(1)`,
+		expectedError: "endswith needs a string, got !!int",
+	},
+	{
+		description:    "Remove a prefix or suffix",
+		subdescription: "Strings that do not start (or end) with it are left as they are",
+		document:       `[gcr.io/app:latest, docker.io/db:1.0]`,
+		expression:     `.[] |= (ltrimstr("gcr.io/") | rtrimstr(":latest"))`,
+		expected: []string{
+			"D0, P[], (!!seq)::[app, 'docker.io/db:1.0']\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[1, null, "cat"]`,
+		expression: `.[] | ltrimstr("c")`,
+		expected: []string{
+			"D0, P[0], (!!int)::1\n",
+			"D0, P[1], (!!null)::null\n",
+			"D0, P[2], (!!str)::at\n",
+		},
+	},
+	{
+		description:    "Find a substring",
+		subdescription: "index and rindex give the first and last position, or null if it is not found. Positions count codepoints, and matches can overlap.",
+		document:       `a,b, cd, efg`,
+		expression:     `[index(", "), rindex(", "), indices(", "), index("z")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 3\n- 7\n- - 3\n  - 7\n- null\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `héllo, aaa`,
+		expression: `[index("l"), indices("aa"), indices("")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 2\n- - 7\n  - 8\n- []\n",
+		},
+	},
+	{
+		description:    "Find in an array",
+		subdescription: "Finds the positions of an item, or of a sequence of items when given an array",
+		document:       `[0, 1, 2, 1, 3, 1, 2]`,
+		expression:     `[indices(1), indices([1, 2]), index(3)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- - 1\n  - 3\n  - 5\n- - 1\n  - 5\n- 4\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[{a: 1}, {a: 2}]`,
+		expression: `index({"a": 2})`,
+		expected: []string{
+			"D0, P[], (!!int)::1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `null | index("a")`,
+		expected: []string{
+			"D0, P[], (!!null)::null\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: 1}`,
+		expression:    `index("a")`,
+		expectedError: "cannot index in !!map, can only operate on strings and arrays",
+	},
+	{
+		skipDoc:       true,
+		document:      `cat`,
+		expression:    `indices(1)`,
+		expectedError: "cannot indices !!int in a string, can only find strings",
+	},
+	{
+		description:    "Explode a string into codepoints",
+		subdescription: "Without an argument, explode gives the codepoints of a string. With one, it explodes anchors.",
+		document:       `héllo`,
+		expression:     `explode`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 104\n- 233\n- 108\n- 108\n- 111\n",
+		},
+	},
+	{
+		description: "Implode codepoints into a string",
+		document:    `[104, 233, 108, 108, 111]`,
+		expression:  `implode`,
+		expected: []string{
+			"D0, P[], (!!str)::héllo\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `cat`,
+		expression: `explode | map(. + 1) | implode`,
+		expected: []string{
+			"D0, P[], (!!str)::dbu\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `{a: 1}`,
+		expression:    `.a | explode`,
+		expectedError: "cannot explode !!int, can only explode strings into codepoints. To explode anchors, use explode(.)",
+	},
+	{
+		skipDoc:       true,
+		document:      `[104, cat]`,
+		expression:    `implode`,
+		expectedError: "cannot implode !!str, codepoints must be ints",
+	},
+	{
+		skipDoc:       true,
+		document:      `[-1]`,
+		expression:    `implode`,
+		expectedError: "cannot implode -1, it is not a valid codepoint",
+	},
+	{
+		description: "Ascii character of a codepoint",
+		document:    `[72, 105]`,
+		expression:  `map(ascii) | join("")`,
+		expected: []string{
+			"D0, P[], (!!str)::Hi\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		expression:    `233 | ascii`,
+		expectedError: "cannot ascii 233, only 0 to 127 are ascii",
+	},
+	{
+		description:    "Split with a regex",
+		subdescription: "Gives each part as a separate result",
+		document:       `a1b22c`,
+		expression:     `[splits("[0-9]+")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- b\n- c\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `[cat, null]`,
+		expression: `[.[] | splits(", *")]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- cat\n",
+		},
+	},
 	{
 		skipDoc:    true,
 		document:   `[!horse cat, !goat meow, !frog 1, null, true]`,
@@ -0,0 +1,89 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+func tryOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("tryOperator")
+	return tryCatch(d, context, expressionNode.RHS, nil)
+}
+
+func catchOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("catchOperator")
+	if expressionNode.LHS.Operation.OperationType != tryOpType {
+		return Context{}, fmt.Errorf("catch must follow a try expression, e.g. try error(\"oops\") catch .")
+	}
+	return tryCatch(d, context, expressionNode.LHS.RHS, expressionNode.RHS)
+}
+
+// evaluateUntilError evaluates the expression like GetMatchingNodes, but on an error also
+// returns the results given before it, as jq does. Unions and pipes are followed so their
+// earlier results are kept, other expressions give all of their results or none.
+func evaluateUntilError(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	switch {
+	case expressionNode == nil || expressionNode.Operation == nil:
+	case expressionNode.Operation.OperationType == unionOpType:
+		lhs, err := evaluateUntilError(d, context, expressionNode.LHS)
+		if err != nil {
+			return lhs, err
+		}
+		rhs, err := evaluateUntilError(d, context, expressionNode.RHS)
+		results := lhs.ChildContext(list.New())
+		results.MatchingNodes.PushBackList(lhs.MatchingNodes)
+		// like the union operator, both sides may have modified the context instead of
+		// creating their own.
+		if rhs.MatchingNodes != nil && rhs.MatchingNodes != lhs.MatchingNodes {
+			results.MatchingNodes.PushBackList(rhs.MatchingNodes)
+		}
+		return results, err
+	case expressionNode.Operation.OperationType == pipeOpType &&
+		expressionNode.LHS.Operation.OperationType != assignVariableOpType:
+		lhs, lhsErr := evaluateUntilError(d, context, expressionNode.LHS)
+		if lhsErr != nil && (lhs.MatchingNodes == nil || lhs.MatchingNodes.Len() == 0) {
+			return context.ChildContext(list.New()), lhsErr
+		}
+		rhs, err := evaluateUntilError(d, context.ChildContext(lhs.MatchingNodes), expressionNode.RHS)
+		if err != nil {
+			return rhs, err
+		}
+		return context.ChildContext(rhs.MatchingNodes), lhsErr
+	}
+	results, err := d.GetMatchingNodes(context, expressionNode)
+	if err != nil {
+		return context.ChildContext(list.New()), err
+	}
+	return results, nil
+}
+
+// tryCatch evaluates the try expression against each candidate separately, so an error
+// in one candidate does not stop the others. Like jq, the results before an error are
+// kept. The catch expression (if any) is given the error message as a string.
+func tryCatch(d *dataTreeNavigator, context Context, tryExp *ExpressionNode, catchExp *ExpressionNode) (Context, error) {
+	var results = list.New()
+
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+		tryResults, err := evaluateUntilError(d, context.SingleChildContext(candidate), tryExp)
+		if tryResults.MatchingNodes != nil {
+			results.PushBackList(tryResults.MatchingNodes)
+		}
+		if err == nil {
+			continue
+		}
+		log.Debugf("try caught error: %v", err)
+		if catchExp == nil {
+			continue
+		}
+
+		errorNode := createStringScalarNode(err.Error())
+		catchResults, err := d.GetMatchingNodes(context.SingleChildContext(errorNode), catchExp)
+		if err != nil {
+			return Context{}, err
+		}
+		results.PushBackList(catchResults.MatchingNodes)
+	}
+
+	return context.ChildContext(results), nil
+}
@@ -0,0 +1,111 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var tryCatchOperatorScenarios = []expressionScenario{
+	{
+		description:    "Catch an error",
+		subdescription: "The error message is given to the catch expression as a string",
+		expression:     `try error("something went wrong") catch ("caught: " + .)`,
+		expected: []string{
+			"D0, P[], (!!str)::caught: something went wrong\n",
+		},
+	},
+	{
+		description:    "Try without catch",
+		subdescription: "Errors are suppressed, and nothing is returned for that node",
+		document:       `[{name: a, port: 80}, {name: b, port: http}, {name: c, port: 443}]`,
+		expression:     `[.[] | try (if .port | tag == "!!int" then .name else error(.name + " has a bad port") end)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- a\n- c\n",
+		},
+	},
+	{
+		description:    "Mark bad records and carry on",
+		subdescription: "Each node is evaluated separately, so an error in one does not stop the others",
+		document:       `[{name: a, port: 80}, {name: b, port: http}]`,
+		expression:     `.[] |= try (if .port | tag == "!!int" then . else error(.name + " has a bad port") end) catch {"error": .}`,
+		expected: []string{
+			"D0, P[], (!!seq)::[{name: a, port: 80}, {error: b has a bad port}]\n",
+		},
+	},
+	{
+		description:    "Postfix ? operator",
+		subdescription: "`exp?` is shorthand for `try exp`, and works on any expression",
+		document:       `[1, two, 3]`,
+		expression:     `map((if tag == "!!int" then . else error("not a number") end)?)`,
+		expected: []string{
+			"D0, P[], (!!seq)::[1, 3]\n",
+		},
+	},
+	{
+		description:    "Results before an error are kept",
+		subdescription: "Like jq, try stops at the first error, keeping the results given before it",
+		expression:     `[try (1, error("x"), 3)]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		expression: `[(1, error("x"))?], [try ((1, error("x"), 3) | . + 1)], [try (1, error("x")) catch .]`,
+		expected: []string{
+			"D0, P[], (!!seq)::- 1\n",
+			"D0, P[], (!!seq)::- 2\n",
+			"D0, P[], (!!seq)::- 1\n- x\n",
+		},
+	},
+	{
+		description: "Provide a default when an expression fails",
+		document:    `a: cat`,
+		expression:  `(error("nope") | .a)? // "default"`,
+		expected: []string{
+			"D0, P[], (!!str)::default\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `a: {b: cat}`,
+		expression: `(.a)?.b`,
+		expected: []string{
+			"D0, P[a b], (!!str)::cat\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `a: {b: cat}`,
+		expression: `try .a.b`,
+		expected: []string{
+			"D0, P[a b], (!!str)::cat\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   `a: cat`,
+		expression: `try error("x") catch . | length`,
+		expected: []string{
+			"D0, P[], (!!int)::1\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      `a: cat`,
+		expression:    `try error("x") catch error("rethrown " + .)`,
+		expectedError: "rethrown x",
+	},
+	{
+		skipDoc:       true,
+		document:      `a: cat`,
+		expression:    `.a catch .`,
+		expectedError: "catch must follow a try expression, e.g. try error(\"oops\") catch .",
+	},
+}
+
+func TestTryCatchOperatorScenarios(t *testing.T) {
+	for _, tt := range tryCatchOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "try-catch", tryCatchOperatorScenarios)
+}
@@ -0,0 +1,43 @@
+package yqlib
+
+import (
+	"container/list"
+	"fmt"
+)
+
+func schemaErrorsToNode(errors []SchemaValidationError) *CandidateNode {
+	errorsNode := &CandidateNode{Kind: SequenceNode, Tag: "!!seq"}
+	for _, validationError := range errors {
+		errorNode := &CandidateNode{Kind: MappingNode, Tag: "!!map"}
+		errorNode.AddKeyValueChild(createStringScalarNode("path"), createStringScalarNode(validationError.Path))
+		errorNode.AddKeyValueChild(createStringScalarNode("line"), createScalarNode(validationError.Line, fmt.Sprintf("%v", validationError.Line)))
+		errorNode.AddKeyValueChild(createStringScalarNode("column"), createScalarNode(validationError.Column, fmt.Sprintf("%v", validationError.Column)))
+		errorNode.AddKeyValueChild(createStringScalarNode("message"), createStringScalarNode(validationError.Message))
+		errorsNode.AddChild(errorNode)
+	}
+	return errorsNode
+}
+
+// validate(schema) returns the list of places the node does not match the JSON Schema,
+// which is empty when it is valid.
+func validateOperator(d *dataTreeNavigator, context Context, expressionNode *ExpressionNode) (Context, error) {
+	log.Debugf("validateOperator")
+
+	results := list.New()
+	for el := context.MatchingNodes.Front(); el != nil; el = el.Next() {
+		candidate := el.Value.(*CandidateNode)
+
+		schemas, err := d.GetMatchingNodes(context.SingleReadonlyChildContext(candidate), expressionNode.RHS)
+		if err != nil {
+			return Context{}, err
+		}
+		for schemaEl := schemas.MatchingNodes.Front(); schemaEl != nil; schemaEl = schemaEl.Next() {
+			errors, err := NewSchemaValidator(schemaEl.Value.(*CandidateNode)).Validate(candidate)
+			if err != nil {
+				return Context{}, err
+			}
+			results.PushBack(schemaErrorsToNode(errors))
+		}
+	}
+	return context.ChildContext(results), nil
+}
@@ -0,0 +1,127 @@
+package yqlib
+
+import (
+	"testing"
+)
+
+var validateOperatorScenarios = []expressionScenario{
+	{
+		description:    "Validate against a schema file",
+		subdescription: "Returns the list of errors, with the path, line and column of each",
+		document:       "name: My App\nports: [80, 70000]\n",
+		expression:     `validate(load("../../examples/schema.json"))`,
+		expected: []string{
+			"D0, P[], (!!seq)::- path: .\n  line: 1\n  column: 1\n  message: missing required property \"replicas\"\n- path: .name\n  line: 1\n  column: 7\n  message: must match the pattern ^[a-z-]+$\n- path: .ports[1]\n  line: 2\n  column: 13\n  message: must be less than or equal to 65535, but got 70000\n",
+		},
+	},
+	{
+		description:    "Check if a document is valid",
+		subdescription: "There are no errors when the document is valid",
+		document:       "name: my-app\nreplicas: 2\n",
+		expression:     `validate(load("../../examples/schema.json")) | length == 0`,
+		expected: []string{
+			"D0, P[], (!!bool)::true\n",
+		},
+	},
+	{
+		description:    "Inline schema",
+		subdescription: "The schema can be any expression, and is evaluated against the node being validated",
+		document:       "a: cat\n",
+		expression:     `.a | validate({"type": "integer"}) | .[].message`,
+		expected: []string{
+			"D0, P[0 message], (!!str)::expected integer, but got string\n",
+		},
+	},
+	{
+		skipDoc:     true,
+		description: "paths quote keys that need it",
+		document:    `{"x.y": 1, "a b": [s]}`,
+		expression:  `validate({"properties": {"x.y": {"type": "string"}, "a b": {"items": {"type": "integer"}}}}) | .[].path`,
+		expected: []string{
+			"D0, P[0 path], (!!str)::.[\"x.y\"]\n",
+			"D0, P[1 path], (!!str)::.[\"a b\"][0]\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1, b: 2.5, c: [1, 1], d: {}}",
+		expression: `validate({"properties": {"a": {"enum": [2, 3]}, "b": {"multipleOf": 0.5, "exclusiveMaximum": 2}, "c": {"uniqueItems": true, "minItems": 3}, "d": {"minProperties": 1}}, "additionalProperties": false}) | .[] | .path + ": " + .message`,
+		expected: []string{
+			"D0, P[0 path], (!!str)::.a: must be one of [2, 3], but got 1\n",
+			"D0, P[1 path], (!!str)::.b: must be less than 2, but got 2.5\n",
+			"D0, P[2 path], (!!str)::.c: must have at least 3 items, but has 2\n",
+			"D0, P[3 path], (!!str)::.c: must only have unique items, but [0] and [1] are the same\n",
+			"D0, P[4 path], (!!str)::.d: must have at least 1 properties, but has 0\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{kind: a, size: 3}",
+		expression: `validate({"oneOf": [{"properties": {"kind": {"const": "a"}}}, {"properties": {"size": {"type": "number"}}}], "not": {"required": ["size"]}, "if": {"properties": {"kind": {"const": "a"}}}, "then": {"required": ["name"]}}) | .[].message`,
+		expected: []string{
+			"D0, P[0 message], (!!str)::must match exactly one of the schemas in oneOf, but matched 2\n",
+			"D0, P[1 message], (!!str)::must not match the schema in not\n",
+			"D0, P[2 message], (!!str)::missing required property \"name\"\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: 1, x-b: 2, c: [x, 3, 4]}",
+		expression: `validate({"patternProperties": {"^x-": {"type": "string"}}, "propertyNames": {"maxLength": 3}, "dependentRequired": {"a": ["z"]}, "properties": {"c": {"prefixItems": [{"type": "string"}], "items": {"type": "string"}, "contains": {"const": 4}}}}) | .[] | .path + ": " + .message`,
+		expected: []string{
+			"D0, P[0 path], (!!str)::.: property \"a\" requires property \"z\"\n",
+			"D0, P[1 path], (!!str)::.x-b: expected string, but got integer\n",
+			"D0, P[2 path], (!!str)::.c[1]: expected string, but got integer\n",
+			"D0, P[3 path], (!!str)::.c[2]: expected string, but got integer\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "[1, 2.0, null, true]",
+		expression: `validate({"items": {"type": ["integer", "null"]}}) | .[] | .path + ": " + .message`,
+		expected: []string{
+			"D0, P[0 path], (!!str)::.[3]: expected integer or null, but got boolean\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "{a: {a: {a: cat}}}",
+		expression: `validate({"$ref": "#/definitions/node", "definitions": {"node": {"type": "object", "properties": {"a": {"anyOf": [{"$ref": "#/definitions/node"}, {"type": "integer"}]}}}}}) | .[] | .path + ": " + .message`,
+		expected: []string{
+			"D0, P[0 path], (!!str)::.a: must match at least one of the schemas in anyOf\n",
+		},
+	},
+	{
+		skipDoc:    true,
+		document:   "a: 1",
+		expression: `validate(false) | .[].message`,
+		expected: []string{
+			"D0, P[0 message], (!!str)::no value is allowed here\n",
+		},
+	},
+	{
+		skipDoc:       true,
+		document:      "a: 1",
+		expression:    `validate({"$ref": "other.json#/a"})`,
+		expectedError: "unsupported $ref other.json#/a, only references within the schema are supported",
+	},
+	{
+		skipDoc:       true,
+		document:      "a: 1",
+		expression:    `validate({"$ref": "#/nope"})`,
+		expectedError: "could not resolve $ref #/nope",
+	},
+	{
+		skipDoc:       true,
+		document:      "a: 1",
+		expression:    `validate("cat")`,
+		expectedError: "a schema must be a map or a boolean, but got !!str",
+	},
+}
+
+func TestValidateOperatorScenarios(t *testing.T) {
+	for _, tt := range validateOperatorScenarios {
+		testScenario(t, &tt)
+	}
+	documentOperatorScenarios(t, "validate", validateOperatorScenarios)
+}
@@ -15,7 +15,6 @@ this.value = cool
 `
 
 const expectedPropertiesWithCommentsOnMapProps = `this.thing = hi hi
-
 # important notes
 # about this value
 this.value = cool
@@ -36,7 +35,6 @@ this.array.1 = dog
 `
 
 const expectedPropertiesWithCommentInArrayProps = `this.array.0 = cat
-
 # important notes
 # about dogs
 this.array.1 = dog
@@ -50,70 +48,124 @@ const expectedPropertiesWithCommentInArrayYaml = `this:
     - dog
 `
 
+const propertiesWithGroupedComments = `# Application settings
+# maintained by hand
+
+# database
+db.url = jdbc:x
+db.user = admin
+
+# cache settings
+#
+# sizes are in MB
+
+! older style comment
+cache.size = large
+# the end
+`
+
+const expectedPropertiesWithGroupedComments = `# Application settings
+# maintained by hand
+
+# database
+db.url = jdbc:x
+db.user = admin
+
+# cache settings
+#
+# sizes are in MB
+
+# older style comment
+cache.size = large
+# the end
+`
+
+const expectedPropertiesWithGroupedCommentsYaml = `# Application settings
+# maintained by hand
+db:
+  # database
+  url: jdbc:x
+  user: admin
+cache:
+  # cache settings
+  #
+  # sizes are in MB
+
+  # older style comment
+  size: large
+# the end
+`
+
 const samplePropertiesYaml = `# block comments come through
-person: # neither do comments on maps
+person: # comments on maps appear
     name: Mike Wazowski # comments on values appear
     pets: 
     - cat # comments on array values appear
     - nested:
         - list entry
-    food: [pizza] # comments on arrays do not
+    food: [pizza] # comments on arrays appear
 emptyArray: []
 emptyMap: []
 `
 
 const expectedPropertiesUnwrapped = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name = Mike Wazowski
-
 # comments on array values appear
 person.pets.0 = cat
 person.pets.1.nested.0 = list entry
+# comments on arrays appear
 person.food.0 = pizza
 `
 
 const expectedPropertiesUnwrappedArrayBrackets = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name = Mike Wazowski
-
 # comments on array values appear
 person.pets[0] = cat
 person.pets[1].nested[0] = list entry
+# comments on arrays appear
 person.food[0] = pizza
 `
 
 const expectedPropertiesUnwrappedCustomSeparator = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name :@ Mike Wazowski
-
 # comments on array values appear
 person.pets.0 :@ cat
 person.pets.1.nested.0 :@ list entry
+# comments on arrays appear
 person.food.0 :@ pizza
 `
 
 const expectedPropertiesWrapped = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name = "Mike Wazowski"
-
 # comments on array values appear
 person.pets.0 = cat
 person.pets.1.nested.0 = "list entry"
+# comments on arrays appear
 person.food.0 = pizza
 `
 
 const expectedUpdatedProperties = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name = Mike Wazowski
-
 # comments on array values appear
 person.pets.0 = dog
 person.pets.1.nested.0 = list entry
+# comments on arrays appear
 person.food.0 = pizza
 `
 
 const expectedDecodedYaml = `person:
   # block comments come through
+  # comments on maps appear
   # comments on values appear
   name: Mike Wazowski
   pets:
@@ -122,10 +174,12 @@ const expectedDecodedYaml = `person:
     - nested:
         - list entry
   food:
+    # comments on arrays appear
     - pizza
 `
 
 const expectedDecodedPersonYaml = `# block comments come through
+# comments on maps appear
 # comments on values appear
 name: Mike Wazowski
 pets:
@@ -134,6 +188,7 @@ pets:
   - nested:
       - list entry
 food:
+  # comments on arrays appear
   - pizza
 `
 
@@ -144,12 +199,13 @@ person.food.0 = pizza
 `
 
 const expectedPropertiesWithEmptyMapsAndArrays = `# block comments come through
+# comments on maps appear
 # comments on values appear
 person.name = Mike Wazowski
-
 # comments on array values appear
 person.pets.0 = cat
 person.pets.1.nested.0 = list entry
+# comments on arrays appear
 person.food.0 = pizza
 emptyArray = 
 emptyMap = 
@@ -265,6 +321,40 @@ var propertyScenarios = []formatScenario{
 		expected:     expectedUpdatedProperties,
 		scenarioType: "roundtrip",
 	},
+	{
+		description:    "Roundtrip with comments",
+		subdescription: "Comments are kept with the blank lines around them, along with comments at the top and end of the file. Blank lines between properties are kept too, when the output is properties.",
+		input:          propertiesWithGroupedComments,
+		expected:       expectedPropertiesWithGroupedComments,
+		scenarioType:   "roundtrip",
+	},
+	{
+		skipDoc:      true,
+		description:  "grouped comments decode",
+		input:        propertiesWithGroupedComments,
+		expected:     expectedPropertiesWithGroupedCommentsYaml,
+		scenarioType: "decode",
+	},
+	{
+		skipDoc:      true,
+		description:  "blank lines are kept as they were",
+		input:        "db.host = x\n# port\ndb.port = 5\n\ndb.user = u\n\n# pass\ndb.pass = p\n",
+		expected:     "db.host = x\n# port\ndb.port = 5\n\ndb.user = u\n\n# pass\ndb.pass = p\n",
+		scenarioType: "roundtrip",
+	},
+	{
+		skipDoc:      true,
+		description:  "comments on keys with escapes and continued values",
+		input:        "# a key with a space\na\\ b = 1\n# continued\nc = 2 \\\n  # not a comment\n",
+		expected:     "# a key with a space\na\\ b = 1\n# continued\nc = 2 # not a comment\n",
+		scenarioType: "roundtrip",
+	},
+	{
+		skipDoc:     true,
+		description: "foot comments",
+		input:       "a:\n  b: 1\n  # after b\nc: 2\n",
+		expected:    "a.b = 1\n# after b\n\nc = 2\n",
+	},
 	{
 		skipDoc:      true,
 		description:  "comments on arrays roundtrip",
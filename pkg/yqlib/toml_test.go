@@ -79,6 +79,139 @@ var expectedSampleWithHeader = `servers:
     ip: 10.0.0.1
 `
 
+var sampleTomlWithComments = `# config for the app
+
+# the title
+title = "TOML" # inline
+
+[owner] # owner table
+# who
+name = "Tom"
+ports = [
+  8000, # first
+  # second
+  8001,
+  # the end
+]
+
+# servers
+[[servers]]
+ip = "a"
+# trailing
+`
+
+var expectedTomlWithCommentsYaml = `# config for the app
+# the title
+title: TOML # inline
+owner: # owner table
+  # who
+  name: Tom
+  ports:
+    - 8000 # first
+    # second
+    - 8001
+    # the end
+servers:
+  # servers
+  - ip: a
+# trailing
+`
+
+var expectedTomlWithCommentsRoundtrip = `# config for the app
+
+# the title
+title = "TOML" # inline
+
+[owner] # owner table
+# who
+name = "Tom"
+ports = [8000, 8001]
+
+# servers
+[[servers]]
+ip = "a"
+# trailing
+`
+
+var sampleYamlForToml = `# Example config
+title: TOML Example # the title
+database:
+  enabled: true
+  ports: [8000, 8001, 8002]
+  limits: {cpu: 1.5, memory: 512}
+servers:
+  alpha:
+    ip: 10.0.0.1
+  beta:
+    ip: 10.0.0.2
+`
+
+var expectedTomlFromYaml = `# Example config
+title = "TOML Example" # the title
+
+[database]
+enabled = true
+ports = [8000, 8001, 8002]
+limits = { cpu = 1.5, memory = 512 }
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[servers.beta]
+ip = "10.0.0.2"
+`
+
+var sampleYamlArrayOfTables = `products:
+  - name: Hammer
+    sku: 738594937
+    dimensions:
+      width: 2
+  - name: Nail
+    colour: gray
+`
+
+var expectedTomlArrayOfTables = `[[products]]
+name = "Hammer"
+sku = 738594937
+
+[products.dimensions]
+width = 2
+
+[[products]]
+name = "Nail"
+colour = "gray"
+`
+
+var sampleTomlRoundtrip = `title = "roundtrip"
+released = 1979-05-27T07:32:00-08:00
+local = 1979-05-27T07:32:00
+day = 1979-05-27
+lunch = 12:30:00
+ratio = 0.5
+big = inf
+"quoted key" = 'literal \ string'
+
+[owner]
+name = "Tom \"the\" Preston-Werner"
+
+[empty]
+
+[[fruits]]
+name = "apple"
+
+[fruits.physical]
+colour = "red"
+
+[[fruits.varieties]]
+name = "red delicious"
+
+[[fruits.varieties]]
+name = "granny smith"
+
+[[fruits]]
+name = "banana"
+`
+
 var tomlScenarios = []formatScenario{
 	{
 		skipDoc:      true,
@@ -151,6 +284,70 @@ var tomlScenarios = []formatScenario{
 		expected:     "hello\n",
 		scenarioType: "roundtrip",
 	},
+	{
+		description:  "Encode: Tables",
+		input:        sampleYamlForToml,
+		expected:     expectedTomlFromYaml,
+		scenarioType: "encode",
+	},
+	{
+		description:    "Encode: Array of tables",
+		subdescription: "Arrays of maps are written as arrays of tables",
+		input:          sampleYamlArrayOfTables,
+		expected:       expectedTomlArrayOfTables,
+		scenarioType:   "encode",
+	},
+	{
+		description:    "Encode: Inline tables",
+		subdescription: "Flow style maps are written as inline tables, nulls are dropped as TOML does not support them",
+		input:          "point: {x: 1, y: 2}\nnothing: null\nmulti: |\n  line one\n  line two\n",
+		expected:       "point = { x = 1, y = 2 }\nmulti = \"\"\"\nline one\nline two\n\"\"\"\n",
+		scenarioType:   "encode",
+	},
+	{
+		skipDoc:      true,
+		description:  "Encode: numbers and dates",
+		input:        "a: 0o17\nb: 1e3\nc: -.inf\nd: 2001-12-14\ne: 10\nf: 5.0\ng: 0x1F\n",
+		expected:     "a = 0o17\nb = 1e3\nc = -inf\nd = 2001-12-14\ne = 10\nf = 5.0\ng = 0x1F\n",
+		scenarioType: "encode",
+	},
+	{
+		skipDoc:      true,
+		description:  "Encode: quoted keys and escapes",
+		input:        "\"a.b\": \"tab\\there\"\nc: 'single'\n",
+		expected:     "\"a.b\" = \"tab\\there\"\nc = 'single'\n",
+		scenarioType: "encode",
+	},
+	{
+		skipDoc:       true,
+		description:   "Encode: top level array",
+		input:         "- a\n- b\n",
+		expectedError: "TOML documents must be a map at the top level, cannot encode !!seq",
+		scenarioType:  "encode-error",
+	},
+	{
+		description:    "Roundtrip",
+		subdescription: "Tables, arrays of tables and typed dates are kept",
+		input:          sampleTomlRoundtrip,
+		expression:     ".",
+		expected:       sampleTomlRoundtrip,
+		scenarioType:   "roundtrip",
+	},
+	{
+		skipDoc:      true,
+		description:  "Parse: local dates and times",
+		input:        "a = 1979-05-27\nb = 07:32:00\nc = 1979-05-27 07:32:00Z\n",
+		expression:   ".[] | tag",
+		expected:     "!!timestamp\n!!timestamp\n!!timestamp\n",
+		scenarioType: "decode",
+	},
+	{
+		skipDoc:      true,
+		description:  "Parse: empty tables before other tables",
+		input:        "[a]\n[b]\nx = 1\n[[c]]\n[[c]]\ny = 2\n",
+		expected:     "a: {}\nb:\n  x: 1\nc:\n  - {}\n  - y: 2\n",
+		scenarioType: "decode",
+	},
 	{
 		skipDoc:      true,
 		input:        `A.B = "hello"`,
@@ -210,9 +407,16 @@ var tomlScenarios = []formatScenario{
 	{
 		description:  "Parse: inline table",
 		input:        `name = { first = "Tom", last = "Preston-Werner" }`,
-		expected:     "name:\n  first: Tom\n  last: Preston-Werner\n",
+		expected:     "name: {first: Tom, last: Preston-Werner}\n",
 		scenarioType: "decode",
 	},
+	{
+		skipDoc:      true,
+		description:  "Roundtrip: inline tables",
+		input:        "a = { x = 1 }\nb = [{ n = 1 }]\n\n[c]\nd = { e = { f = 1 }, g = [{ h = 2 }] }\n\n[[t]]\nn = { o = 1 }\n",
+		expected:     "a = { x = 1 }\nb = [{ n = 1 }]\n\n[c]\nd = { e = { f = 1 }, g = [{ h = 2 }] }\n\n[[t]]\nn = { o = 1 }\n",
+		scenarioType: "roundtrip",
+	},
 	{
 		skipDoc:      true,
 		input:        sampleTable,
@@ -231,6 +435,41 @@ var tomlScenarios = []formatScenario{
 		expected:     emptyTableExpected,
 		scenarioType: "decode",
 	},
+	{
+		description:    "Parse: comments",
+		subdescription: "Comments before a key or table, and at the end of its line, are kept.",
+		input:          sampleTomlWithComments,
+		expected:       expectedTomlWithCommentsYaml,
+		scenarioType:   "decode",
+	},
+	{
+		description:    "Roundtrip: comments",
+		subdescription: "Comments in arrays are only kept when converting to other formats, as arrays are written on one line.",
+		input:          sampleTomlWithComments,
+		expected:       expectedTomlWithCommentsRoundtrip,
+		scenarioType:   "roundtrip",
+	},
+	{
+		skipDoc:      true,
+		description:  "comments on array tables",
+		input:        "[[a]] # first\nb = 1\n\n[[a]] # second\nb = 2\n",
+		expected:     "a:\n  # first\n  - b: 1\n  # second\n  - b: 2\n",
+		scenarioType: "decode",
+	},
+	{
+		skipDoc:      true,
+		description:  "comments in an empty array",
+		input:        "a = [\n  # nothing\n]\n",
+		expected:     "a: []\n# nothing\n",
+		scenarioType: "decode",
+	},
+	{
+		skipDoc:      true,
+		description:  "comments in a table after a sub table header",
+		input:        "[a.b] # header\n# before c\nc = 1 # c\n",
+		expected:     "a:\n  b: # header\n    # before c\n    c: 1 # c\n",
+		scenarioType: "decode",
+	},
 	{
 		description:  "Parse: with header",
 		skipDoc:      true,
@@ -253,6 +492,15 @@ func testTomlScenario(t *testing.T, s formatScenario) {
 		}
 	case "roundtrip":
 		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewTomlDecoder(), NewTomlEncoder()), s.description)
+	case "encode":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewTomlEncoder()), s.description)
+	case "encode-error":
+		result, err := processFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewTomlEncoder())
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
 	}
 }
 
@@ -278,6 +526,28 @@ func documentTomlDecodeScenario(w *bufio.Writer, s formatScenario) {
 	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewTomlDecoder(), NewYamlEncoder(ConfiguredYamlPreferences))))
 }
 
+func documentTomlEncodeScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.yml file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```yaml\n%v\n```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+	expression := s.expression
+	if expression == "" {
+		expression = "."
+	}
+	writeOrPanic(w, fmt.Sprintf("```bash\nyq -o toml '%v' sample.yml\n```\n", expression))
+	writeOrPanic(w, "will output\n")
+
+	writeOrPanic(w, fmt.Sprintf("```toml\n%v```\n\n", mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewTomlEncoder())))
+}
+
 func documentTomlRoundtripScenario(w *bufio.Writer, s formatScenario) {
 	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
 
@@ -297,7 +567,7 @@ func documentTomlRoundtripScenario(w *bufio.Writer, s formatScenario) {
 	writeOrPanic(w, fmt.Sprintf("```bash\nyq '%v' sample.toml\n```\n", expression))
 	writeOrPanic(w, "will output\n")
 
-	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewTomlDecoder(), NewTomlEncoder())))
+	writeOrPanic(w, fmt.Sprintf("```toml\n%v```\n\n", mustProcessFormatScenario(s, NewTomlDecoder(), NewTomlEncoder())))
 }
 
 func documentTomlScenario(_ *testing.T, w *bufio.Writer, i interface{}) {
@@ -311,6 +581,8 @@ func documentTomlScenario(_ *testing.T, w *bufio.Writer, i interface{}) {
 		documentTomlDecodeScenario(w, s)
 	case "roundtrip":
 		documentTomlRoundtripScenario(w, s)
+	case "encode":
+		documentTomlEncodeScenario(w, s)
 
 	default:
 		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
@@ -0,0 +1,256 @@
+package yqlib
+
+import (
+	"fmt"
+	"strings"
+)
+
+const unifiedDiffContext = 3
+
+type lineEdit struct {
+	// ' ' for an unchanged line, '-' for a removed line and '+' for an added line
+	kind byte
+	line string
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return make([]string, 0)
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineDiffer finds the shortest edit script between two lists of lines, using the linear
+// space version of Myers' algorithm: it finds the middle of the edit script, and then
+// recursively diffs the parts before and after it. The lines are numbered so that they
+// can be compared quickly.
+type lineDiffer struct {
+	aLines []string
+	bLines []string
+	a      []int
+	b      []int
+	edits  []lineEdit
+}
+
+// diffLines finds the shortest edit script between the lines of a and b.
+func diffLines(a []string, b []string) []lineEdit {
+	ids := make(map[string]int)
+	number := func(lines []string) []int {
+		numbered := make([]int, len(lines))
+		for i, line := range lines {
+			id, exists := ids[line]
+			if !exists {
+				id = len(ids)
+				ids[line] = id
+			}
+			numbered[i] = id
+		}
+		return numbered
+	}
+	differ := &lineDiffer{aLines: a, bLines: b, a: number(a), b: number(b), edits: make([]lineEdit, 0, len(a)+len(b))}
+	differ.diff(0, len(a), 0, len(b))
+	return differ.edits
+}
+
+func (ld *lineDiffer) diff(aStart int, aEnd int, bStart int, bEnd int) {
+	for aStart < aEnd && bStart < bEnd && ld.a[aStart] == ld.b[bStart] {
+		ld.edits = append(ld.edits, lineEdit{' ', ld.aLines[aStart]})
+		aStart++
+		bStart++
+	}
+	suffix := 0
+	for aStart < aEnd-suffix && bStart < bEnd-suffix && ld.a[aEnd-suffix-1] == ld.b[bEnd-suffix-1] {
+		suffix++
+	}
+	aEnd -= suffix
+	bEnd -= suffix
+
+	if aStart == aEnd || bStart == bEnd {
+		for _, line := range ld.aLines[aStart:aEnd] {
+			ld.edits = append(ld.edits, lineEdit{'-', line})
+		}
+		for _, line := range ld.bLines[bStart:bEnd] {
+			ld.edits = append(ld.edits, lineEdit{'+', line})
+		}
+	} else if x, y, found := ld.middle(aStart, aEnd, bStart, bEnd); found {
+		ld.diff(aStart, x, bStart, y)
+		ld.diff(x, aEnd, y, bEnd)
+	} else {
+		for _, line := range ld.aLines[aStart:aEnd] {
+			ld.edits = append(ld.edits, lineEdit{'-', line})
+		}
+		for _, line := range ld.bLines[bStart:bEnd] {
+			ld.edits = append(ld.edits, lineEdit{'+', line})
+		}
+	}
+
+	for i := aEnd; i < aEnd+suffix; i++ {
+		ld.edits = append(ld.edits, lineEdit{' ', ld.aLines[i]})
+	}
+}
+
+// middle searches forwards from the start and backwards from the end at the same time,
+// returning the point where the two paths overlap. That splits the edit script into two
+// smaller ones, while only keeping the furthest point of each diagonal in memory.
+// The ranges must not start or end with the same line.
+func (ld *lineDiffer) middle(aStart int, aEnd int, bStart int, bEnd int) (int, int, bool) {
+	a := ld.a[aStart:aEnd]
+	b := ld.b[bStart:bEnd]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	// when delta is odd, the paths overlap while searching forwards, otherwise backwards
+	checkForwards := delta%2 != 0
+	// the diagonals that have gone past the end of a or b are skipped
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case checkForwards:
+				backwardK := offset + delta - k
+				if backwardK >= 0 && backwardK < len(backward) && backward[backwardK] != -1 && x >= n-backward[backwardK] {
+					return aStart + x, bStart + y, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !checkForwards:
+				forwardK := offset + delta - k
+				if forwardK >= 0 && forwardK < len(forward) && forward[forwardK] != -1 {
+					forwardX := forward[forwardK]
+					forwardY := forwardX - (forwardK - offset)
+					if forwardX >= n-x {
+						return aStart + forwardX, bStart + forwardY, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%v,0", start-1)
+	} else if count == 1 {
+		return fmt.Sprintf("%v", start)
+	}
+	return fmt.Sprintf("%v,%v", start, count)
+}
+
+func writeDiffLine(sb *strings.Builder, kind byte, line string) {
+	sb.WriteByte(kind)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// unifiedDiff returns the changes between from and to in the unified diff format,
+// or an empty string if they are the same.
+func unifiedDiff(fromName string, toName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+	edits := diffLines(splitLines(from), splitLines(to))
+
+	var sb strings.Builder
+	sb.WriteString("--- " + fromName + "\n")
+	sb.WriteString("+++ " + toName + "\n")
+
+	for start := 0; start < len(edits); {
+		if edits[start].kind == ' ' {
+			start++
+			continue
+		}
+		// a hunk covers the changes that are within twice the context of each other
+		hunkStart := start - unifiedDiffContext
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := start
+		for index := start; index < len(edits) && index <= hunkEnd+2*unifiedDiffContext+1; index++ {
+			if edits[index].kind != ' ' {
+				hunkEnd = index
+			}
+		}
+		hunkEnd = hunkEnd + unifiedDiffContext
+		if hunkEnd >= len(edits) {
+			hunkEnd = len(edits) - 1
+		}
+
+		fromLine, toLine := 1, 1
+		for _, edit := range edits[:hunkStart] {
+			if edit.kind != '+' {
+				fromLine++
+			}
+			if edit.kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, edit := range edits[hunkStart : hunkEnd+1] {
+			if edit.kind != '+' {
+				fromCount++
+			}
+			if edit.kind != '-' {
+				toCount++
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%v +%v @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount)))
+		for _, edit := range edits[hunkStart : hunkEnd+1] {
+			writeDiffLine(&sb, edit.kind, edit.line)
+		}
+		start = hunkEnd + 1
+	}
+	return sb.String()
+}
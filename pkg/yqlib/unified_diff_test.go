@@ -0,0 +1,103 @@
+package yqlib
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mikefarah/yq/v4/test"
+)
+
+type unifiedDiffScenario struct {
+	description string
+	from        string
+	to          string
+	expected    string
+}
+
+var unifiedDiffScenarios = []unifiedDiffScenario{
+	{
+		description: "same",
+		from:        "a: 1\n",
+		to:          "a: 1\n",
+		expected:    "",
+	},
+	{
+		description: "change a line",
+		from:        "a: 1\nb: 2\nc: 3\n",
+		to:          "a: 1\nb: 3\nc: 3\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -1,3 +1,3 @@\n a: 1\n-b: 2\n+b: 3\n c: 3\n",
+	},
+	{
+		description: "changes far apart are separate hunks",
+		from:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+		to:          "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+	},
+	{
+		description: "changes close together are one hunk",
+		from:        "1\n2\n3\n4\n5\n6\n7\n8\n",
+		to:          "one\n2\n3\n4\n5\n6\n7\neight\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+	},
+	{
+		description: "add to an empty file",
+		from:        "",
+		to:          "a: 1\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -0,0 +1 @@\n+a: 1\n",
+	},
+	{
+		description: "no newline at end of file",
+		from:        "a: 1",
+		to:          "a: 1\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -1 +1 @@\n-a: 1\n\\ No newline at end of file\n+a: 1\n",
+	},
+	{
+		description: "moved line",
+		from:        "b: 2\na: 1\nc: 3\n",
+		to:          "a: 1\nb: 2\nc: 3\n",
+		expected:    "--- f.yml\n+++ f.yml\n@@ -1,3 +1,3 @@\n-b: 2\n a: 1\n+b: 2\n c: 3\n",
+	},
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, s := range unifiedDiffScenarios {
+		test.AssertResultWithContext(t, s.expected, unifiedDiff("f.yml", "f.yml", s.from, s.to), s.description)
+	}
+}
+
+func TestUnifiedDiffLargeReversedInput(t *testing.T) {
+	var from []string
+	for i := 0; i < 10000; i++ {
+		from = append(from, fmt.Sprintf("line: %v", i))
+	}
+	to := make([]string, len(from))
+	for i, line := range from {
+		to[len(from)-i-1] = line
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits := diffLines(from, to)
+	runtime.ReadMemStats(&after)
+
+	// storing every step of the search would need gigabytes here
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64*1024*1024 {
+		t.Errorf("expected diffLines to allocate less than 64MB, but it allocated %v bytes", allocated)
+	}
+
+	var fromResult, toResult []string
+	for _, edit := range edits {
+		if edit.kind != '+' {
+			fromResult = append(fromResult, edit.line)
+		}
+		if edit.kind != '-' {
+			toResult = append(toResult, edit.line)
+		}
+	}
+	test.AssertResult(t, strings.Join(from, "\n"), strings.Join(fromResult, "\n"))
+	test.AssertResult(t, strings.Join(to, "\n"), strings.Join(toResult, "\n"))
+	// only one line can be kept when the lines are reversed
+	test.AssertResult(t, 2*len(from)-1, len(edits))
+}
@@ -1,41 +1,154 @@
 package yqlib
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 )
 
+type WriteStrategy int
+
+const (
+	// RenameWriteStrategy writes to a temp file and renames it over the original, so the
+	// update is atomic. Hard links to the original are not updated.
+	RenameWriteStrategy WriteStrategy = iota
+	// TruncateWriteStrategy writes back into the original file, keeping its inode, hard links and owner.
+	TruncateWriteStrategy
+)
+
+func WriteStrategyFromString(strategy string) (WriteStrategy, error) {
+	switch strategy {
+	case "rename":
+		return RenameWriteStrategy, nil
+	case "truncate":
+		return TruncateWriteStrategy, nil
+	}
+	return RenameWriteStrategy, fmt.Errorf("unknown write strategy '%v' please use [rename, truncate]", strategy)
+}
+
+type WriteInPlacePreferences struct {
+	// Check leaves the file untouched, recording whether it would have changed
+	Check bool
+	// Diff leaves the file untouched, writing a unified diff of the change to DiffWriter instead
+	Diff       bool
+	DiffWriter io.Writer
+	// BackupSuffix, when set, keeps a copy of the original file with this suffix
+	BackupSuffix string
+	Strategy     WriteStrategy
+}
+
+func NewDefaultWriteInPlacePreferences() WriteInPlacePreferences {
+	return WriteInPlacePreferences{
+		Check:        false,
+		Diff:         false,
+		DiffWriter:   os.Stdout,
+		BackupSuffix: "",
+		Strategy:     RenameWriteStrategy,
+	}
+}
+
+var ConfiguredWriteInPlacePreferences = NewDefaultWriteInPlacePreferences()
+
+// the number of times to retry locking a file that was replaced while waiting for the lock
+const writeInPlaceLockAttempts = 10
+
 type writeInPlaceHandler interface {
 	CreateTempFile() (*os.File, error)
 	FinishWriteInPlace(evaluatedSuccessfully bool) error
+	// Changed returns whether, with Check or Diff, the file would have been changed
+	Changed() bool
 }
 
 type writeInPlaceHandlerImpl struct {
 	inputFilename string
-	tempFile      *os.File
+	// targetFilename is the input file with any symlinks resolved, so that they are kept
+	targetFilename string
+	targetInfo     os.FileInfo
+	lockedFile     *os.File
+	tempFile       *os.File
+	prefs          WriteInPlacePreferences
+	changed        bool
 }
 
 func NewWriteInPlaceHandler(inputFile string) writeInPlaceHandler {
 
-	return &writeInPlaceHandlerImpl{inputFile, nil}
+	return &writeInPlaceHandlerImpl{inputFilename: inputFile, prefs: ConfiguredWriteInPlacePreferences}
 }
 
-func (w *writeInPlaceHandlerImpl) CreateTempFile() (*os.File, error) {
-	file, err := createTempFile()
+// lock waits for an exclusive lock on the target file, which is held until the update is finished.
+// If another process replaced the file while we were waiting, the new file is locked instead.
+func (w *writeInPlaceHandlerImpl) lock() error {
+	for attempt := 0; attempt < writeInPlaceLockAttempts; attempt++ {
+		target, err := filepath.EvalSymlinks(w.inputFilename)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(target) // #nosec
+		if err != nil {
+			return err
+		}
+		if err = lockFile(file); err != nil {
+			// e.g. some network file systems, not a reason to stop
+			log.Info("Skipping file lock of %v: %v", target, err)
+		}
+		lockedInfo, err := file.Stat()
+		if err != nil {
+			safelyCloseFile(file)
+			return err
+		}
+		currentInfo, err := os.Stat(target)
+		if err == nil && os.SameFile(lockedInfo, currentInfo) {
+			log.Debug("WriteInPlaceHandler: locked %v", target)
+			w.targetFilename = target
+			w.targetInfo = lockedInfo
+			w.lockedFile = file
+			return nil
+		}
+		log.Debug("WriteInPlaceHandler: %v was replaced while waiting for the lock, trying again", target)
+		safelyCloseFile(file)
+	}
+	return fmt.Errorf("could not lock %v, it keeps being replaced", w.inputFilename)
+}
 
-	if err != nil {
+func (w *writeInPlaceHandlerImpl) unlock() {
+	if w.lockedFile == nil {
+		return
+	}
+	if err := unlockFile(w.lockedFile); err != nil {
+		log.Debug("Error unlocking %v: %v", w.targetFilename, err)
+	}
+	safelyCloseFile(w.lockedFile)
+	w.lockedFile = nil
+}
+
+func (w *writeInPlaceHandlerImpl) CreateTempFile() (*os.File, error) {
+	if _, err := os.Stat(w.inputFilename); err != nil {
 		return nil, err
 	}
-	info, err := os.Stat(w.inputFilename)
+	if err := w.lock(); err != nil {
+		return nil, err
+	}
+	info := w.targetInfo
+	// next to the target file, so that it can be atomically renamed over it
+	file, err := createTempFileNextTo(w.targetFilename)
 	if err != nil {
+		w.unlock()
 		return nil, err
 	}
-	err = os.Chmod(file.Name(), info.Mode())
 
-	if err != nil {
+	if err = os.Chmod(file.Name(), info.Mode()); err != nil {
+		safelyCloseFile(file)
+		tryRemoveTempFile(file.Name())
+		w.unlock()
 		return nil, err
 	}
 
 	if err = changeOwner(info, file); err != nil {
+		safelyCloseFile(file)
+		tryRemoveTempFile(file.Name())
+		w.unlock()
 		return nil, err
 	}
 	log.Debug("WriteInPlaceHandler: writing to tempfile: %v", file.Name())
@@ -44,13 +157,66 @@ func (w *writeInPlaceHandlerImpl) CreateTempFile() (*os.File, error) {
 }
 
 func (w *writeInPlaceHandlerImpl) FinishWriteInPlace(evaluatedSuccessfully bool) error {
-	log.Debug("Going to write in place, evaluatedSuccessfully=%v, target=%v", evaluatedSuccessfully, w.inputFilename)
+	log.Debug("Going to write in place, evaluatedSuccessfully=%v, target=%v", evaluatedSuccessfully, w.targetFilename)
+	defer w.unlock()
 	safelyCloseFile(w.tempFile)
-	if evaluatedSuccessfully {
-		log.Debug("Moving temp file to target")
-		return tryRenameFile(w.tempFile.Name(), w.inputFilename)
+	if !evaluatedSuccessfully {
+		tryRemoveTempFile(w.tempFile.Name())
+		return nil
 	}
-	tryRemoveTempFile(w.tempFile.Name())
 
+	if w.prefs.Check || w.prefs.Diff {
+		defer tryRemoveTempFile(w.tempFile.Name())
+		return w.compare()
+	}
+
+	if w.prefs.BackupSuffix != "" {
+		if err := w.backup(); err != nil {
+			tryRemoveTempFile(w.tempFile.Name())
+			return err
+		}
+	}
+
+	if w.prefs.Strategy == TruncateWriteStrategy {
+		log.Debug("Copying temp file into target")
+		defer tryRemoveTempFile(w.tempFile.Name())
+		if err := copyFileContents(w.tempFile.Name(), w.targetFilename); err != nil {
+			return fmt.Errorf("failed copying from %v to %v: %w", w.tempFile.Name(), w.targetFilename, err)
+		}
+		return nil
+	}
+	log.Debug("Moving temp file to target")
+	return tryRenameFile(w.tempFile.Name(), w.targetFilename)
+}
+
+// backup copies the original file next to the file given, with the backup suffix.
+func (w *writeInPlaceHandlerImpl) backup() error {
+	backupFilename := w.inputFilename + w.prefs.BackupSuffix
+	log.Debug("WriteInPlaceHandler: backing up %v to %v", w.targetFilename, backupFilename)
+	if err := copyFileContents(w.targetFilename, backupFilename); err != nil {
+		return fmt.Errorf("failed to back up %v to %v: %w", w.inputFilename, backupFilename, err)
+	}
+	return os.Chmod(backupFilename, w.targetInfo.Mode())
+}
+
+func (w *writeInPlaceHandlerImpl) compare() error {
+	original, err := os.ReadFile(w.inputFilename)
+	if err != nil {
+		return err
+	}
+	updated, err := os.ReadFile(w.tempFile.Name())
+	if err != nil {
+		return err
+	}
+	w.changed = !bytes.Equal(original, updated)
+	log.Debug("WriteInPlaceHandler: %v changed=%v", w.inputFilename, w.changed)
+
+	if w.changed && w.prefs.Diff {
+		return writeString(w.prefs.DiffWriter, unifiedDiff(w.inputFilename, w.inputFilename, string(original), string(updated)))
+	}
 	return nil
 }
+
+func (w *writeInPlaceHandlerImpl) Changed() bool {
+	return w.changed
+}
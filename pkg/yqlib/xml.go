@@ -11,6 +11,10 @@ type XmlPreferences struct {
 	DirectiveName   string
 	SkipProcInst    bool
 	SkipDirectives  bool
+	// Lossless decodes into an ordered form that encodes back to the same document
+	Lossless    bool
+	CDataName   string
+	CommentName string
 }
 
 func NewDefaultXmlPreferences() XmlPreferences {
@@ -25,6 +29,9 @@ func NewDefaultXmlPreferences() XmlPreferences {
 		DirectiveName:   "+directive",
 		SkipProcInst:    false,
 		SkipDirectives:  false,
+		Lossless:        false,
+		CDataName:       "+cdata",
+		CommentName:     "+comment",
 	}
 }
 
@@ -40,6 +47,9 @@ func (p *XmlPreferences) Copy() XmlPreferences {
 		DirectiveName:   p.DirectiveName,
 		SkipProcInst:    p.SkipProcInst,
 		SkipDirectives:  p.SkipDirectives,
+		Lossless:        p.Lossless,
+		CDataName:       p.CDataName,
+		CommentName:     p.CommentName,
 	}
 }
 
@@ -262,6 +262,47 @@ const expectedXmlWithProcInstAndDirectives = `<?xml version="1.0"?>
 </apple>
 `
 
+const inputXMLLossless = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- the project -->
+<project xmlns="http://maven.apache.org/POM/4.0.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+         xsi:schemaLocation="http://maven.apache.org/POM/4.0.0 http://maven.apache.org/xsd/maven-4.0.0.xsd">
+  <version>1.0</version>
+  <description>Some <b>mixed</b> text &amp; more</description>
+  <script><![CDATA[if (a < b) { run(); }]]></script>
+  <optional />
+  <empty></empty>
+</project>
+`
+
+const expectedXMLLosslessUpdated = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- the project -->
+<project xmlns="http://maven.apache.org/POM/4.0.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+         xsi:schemaLocation="http://maven.apache.org/POM/4.0.0 http://maven.apache.org/xsd/maven-4.0.0.xsd">
+  <version>2.0</version>
+  <description>Some <b>mixed</b> text &amp; more</description>
+  <script><![CDATA[if (a < b) { run(); }]]></script>
+  <optional />
+  <empty></empty>
+</project>
+`
+
+const inputXMLLosslessSmall = `<a x='1'>text <b>bold</b><!-- note --><c/></a>
+`
+
+const expectedXMLLosslessDecode = `+content:
+  - a:
+      +@x: '1'
+      +content:
+        - 'text '
+        - b: bold
+        - +comment: ' note '
+        - c:
+  - |2+
+`
+
+const inputXMLLosslessSoap = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:Get xmlns:m="urn:m" m:id="1"/></soap:Body></soap:Envelope>
+`
+
 var xmlScenarios = []formatScenario{
 	{
 		skipDoc:     true,
@@ -407,6 +448,78 @@ var xmlScenarios = []formatScenario{
 		expected:     inputXMLWithNamespacedAttr,
 		scenarioType: "roundtrip",
 	},
+	{
+		description:    "Lossless round trip",
+		subdescription: "With `--xml-lossless`, the document is decoded into an ordered form that keeps all the text, white space, cdata, comments, namespace declarations and empty tags, so it is written back exactly as it was.",
+		input:          inputXMLLossless,
+		expected:       inputXMLLossless,
+		scenarioType:   "roundtrip-lossless",
+	},
+	{
+		description:    "Lossless update",
+		subdescription: "Each element is a map of its name to its body, so find elements by name to update them.",
+		input:          inputXMLLossless,
+		expression:     `(.. | select(tag == "!!map" and has("version")) | .version) = "2.0"`,
+		expected:       expectedXMLLosslessUpdated,
+		scenarioType:   "roundtrip-lossless",
+	},
+	{
+		description:    "Lossless decode",
+		subdescription: "The body of an element is its text, null for an empty tag, or a map of its attributes and its content: a list of the text, cdata, comments, processing instructions and elements in it. Attributes in single quotes are single quoted.",
+		input:          inputXMLLosslessSmall,
+		expected:       expectedXMLLosslessDecode,
+		scenarioType:   "decode-lossless",
+	},
+	{
+		description:  "Lossless round trip namespaces",
+		skipDoc:      true,
+		input:        inputXMLLosslessSoap,
+		expected:     inputXMLLosslessSoap,
+		scenarioType: "roundtrip-lossless",
+	},
+	{
+		description:  "Lossless round trip directives and attributes",
+		skipDoc:      true,
+		input:        "<!DOCTYPE note>\n<?xml-stylesheet href=\"a.xsl\"?>\n<a  one=\"&quot;1&quot;\"\n\ttwo='it&apos;s' >\r\n<![CDATA[x]]>y<![CDATA[z]]></a>",
+		expected:     "<!DOCTYPE note>\n<?xml-stylesheet href=\"a.xsl\"?>\n<a  one=\"&quot;1&quot;\"\n\ttwo='it&apos;s' >\r\n<![CDATA[x]]>y<![CDATA[z]]></a>",
+		scenarioType: "roundtrip-lossless",
+	},
+	{
+		description:  "Lossless round trip entities and character references",
+		skipDoc:      true,
+		input:        "<a x=\"1 &gt; 0 &quot;q&quot; &#65;\" y='&apos;&#10;'>a &gt; b &quot;c&quot; &apos;d&apos; &#65; &#10;<b>&amp;&#x41;</b></a>",
+		expected:     "<a x=\"1 &gt; 0 &quot;q&quot; &#65;\" y='&apos;&#10;'>a &gt; b &quot;c&quot; &apos;d&apos; &#65; &#10;<b>&amp;&#x41;</b></a>",
+		scenarioType: "roundtrip-lossless",
+	},
+	{
+		description:  "Lossless update text with entities",
+		skipDoc:      true,
+		input:        "<a x=\"&#65;\" y=\"&#66;\"><b>&#65;</b><c>&#66;</c></a>",
+		expression:   `.+content[0].a.+@x = "C" | .+content[0].a.+content[0].b = "<C>"`,
+		expected:     "<a x=\"C\" y=\"&#66;\"><b>&lt;C></b><c>&#66;</c></a>",
+		scenarioType: "roundtrip-lossless",
+	},
+	{
+		description:  "Lossless encode new elements",
+		skipDoc:      true,
+		input:        "a:\n  +@id: 1\n  b: [x, y]\n  c: ~\n  +content: [\"<&>\", {+cdata: \"a]]>b\"}]\n",
+		expected:     "<a id=\"1\"><b>x</b><b>y</b><c/>&lt;&amp;><![CDATA[a]]]]><![CDATA[>b]]></a>",
+		scenarioType: "encode-lossless",
+	},
+	{
+		description:   "Lossless unclosed element",
+		skipDoc:       true,
+		input:         "<a><b></b>",
+		expectedError: "bad file 'sample.yml': invalid XML: element <a> is not closed",
+		scenarioType:  "decode-lossless-error",
+	},
+	{
+		description:   "Lossless text outside of the root",
+		skipDoc:       true,
+		input:         "hello <a/>",
+		expectedError: "bad file 'sample.yml': invalid XML: Encountered chardata [hello] outside of XML node",
+		scenarioType:  "decode-lossless-error",
+	},
 	{
 		description:    "Parse xml: skip custom dtd",
 		subdescription: "DTDs are directives, skip over directives to skip DTDs.",
@@ -612,6 +725,27 @@ var xmlScenarios = []formatScenario{
 		expected:       expectedXmlWithProcInstAndDirectives,
 		scenarioType:   "roundtrip",
 	},
+	{
+		description:  "stream xml",
+		skipDoc:      true,
+		input:        `<r x="1">t<a/><g>1</g><g>2</g></r>`,
+		scenarioType: "decode-stream",
+		expected:     "[[\"r\",\"+@x\"],\"1\"]\n[[\"r\",\"+content\"],\"t\"]\n[[\"r\",\"a\",0],null]\n[[\"r\",\"g\",0],\"1\"]\n[[\"r\",\"g\",1],\"2\"]\n[[\"r\",\"g\",1]]\n[[\"r\"]]\n",
+	},
+	{
+		description:  "stream xml with text after children",
+		skipDoc:      true,
+		input:        `<r><a>1</a>2<b/></r>`,
+		scenarioType: "decode-stream",
+		expected:     "[[\"r\",\"a\",0],\"1\"]\n[[\"r\",\"+content\"],\"2\"]\n[[\"r\",\"b\",0],null]\n[[\"r\",\"b\",0]]\n[[\"r\"]]\n",
+	},
+	{
+		description:   "stream unclosed xml",
+		skipDoc:       true,
+		input:         `<r><a>`,
+		scenarioType:  "decode-stream-error",
+		expectedError: "bad file 'sample.yml': unexpected EOF",
+	},
 }
 
 func testXMLScenario(t *testing.T, s formatScenario) {
@@ -647,6 +781,39 @@ func testXMLScenario(t *testing.T, s formatScenario) {
 		} else {
 			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
 		}
+	case "roundtrip-lossless":
+		prefs := NewDefaultXmlPreferences()
+		prefs.Lossless = true
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewXMLDecoder(prefs), NewXMLEncoder(prefs)), s.description)
+	case "decode-lossless":
+		prefs := NewDefaultXmlPreferences()
+		prefs.Lossless = true
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewXMLDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
+	case "encode-lossless":
+		prefs := NewDefaultXmlPreferences()
+		prefs.Lossless = true
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewXMLEncoder(prefs)), s.description)
+	case "decode-lossless-error":
+		prefs := NewDefaultXmlPreferences()
+		prefs.Lossless = true
+		result, err := processFormatScenario(s, NewXMLDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
+	case "decode-stream":
+		jsonPrefs := ConfiguredJSONPreferences.Copy()
+		jsonPrefs.Indent = 0
+		jsonPrefs.UnwrapScalar = false
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewXMLStreamDecoder(NewDefaultXmlPreferences()), NewJSONEncoder(jsonPrefs)), s.description)
+	case "decode-stream-error":
+		result, err := processFormatScenario(s, NewXMLStreamDecoder(NewDefaultXmlPreferences()), NewJSONEncoder(ConfiguredJSONPreferences))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
 	case "encode-error":
 		result, err := processFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewXMLEncoder(NewDefaultXmlPreferences()))
 		if err == nil {
@@ -679,6 +846,10 @@ func documentXMLScenario(_ *testing.T, w *bufio.Writer, i interface{}) {
 		documentXMLDecodeKeepNsRawTokenScenario(w, s)
 	case "roundtrip-skip-directives":
 		documentXMLSkipDirectivesScenario(w, s)
+	case "roundtrip-lossless":
+		documentXMLLosslessRoundTripScenario(w, s)
+	case "decode-lossless":
+		documentXMLLosslessDecodeScenario(w, s)
 
 	default:
 		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
@@ -814,6 +985,50 @@ func documentXMLSkipDirectivesScenario(w *bufio.Writer, s formatScenario) {
 	writeOrPanic(w, fmt.Sprintf("```xml\n%v```\n\n", mustProcessFormatScenario(s, NewXMLDecoder(prefs), NewXMLEncoder(prefs))))
 }
 
+func documentXMLLosslessRoundTripScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.xml file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```xml\n%v```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+	expression := s.expression
+	if expression == "" {
+		expression = "."
+	}
+	writeOrPanic(w, fmt.Sprintf("```bash\nyq --xml-lossless '%v' sample.xml\n```\n", expression))
+	writeOrPanic(w, "will output\n")
+	prefs := NewDefaultXmlPreferences()
+	prefs.Lossless = true
+
+	writeOrPanic(w, fmt.Sprintf("```xml\n%v```\n\n", mustProcessFormatScenario(s, NewXMLDecoder(prefs), NewXMLEncoder(prefs))))
+}
+
+func documentXMLLosslessDecodeScenario(w *bufio.Writer, s formatScenario) {
+	writeOrPanic(w, fmt.Sprintf("## %v\n", s.description))
+
+	if s.subdescription != "" {
+		writeOrPanic(w, s.subdescription)
+		writeOrPanic(w, "\n\n")
+	}
+
+	writeOrPanic(w, "Given a sample.xml file of:\n")
+	writeOrPanic(w, fmt.Sprintf("```xml\n%v```\n", s.input))
+
+	writeOrPanic(w, "then\n")
+	writeOrPanic(w, "```bash\nyq --xml-lossless -oy '.' sample.xml\n```\n")
+	writeOrPanic(w, "will output\n")
+	prefs := NewDefaultXmlPreferences()
+	prefs.Lossless = true
+
+	writeOrPanic(w, fmt.Sprintf("```yaml\n%v```\n\n", mustProcessFormatScenario(s, NewXMLDecoder(prefs), NewYamlEncoder(ConfiguredYamlPreferences))))
+}
+
 func TestXMLScenarios(t *testing.T) {
 	for _, tt := range xmlScenarios {
 		testXMLScenario(t, tt)
@@ -1,6 +1,7 @@
 package yqlib
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/mikefarah/yq/v4/test"
@@ -83,6 +84,27 @@ var yamlFormatScenarios = []formatScenario{
 	},
 }
 
+var yamlStreamScenarios = []formatScenario{
+	{
+		description:  "stream yaml",
+		input:        "a: &x {b: 1}\nc: *x\nd: &s hi\ne: [*s, []]\n---\n3\n",
+		scenarioType: "decode-stream",
+		expected:     "[[\"a\",\"b\"],1]\n[[\"a\",\"b\"]]\n[[\"c\",\"b\"],1]\n[[\"c\",\"b\"]]\n[[\"d\"],\"hi\"]\n[[\"e\",0],\"hi\"]\n[[\"e\",1],[]]\n[[\"e\",1]]\n[[\"e\"]]\n[[],3]\n",
+	},
+	{
+		description:   "stream yaml with a map key",
+		input:         "? [a]\n: 1\n",
+		scenarioType:  "decode-stream-error",
+		expectedError: "bad file 'sample.yml': yaml: line 1: --stream only supports scalar keys",
+	},
+	{
+		description:   "stream yaml with an unknown alias",
+		input:         "a: *x\n",
+		scenarioType:  "decode-stream-error",
+		expectedError: "bad file 'sample.yml': yaml: line 1: unknown anchor 'x' referenced",
+	},
+}
+
 var yamlParseScenarios = []expressionScenario{
 	{
 		document: `a: hello # things`,
@@ -115,6 +137,25 @@ func testYamlScenario(t *testing.T, s formatScenario) {
 	test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlDecoder(ConfiguredYamlPreferences), NewYamlEncoder(ConfiguredYamlPreferences)), s.description)
 }
 
+func testYamlStreamScenario(t *testing.T, s formatScenario) {
+	prefs := ConfiguredJSONPreferences.Copy()
+	prefs.Indent = 0
+	prefs.UnwrapScalar = false
+	switch s.scenarioType {
+	case "decode-stream":
+		test.AssertResultWithContext(t, s.expected, mustProcessFormatScenario(s, NewYamlStreamDecoder(), NewJSONEncoder(prefs)), s.description)
+	case "decode-stream-error":
+		result, err := processFormatScenario(s, NewYamlStreamDecoder(), NewJSONEncoder(prefs))
+		if err == nil {
+			t.Errorf("Expected error '%v' but it worked: %v", s.expectedError, result)
+		} else {
+			test.AssertResultComplexWithContext(t, s.expectedError, err.Error(), s.description)
+		}
+	default:
+		panic(fmt.Sprintf("unhandled scenario type %q", s.scenarioType))
+	}
+}
+
 func TestYamlParseScenarios(t *testing.T) {
 	for _, tt := range yamlParseScenarios {
 		testScenario(t, &tt)
@@ -126,3 +167,9 @@ func TestYamlFormatScenarios(t *testing.T) {
 		testYamlScenario(t, tt)
 	}
 }
+
+func TestYamlStreamScenarios(t *testing.T) {
+	for _, tt := range yamlStreamScenarios {
+		testYamlStreamScenario(t, tt)
+	}
+}